@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tikv-backend/config"
+	"tikv-backend/pkg/pd"
+	"tikv-backend/pkg/tikv"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// 数据模式，与 HTTP API 的 type 参数一致
+const (
+	modeRawKV = "rawkv"
+	modeTxn   = "txn"
+)
+
+func validateMode(mode string) error {
+	if mode != modeRawKV && mode != modeTxn {
+		return fmt.Errorf("invalid type %q: must be %s or %s", mode, modeRawKV, modeTxn)
+	}
+	return nil
+}
+
+// errNotFound 读取的 key 不存在
+var errNotFound = errors.New("key not found")
+
+// kvPair 一个键值对
+type kvPair struct {
+	Key   []byte
+	Value []byte
+}
+
+// scanRequest 扫描参数
+type scanRequest struct {
+	Mode    string
+	Range   tikv.KeyRange
+	Reverse bool
+	// KeysOnly 只扫描 key，value 为空
+	KeysOnly bool
+	// ReadTS 仅 Txn 模式使用，0 表示读取最新数据
+	ReadTS uint64
+}
+
+// clusterStatus 集群状态，字段与 HTTP API 的 /api/kv/cluster 一致
+type clusterStatus struct {
+	ClusterStatus string       `json:"cluster_status"`
+	Endpoints     []string     `json:"endpoints"`
+	Topology      *pd.Topology `json:"topology,omitempty"`
+	Error         string       `json:"error,omitempty"`
+	APIVersion    string       `json:"api_version"`
+	Keyspace      string       `json:"keyspace"`
+	KeyspaceID    uint32       `json:"keyspace_id"`
+}
+
+// backend 命令使用的数据访问接口，可以直接连接 PD，也可以通过后端的 HTTP API 访问。
+// key 和 HTTP API 一样不加任何前缀。
+type backend interface {
+	// Get 读取一个 key，不存在时返回 errNotFound；ts 仅 Txn 模式使用
+	Get(ctx context.Context, mode string, key []byte, ts uint64) ([]byte, error)
+	Put(ctx context.Context, mode string, key, value []byte) error
+	// Delete 删除一批 key，Txn 模式在一个事务中删除
+	Delete(ctx context.Context, mode string, keys [][]byte) error
+	// BatchPut 写入一批键值对，Txn 模式在一个事务中写入
+	BatchPut(ctx context.Context, mode string, pairs []kvPair) error
+	// Scan 按顺序扫描范围内的键值对，fn 返回 false 时停止
+	Scan(ctx context.Context, req scanRequest, fn tikv.ScanFunc) error
+	// Stats 统计前缀范围内的键，limit 为 0 表示扫描整个范围
+	Stats(ctx context.Context, mode, prefix string, limit int) (*tikv.KVStats, error)
+	ClusterStatus(ctx context.Context) (*clusterStatus, error)
+	TSO(ctx context.Context) (tikv.TSOInfo, error)
+	Close()
+}
+
+// directBackend 按配置文件直接连接 PD，与后端服务共用 pkg/tikv 的客户端
+type directBackend struct {
+	clients *tikv.Clients
+}
+
+// newDirectBackend 按配置设置 TLS、客户端参数和提交选项，连接集群并验证客户端可用
+func newDirectBackend(ctx context.Context, cfg *config.Config) (*directBackend, error) {
+	opts, err := cfg.TiKV.ClusterOptions()
+	if err != nil {
+		return nil, err
+	}
+	security := cfg.TiKV.Security()
+	if err := security.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := security.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	tikv.SetSecurity(security)
+	pd.SetTLSConfig(tlsConfig)
+	if err := tikv.SetClientTuning(cfg.TiKV.Client.Tuning()); err != nil {
+		return nil, err
+	}
+	tikv.SetDefaultCommitOptions(tikv.CommitOptions{
+		AsyncCommit: cfg.TiKV.AsyncCommit,
+		OnePC:       cfg.TiKV.OnePC,
+	})
+
+	if err := tikv.SwapClients(ctx, cfg.TiKV.PDEndpoints, opts, 0); err != nil {
+		return nil, fmt.Errorf("failed to connect to %v: %v", cfg.TiKV.PDEndpoints, err)
+	}
+	return &directBackend{clients: tikv.AcquireClients()}, nil
+}
+
+func (b *directBackend) Close() {
+	b.clients.Release()
+	tikv.CloseClients(tikv.DefaultDrainTimeout)
+}
+
+// writeTxn 在一个事务中执行 fn 并按默认提交选项提交
+func (b *directBackend) writeTxn(ctx context.Context, fn func(txn *txnkv.KVTxn) error) error {
+	txn, err := b.clients.Txn().Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(txn); err != nil {
+		txn.Rollback()
+		return err
+	}
+	_, err = tikv.CommitWithOptions(ctx, txn, tikv.DefaultCommitOptions())
+	return err
+}
+
+func (b *directBackend) Get(ctx context.Context, mode string, key []byte, ts uint64) ([]byte, error) {
+	if mode == modeRawKV {
+		value, err := b.clients.RawKV().Get(ctx, key)
+		if err == nil && value == nil {
+			return nil, errNotFound
+		}
+		return value, err
+	}
+
+	snapshot, _, err := b.clients.Txn().SnapshotAt(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	value, err := snapshot.Get(ctx, key)
+	if tikverr.IsErrNotFound(err) {
+		return nil, errNotFound
+	}
+	return value, err
+}
+
+func (b *directBackend) Put(ctx context.Context, mode string, key, value []byte) error {
+	return b.BatchPut(ctx, mode, []kvPair{{Key: key, Value: value}})
+}
+
+func (b *directBackend) BatchPut(ctx context.Context, mode string, pairs []kvPair) error {
+	if mode == modeRawKV {
+		keys := make([][]byte, len(pairs))
+		values := make([][]byte, len(pairs))
+		for i, pair := range pairs {
+			keys[i], values[i] = pair.Key, pair.Value
+		}
+		return b.clients.RawKV().BatchPut(ctx, keys, values)
+	}
+	return b.writeTxn(ctx, func(txn *txnkv.KVTxn) error {
+		for _, pair := range pairs {
+			if err := txn.Set(pair.Key, pair.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *directBackend) Delete(ctx context.Context, mode string, keys [][]byte) error {
+	if mode == modeRawKV {
+		return b.clients.RawKV().BatchDelete(ctx, keys)
+	}
+	return b.writeTxn(ctx, func(txn *txnkv.KVTxn) error {
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *directBackend) Scan(ctx context.Context, req scanRequest, fn tikv.ScanFunc) error {
+	if req.Mode == modeRawKV {
+		var options []rawkv.RawOption
+		batchSize := tikv.DefaultScanBatchSize
+		if req.KeysOnly {
+			options = append(options, rawkv.ScanKeyOnly())
+			batchSize = rawkv.MaxRawKVScanLimit
+		}
+		return tikv.ScanRawRange(ctx, b.clients.RawKV(), req.Range, req.Reverse, batchSize, fn, options...)
+	}
+
+	snapshot, _, err := b.clients.Txn().SnapshotAt(ctx, req.ReadTS)
+	if err != nil {
+		return err
+	}
+	snapshot.SetKeyOnly(req.KeysOnly)
+	return tikv.ScanSnapshotRange(snapshot, req.Range, req.Reverse, fn)
+}
+
+func (b *directBackend) Stats(ctx context.Context, mode, prefix string, limit int) (*tikv.KVStats, error) {
+	r := tikv.PrefixRange([]byte(prefix))
+	return tikv.ComputeStats(ctx, mode, prefix, limit, func(ctx context.Context, fn tikv.ScanFunc) error {
+		return b.Scan(ctx, scanRequest{Mode: mode, Range: r}, fn)
+	})
+}
+
+// ClusterStatus 从 PD 查询集群拓扑，PD 不可达时返回 unreachable 状态而不是错误
+func (b *directBackend) ClusterStatus(ctx context.Context) (*clusterStatus, error) {
+	endpoints := b.clients.Endpoints()
+	status := &clusterStatus{
+		Endpoints:  endpoints,
+		APIVersion: b.clients.Options().APIVersionName(),
+		Keyspace:   b.clients.Options().Keyspace,
+		KeyspaceID: b.clients.KeyspaceID(),
+	}
+	topology, err := pd.NewClient(endpoints).Topology(ctx)
+	if err != nil {
+		status.ClusterStatus = pd.HealthUnreachable
+		status.Error = err.Error()
+	} else {
+		status.ClusterStatus = topology.Health
+		status.Topology = topology
+	}
+	return status, nil
+}
+
+func (b *directBackend) TSO(ctx context.Context) (tikv.TSOInfo, error) {
+	return tikv.GetTSO(ctx, b.clients.Txn().GetClient())
+}
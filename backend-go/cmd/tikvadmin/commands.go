@@ -0,0 +1,631 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"tikv-backend/pkg/pd"
+	"tikv-backend/pkg/tikv"
+)
+
+// defaultBatchSize import 和 delete-range 每批写入或删除的键数
+const defaultBatchSize = 256
+
+// rangeFlags 扫描范围，与 HTTP API 的 prefix / start / end 参数含义相同
+type rangeFlags struct {
+	prefix         string
+	start          string
+	end            string
+	startExclusive bool
+	endInclusive   bool
+}
+
+func (r *rangeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&r.prefix, "prefix", "", "only keys with this prefix")
+	fs.StringVar(&r.start, "start", "", "first key of the range (inclusive unless --start-exclusive)")
+	fs.StringVar(&r.end, "end", "", "end of the range (exclusive unless --end-inclusive)")
+	fs.BoolVar(&r.startExclusive, "start-exclusive", false, "exclude --start from the range")
+	fs.BoolVar(&r.endInclusive, "end-inclusive", false, "include --end in the range")
+}
+
+// isSet 是否指定了前缀或起止键
+func (r *rangeFlags) isSet() bool {
+	return r.prefix != "" || r.start != "" || r.end != ""
+}
+
+// keyRange 按 key 编码解析范围，同时指定前缀和起止键时取两者的交集
+func (r *rangeFlags) keyRange(keys codec) (tikv.KeyRange, error) {
+	prefix, err := keys.decode(r.prefix)
+	if err != nil {
+		return tikv.KeyRange{}, usagef("invalid --prefix: %v", err)
+	}
+	var bounds tikv.KeyRange
+	if r.start != "" {
+		if bounds.Start, err = keys.decode(r.start); err != nil {
+			return tikv.KeyRange{}, usagef("invalid --start: %v", err)
+		}
+		if r.startExclusive {
+			bounds.Start = tikv.KeyAfter(bounds.Start)
+		}
+	}
+	if r.end != "" {
+		if bounds.End, err = keys.decode(r.end); err != nil {
+			return tikv.KeyRange{}, usagef("invalid --end: %v", err)
+		}
+		if r.endInclusive {
+			bounds.End = tikv.KeyAfter(bounds.End)
+		}
+	}
+	return tikv.PrefixRange(prefix).Intersect(bounds), nil
+}
+
+// parseReadTS 解析 --ts，只有 Txn 模式支持历史快照读取
+func (c *cli) parseReadTS(ts string) (uint64, error) {
+	if ts == "" {
+		return 0, nil
+	}
+	if c.opts.mode != modeTxn {
+		return 0, usagef("--ts is only supported with --type txn")
+	}
+	readTS, err := tikv.ParseTimestamp(ts)
+	if err != nil {
+		return 0, usagef("invalid --ts: %v", err)
+	}
+	return readTS, nil
+}
+
+// kvRecord 输出的一个键值对，只扫描 key 时没有 value
+type kvRecord struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value,omitempty"`
+}
+
+// exportRecord 导出文件中的一行，key 和 value 按 --encoding 编码
+type exportRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (c *cli) kvPrinter(keysOnly bool) *listPrinter {
+	if keysOnly {
+		return newListPrinter(c.stdout, c.opts.output, "KEY")
+	}
+	return newListPrinter(c.stdout, c.opts.output, "KEY", "VALUE")
+}
+
+func (c *cli) printPair(p *listPrinter, key, value []byte, keysOnly bool) error {
+	record := kvRecord{Key: c.keys.encode(key)}
+	if keysOnly {
+		return p.Row(record, record.Key)
+	}
+	encoded := c.values.encode(value)
+	record.Value = &encoded
+	return p.Row(record, record.Key, encoded)
+}
+
+func runGet(c *cli, args []string) error {
+	fs := c.newFlagSet("get", "<key>")
+	ts := fs.String("ts", "", "txn only: read the snapshot at this TSO or RFC3339 time")
+	raw := fs.Bool("raw", false, "write only the value bytes to stdout, ignoring -o and --value-encoding")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usagef("expected exactly one key")
+	}
+	key, err := c.keys.decode(args[0])
+	if err != nil {
+		return usagef("invalid key: %v", err)
+	}
+	readTS, err := c.parseReadTS(*ts)
+	if err != nil {
+		return err
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		value, err := b.Get(ctx, c.opts.mode, key, readTS)
+		if err != nil {
+			return err
+		}
+		if *raw {
+			_, err = c.stdout.Write(value)
+			return err
+		}
+		p := c.kvPrinter(false)
+		if err := c.printPair(p, key, value, false); err != nil {
+			return err
+		}
+		return p.Close()
+	})
+}
+
+func runPut(c *cli, args []string) error {
+	fs := c.newFlagSet("put", "<key> [value]")
+	valueFile := fs.String("value-file", "", `read the value bytes from this file, "-" for stdin; --value-encoding is not applied`)
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return usagef("expected a key and a value")
+	}
+	key, err := c.keys.decode(args[0])
+	if err != nil {
+		return usagef("invalid key: %v", err)
+	}
+
+	var value []byte
+	switch {
+	case len(args) == 2 && *valueFile != "":
+		return usagef("give the value either as an argument or with --value-file")
+	case len(args) == 2:
+		if value, err = c.values.decode(args[1]); err != nil {
+			return usagef("invalid value: %v", err)
+		}
+	case *valueFile == "-":
+		if value, err = io.ReadAll(c.stdin); err != nil {
+			return err
+		}
+	case *valueFile != "":
+		if value, err = os.ReadFile(*valueFile); err != nil {
+			return err
+		}
+	default:
+		return usagef("missing value: give it as an argument or with --value-file")
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		if err := b.Put(ctx, c.opts.mode, key, value); err != nil {
+			return err
+		}
+		result := map[string]any{"key": c.keys.encode(key), "value_bytes": len(value)}
+		return printObject(c.stdout, c.opts.output, result,
+			field{"KEY", result["key"]}, field{"VALUE BYTES", len(value)})
+	})
+}
+
+func runDelete(c *cli, args []string) error {
+	fs := c.newFlagSet("delete", "<key>...")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("expected at least one key")
+	}
+	keys := make([][]byte, len(args))
+	for i, arg := range args {
+		if keys[i], err = c.keys.decode(arg); err != nil {
+			return usagef("invalid key %q: %v", arg, err)
+		}
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		if err := b.Delete(ctx, c.opts.mode, keys); err != nil {
+			return err
+		}
+		result := map[string]any{"deleted": len(keys)}
+		return printObject(c.stdout, c.opts.output, result, field{"DELETED", len(keys)})
+	})
+}
+
+func runScan(c *cli, args []string) error {
+	fs := c.newFlagSet("scan", "")
+	var rf rangeFlags
+	rf.register(fs)
+	reverse := fs.Bool("reverse", false, "scan from the end of the range backwards")
+	limit := fs.Int("limit", 100, "stop after this many keys; 0 means no limit")
+	keysOnly := fs.Bool("keys-only", false, "only list keys; values are not read")
+	ts := fs.String("ts", "", "txn only: read the snapshot at this TSO or RFC3339 time")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v; use --prefix, --start and --end", args)
+	}
+	if *limit < 0 {
+		return usagef("--limit must not be negative")
+	}
+	req := scanRequest{Mode: c.opts.mode, Reverse: *reverse, KeysOnly: *keysOnly}
+	if req.Range, err = rf.keyRange(c.keys); err != nil {
+		return err
+	}
+	if req.ReadTS, err = c.parseReadTS(*ts); err != nil {
+		return err
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		p := c.kvPrinter(*keysOnly)
+		var writeErr error
+		err := b.Scan(ctx, req, func(key, value []byte) bool {
+			if writeErr = c.printPair(p, key, value, *keysOnly); writeErr != nil {
+				return false
+			}
+			return *limit == 0 || p.count < *limit
+		})
+		if err == nil {
+			err = writeErr
+		}
+		if closeErr := p.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+func runExport(c *cli, args []string) error {
+	fs := c.newFlagSet("export", "")
+	var rf rangeFlags
+	rf.register(fs)
+	file := fs.String("file", "", "write to this file instead of stdout")
+	encoding := fs.String("encoding", encodingBase64, "encoding of keys and values in the file: base64, hex or utf8 (utf8 is lossy for binary data)")
+	ts := fs.String("ts", "", "txn only: export the snapshot at this TSO or RFC3339 time")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v; use --prefix, --start and --end", args)
+	}
+	fileCodec, err := parseCodec(*encoding)
+	if err != nil {
+		return usageError{err}
+	}
+	req := scanRequest{Mode: c.opts.mode}
+	if req.Range, err = rf.keyRange(c.keys); err != nil {
+		return err
+	}
+	if req.ReadTS, err = c.parseReadTS(*ts); err != nil {
+		return err
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		out := c.stdout
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		w := bufio.NewWriter(out)
+		encoder := json.NewEncoder(w)
+		count := 0
+		var writeErr error
+		err := b.Scan(ctx, req, func(key, value []byte) bool {
+			writeErr = encoder.Encode(exportRecord{Key: fileCodec.encode(key), Value: fileCodec.encode(value)})
+			count++
+			return writeErr == nil
+		})
+		if err == nil {
+			err = writeErr
+		}
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			return err
+		}
+
+		// 导出到文件时再输出导出的键数，导出到标准输出时只有数据
+		if *file == "" {
+			return nil
+		}
+		if f, ok := out.(*os.File); ok {
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+		result := map[string]any{"exported": count, "file": *file}
+		return printObject(c.stdout, c.opts.output, result, field{"EXPORTED", count}, field{"FILE", *file})
+	})
+}
+
+// readImport 逐行读取导入文件，每 batchSize 条调用一次 fn；空行跳过
+func readImport(r io.Reader, fileCodec codec, batchSize int, fn func(batch []kvPair) error) error {
+	scanner := bufio.NewScanner(r)
+	// 单行最大 64 MiB，足够容纳 TiKV 允许的最大 value
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	batch := make([]kvPair, 0, batchSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record exportRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		key, err := fileCodec.decode(record.Key)
+		if err == nil && len(key) == 0 {
+			err = fmt.Errorf("empty key")
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: invalid key: %v", line, err)
+		}
+		value, err := fileCodec.decode(record.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid value: %v", line, err)
+		}
+		batch = append(batch, kvPair{Key: key, Value: value})
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func runImport(c *cli, args []string) error {
+	fs := c.newFlagSet("import", "[file]")
+	batchSize := fs.Int("batch-size", defaultBatchSize, "keys written per request; with --type txn each batch is one transaction")
+	encoding := fs.String("encoding", encodingBase64, "encoding of keys and values in the file: base64, hex or utf8")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	fileCodec, err := parseCodec(*encoding)
+	if err != nil {
+		return usageError{err}
+	}
+	if len(args) > 1 {
+		return usagef("expected at most one file")
+	}
+	if *batchSize <= 0 {
+		return usagef("--batch-size must be positive")
+	}
+
+	in := c.stdin
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		count := 0
+		err := readImport(in, fileCodec, *batchSize, func(batch []kvPair) error {
+			if err := b.BatchPut(ctx, c.opts.mode, batch); err != nil {
+				return err
+			}
+			count += len(batch)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("imported %d keys before failing: %v", count, err)
+		}
+		result := map[string]any{"imported": count}
+		return printObject(c.stdout, c.opts.output, result, field{"IMPORTED", count})
+	})
+}
+
+func runDeleteRange(c *cli, args []string) error {
+	fs := c.newFlagSet("delete-range", "")
+	var rf rangeFlags
+	rf.register(fs)
+	all := fs.Bool("all", false, "delete every key when no prefix or range is given")
+	dryRun := fs.Bool("dry-run", false, "only count the keys that would be deleted")
+	batchSize := fs.Int("batch-size", defaultBatchSize, "keys deleted per request; with --type txn each batch is one transaction")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v; use --prefix, --start and --end", args)
+	}
+	if !rf.isSet() && !*all {
+		return usagef("give --prefix, --start or --end, or --all to delete every key")
+	}
+	if *batchSize <= 0 {
+		return usagef("--batch-size must be positive")
+	}
+	r, err := rf.keyRange(c.keys)
+	if err != nil {
+		return err
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		var count int
+		var err error
+		if *dryRun {
+			err = b.Scan(ctx, scanRequest{Mode: c.opts.mode, Range: r, KeysOnly: true}, func(_, _ []byte) bool {
+				count++
+				return true
+			})
+		} else {
+			count, err = deleteRange(ctx, b, c.opts.mode, r, *batchSize)
+		}
+		if err != nil {
+			return fmt.Errorf("deleted %d keys before failing: %v", count, err)
+		}
+
+		result := map[string]any{"deleted": count}
+		fields := []field{{"DELETED", count}}
+		if *dryRun {
+			result = map[string]any{"matched": count, "dry_run": true}
+			fields = []field{{"MATCHED", count}, {"DRY RUN", true}}
+		}
+		return printObject(c.stdout, c.opts.output, result, fields...)
+	})
+}
+
+// deleteRange 分批扫描并删除范围内的键，返回删除的键数。
+// 每批删除后从最后一个键之后重新扫描，Txn 模式每批是一个事务，整个范围的删除不是原子的。
+func deleteRange(ctx context.Context, b backend, mode string, r tikv.KeyRange, batchSize int) (int, error) {
+	deleted := 0
+	for !r.IsEmpty() {
+		keys := make([][]byte, 0, batchSize)
+		err := b.Scan(ctx, scanRequest{Mode: mode, Range: r, KeysOnly: true}, func(key, _ []byte) bool {
+			keys = append(keys, append([]byte{}, key...))
+			return len(keys) < batchSize
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		if err := b.Delete(ctx, mode, keys); err != nil {
+			return deleted, err
+		}
+		deleted += len(keys)
+		if len(keys) < batchSize {
+			break
+		}
+		r.Start = tikv.KeyAfter(keys[len(keys)-1])
+	}
+	return deleted, nil
+}
+
+func runStats(c *cli, args []string) error {
+	fs := c.newFlagSet("stats", "")
+	prefix := fs.String("prefix", "", "only count keys with this prefix")
+	limit := fs.Int("limit", 0, "stop counting after this many keys, so the result is a lower bound; 0 scans the whole prefix (ignored with --server)")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v; use --prefix", args)
+	}
+	if *limit < 0 {
+		return usagef("--limit must not be negative")
+	}
+	prefixBytes, err := c.keys.decode(*prefix)
+	if err != nil {
+		return usagef("invalid --prefix: %v", err)
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		stats, err := b.Stats(ctx, c.opts.mode, string(prefixBytes), *limit)
+		if err != nil {
+			return err
+		}
+		fields := []field{
+			{"MODE", stats.Mode},
+			{"PREFIX", c.keys.encode([]byte(stats.Prefix))},
+			{"KEYS", stats.KeyCount},
+			{"KEY BYTES", stats.KeyBytes},
+			{"VALUE BYTES", stats.ValueBytes},
+			{"EXACT", stats.Exact},
+			{"DURATION", fmt.Sprintf("%dms", stats.DurationMs)},
+		}
+		for i, largest := range stats.LargestKeys {
+			fields = append(fields, field{fmt.Sprintf("LARGEST #%d", i+1),
+				fmt.Sprintf("%s (%d bytes)", c.keys.encode([]byte(largest.Key)), largest.ValueSize)})
+		}
+		return printObject(c.stdout, c.opts.output, stats, fields...)
+	})
+}
+
+func runCluster(c *cli, args []string) error {
+	fs := c.newFlagSet("cluster", "[status]")
+	check := fs.Bool("check", false, "exit with status 1 unless the cluster is healthy")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 || (len(args) == 1 && args[0] != "status") {
+		return usagef("unknown cluster subcommand %v; only status is supported", args)
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		status, err := b.ClusterStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.printClusterStatus(status); err != nil {
+			return err
+		}
+		if *check && status.ClusterStatus != pd.HealthHealthy {
+			return fmt.Errorf("cluster is %s", status.ClusterStatus)
+		}
+		return nil
+	})
+}
+
+// printClusterStatus table 格式先输出集群概况，再分别列出 PD 成员和 TiKV 节点
+func (c *cli) printClusterStatus(status *clusterStatus) error {
+	if c.opts.output != outputTable {
+		return printObject(c.stdout, c.opts.output, status)
+	}
+
+	fields := []field{
+		{"STATUS", status.ClusterStatus},
+		{"ENDPOINTS", strings.Join(status.Endpoints, ",")},
+		{"API VERSION", status.APIVersion},
+		{"KEYSPACE", status.Keyspace},
+		{"KEYSPACE ID", status.KeyspaceID},
+	}
+	if status.Error != "" {
+		fields = append(fields, field{"ERROR", status.Error})
+	}
+	topology := status.Topology
+	if topology != nil {
+		fields = append(fields, field{"CLUSTER ID", topology.ClusterID}, field{"PD LEADER", topology.Leader})
+		for _, problem := range topology.Problems {
+			fields = append(fields, field{"PROBLEM", problem})
+		}
+	}
+	if err := printObject(c.stdout, outputTable, status, fields...); err != nil || topology == nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout)
+	members := newListPrinter(c.stdout, outputTable, "PD MEMBER", "CLIENT URLS", "VERSION", "LEADER", "HEALTHY")
+	for _, m := range topology.Members {
+		members.Row(m, m.Name, strings.Join(m.ClientURLs, ","), m.Version, strconv.FormatBool(m.IsLeader), strconv.FormatBool(m.Healthy))
+	}
+	if err := members.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout)
+	stores := newListPrinter(c.stdout, outputTable, "STORE", "ADDRESS", "STATE", "VERSION", "CAPACITY", "AVAILABLE", "REGIONS", "LEADERS")
+	for _, s := range topology.Stores {
+		stores.Row(s, strconv.FormatUint(s.ID, 10), s.Address, s.State, s.Version,
+			humanBytes(uint64(s.Capacity)), humanBytes(uint64(s.Available)), strconv.Itoa(s.RegionCount), strconv.Itoa(s.LeaderCount))
+	}
+	return stores.Close()
+}
+
+func runTSO(c *cli, args []string) error {
+	fs := c.newFlagSet("tso", "")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	return c.withBackend(func(ctx context.Context, b backend) error {
+		info, err := b.TSO(ctx)
+		if err != nil {
+			return err
+		}
+		return printObject(c.stdout, c.opts.output, info,
+			field{"TS", info.TS}, field{"PHYSICAL", info.Physical}, field{"LOGICAL", info.Logical}, field{"TIME", info.Time})
+	})
+}
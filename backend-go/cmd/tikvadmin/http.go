@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tikv-backend/pkg/tikv"
+)
+
+// httpScanBatchSize 通过 HTTP API 扫描时每次请求的键数
+const httpScanBatchSize = 500
+
+// statsPollInterval 通过 HTTP API 统计时查询统计任务进度的间隔
+const statsPollInterval = 500 * time.Millisecond
+
+// httpBackend 通过后端服务的 /api/kv 接口访问当前连接的集群。
+// 请求和响应的 key、value 都是 JSON 字符串，因此只支持 UTF-8 数据，二进制数据需要直接连接 PD。
+type httpBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newHTTPBackend(server string) (*httpBackend, error) {
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q: must be http(s)://host:port", server)
+	}
+	return &httpBackend{
+		baseURL: strings.TrimRight(server, "/") + "/api/kv",
+		client:  &http.Client{},
+	}, nil
+}
+
+func (b *httpBackend) Close() {}
+
+// apiResponse 后端的通用响应结构
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// apiError 后端返回的失败响应
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.Status, e.Message)
+}
+
+// call 发送请求并把响应的 data 解析到 out，success 为 false 时返回 apiError
+func (b *httpBackend) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := b.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("server returned %d with an invalid body: %v", resp.StatusCode, err)
+	}
+	if !result.Success {
+		message := result.Message
+		if result.Error != "" && !strings.Contains(message, result.Error) {
+			message += ": " + result.Error
+		}
+		return &apiError{Status: resp.StatusCode, Message: message}
+	}
+	if out != nil && len(result.Data) > 0 {
+		return json.Unmarshal(result.Data, out)
+	}
+	return nil
+}
+
+// checkUTF8 检查写入的数据能否用 JSON 字符串原样传给后端
+func checkUTF8(data ...[]byte) error {
+	for _, d := range data {
+		if !utf8.Valid(d) {
+			return fmt.Errorf("the HTTP API only supports UTF-8 keys and values, connect to PD directly for binary data")
+		}
+	}
+	return nil
+}
+
+// Get 通过扫描接口读取 [key, KeyAfter(key)) 范围。key 可能包含 "/" 或与 /stats 等路由同名，
+// 不能放在 /api/kv/:key 的路径中
+func (b *httpBackend) Get(ctx context.Context, mode string, key []byte, ts uint64) ([]byte, error) {
+	if err := checkUTF8(key); err != nil {
+		return nil, err
+	}
+	var value []byte
+	found := false
+	req := scanRequest{Mode: mode, Range: tikv.KeyRange{Start: key, End: tikv.KeyAfter(key)}, ReadTS: ts}
+	err := b.Scan(ctx, req, func(k, v []byte) bool {
+		if bytes.Equal(k, key) {
+			value, found = v, true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errNotFound
+	}
+	return value, nil
+}
+
+func (b *httpBackend) Put(ctx context.Context, mode string, key, value []byte) error {
+	if err := checkUTF8(key, value); err != nil {
+		return err
+	}
+	body := map[string]string{"key": string(key), "value": string(value), "type": mode}
+	return b.call(ctx, http.MethodPost, "", nil, body, nil)
+}
+
+func (b *httpBackend) Delete(ctx context.Context, mode string, keys [][]byte) error {
+	if err := checkUTF8(keys...); err != nil {
+		return err
+	}
+	body := struct {
+		Keys []string `json:"keys"`
+		Type string   `json:"type"`
+	}{Type: mode}
+	for _, key := range keys {
+		body.Keys = append(body.Keys, string(key))
+	}
+	return b.call(ctx, http.MethodDelete, "", nil, body, nil)
+}
+
+// BatchPut RawKV 使用 /batch 逐个写入，Txn 使用 /transaction 在一个事务中写入。
+// /batch 把空 value 当作删除，因此 RawKV 不能写入空 value。
+func (b *httpBackend) BatchPut(ctx context.Context, mode string, pairs []kvPair) error {
+	type operation struct {
+		Type  string `json:"type"`
+		Key   string `json:"key"`
+		Value string `json:"value,omitempty"`
+	}
+	operations := make([]operation, 0, len(pairs))
+	for _, pair := range pairs {
+		if err := checkUTF8(pair.Key, pair.Value); err != nil {
+			return err
+		}
+		op := operation{Type: "put", Key: string(pair.Key), Value: string(pair.Value)}
+		if mode == modeRawKV {
+			if len(pair.Value) == 0 {
+				return fmt.Errorf("key %q: the HTTP API cannot write empty RawKV values", pair.Key)
+			}
+			op.Type = modeRawKV
+		}
+		operations = append(operations, op)
+	}
+	body := map[string]any{"operations": operations}
+
+	if mode == modeTxn {
+		return b.call(ctx, http.MethodPost, "/transaction", nil, body, nil)
+	}
+	var batch struct {
+		Data struct {
+			Results []struct {
+				Key     string `json:"key"`
+				Success bool   `json:"success"`
+				Error   string `json:"error"`
+			} `json:"results"`
+			FailureCount int `json:"failureCount"`
+		} `json:"data"`
+	}
+	if err := b.call(ctx, http.MethodPost, "/batch", nil, body, &batch); err != nil {
+		return err
+	}
+	if batch.Data.FailureCount > 0 {
+		for _, result := range batch.Data.Results {
+			if !result.Success {
+				return fmt.Errorf("%d of %d writes failed, first failure: key %q: %s", batch.Data.FailureCount, len(pairs), result.Key, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Scan 分页请求扫描接口，每页从上一页最后一个 key 之后继续；Txn 模式后续页面使用第一页的读取时间戳，保证读到同一个快照
+func (b *httpBackend) Scan(ctx context.Context, req scanRequest, fn tikv.ScanFunc) error {
+	r := req.Range
+	readTS := req.ReadTS
+	for {
+		query := url.Values{
+			"type":  {req.Mode},
+			"page":  {"1"},
+			"limit": {strconv.Itoa(httpScanBatchSize)},
+		}
+		if r.Start != nil {
+			query.Set("start", string(r.Start))
+		}
+		if r.End != nil {
+			query.Set("end", string(r.End))
+		}
+		if req.Reverse {
+			query.Set("reverse", "true")
+		}
+		if req.KeysOnly {
+			query.Set("keysOnly", "true")
+		}
+		if readTS != 0 {
+			query.Set("ts", strconv.FormatUint(readTS, 10))
+		}
+
+		var page struct {
+			Data []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"data"`
+			ReadTS uint64 `json:"readTs"`
+		}
+		if err := b.call(ctx, http.MethodGet, "", query, nil, &page); err != nil {
+			return err
+		}
+		readTS = page.ReadTS
+
+		for _, pair := range page.Data {
+			if !fn([]byte(pair.Key), []byte(pair.Value)) {
+				return nil
+			}
+		}
+		if len(page.Data) < httpScanBatchSize {
+			return nil
+		}
+		last := []byte(page.Data[len(page.Data)-1].Key)
+		if req.Reverse {
+			r.End = last
+		} else {
+			r.Start = tikv.KeyAfter(last)
+		}
+	}
+}
+
+// Stats 启动一次重新统计任务并等待完成。后端的重新统计总是扫描整个范围，limit 参数不起作用。
+func (b *httpBackend) Stats(ctx context.Context, mode, prefix string, _ int) (*tikv.KVStats, error) {
+	var job tikv.StatsJob
+	body := map[string]string{"type": mode, "prefix": prefix}
+	if err := b.call(ctx, http.MethodPost, "/stats/recount", nil, body, &job); err != nil {
+		return nil, err
+	}
+	for {
+		switch job.Status {
+		case tikv.StatsJobDone:
+			return job.Result, nil
+		case tikv.StatsJobFailed:
+			return nil, fmt.Errorf("stats job %s failed: %s", job.ID, job.Error)
+		case tikv.StatsJobCanceled:
+			return nil, fmt.Errorf("stats job %s was canceled", job.ID)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(statsPollInterval):
+		}
+		if err := b.call(ctx, http.MethodGet, "/stats/jobs/"+url.PathEscape(job.ID), nil, nil, &job); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (b *httpBackend) ClusterStatus(ctx context.Context) (*clusterStatus, error) {
+	var status clusterStatus
+	if err := b.call(ctx, http.MethodGet, "/cluster", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (b *httpBackend) TSO(ctx context.Context) (tikv.TSOInfo, error) {
+	var info tikv.TSOInfo
+	err := b.call(ctx, http.MethodGet, "/tso", nil, nil, &info)
+	return info, err
+}
@@ -0,0 +1,316 @@
+// tikvadmin 是 TiKV Admin 的命令行工具：直接连接 PD，或者通过后端服务的 HTTP API 读写数据、查看统计和集群状态。
+// 输出支持表格、JSON 和 NDJSON，退出码可以在脚本和定时任务中判断。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"tikv-backend/config"
+	"tikv-backend/pkg/logging"
+)
+
+// 退出码
+const (
+	exitOK = 0
+	// exitError 命令执行失败，例如无法连接集群或写入失败
+	exitError = 1
+	// exitUsage 参数或配置错误
+	exitUsage = 2
+	// exitNotFound get 读取的 key 不存在
+	exitNotFound = 3
+)
+
+// usageError 参数错误，退出码为 exitUsage
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// command 一个子命令
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"get", "<key>", "read a key", runGet},
+	{"put", "<key> [value]", "write a key; the value comes from the argument, --value-file or stdin", runPut},
+	{"delete", "<key>...", "delete keys", runDelete},
+	{"scan", "", "list keys in a prefix or range", runScan},
+	{"export", "", "write keys in a prefix or range as NDJSON", runExport},
+	{"import", "[file]", "write keys from an NDJSON file or stdin", runImport},
+	{"delete-range", "", "delete every key in a prefix or range", runDeleteRange},
+	{"stats", "", "count keys and sizes under a prefix", runStats},
+	{"cluster", "[status]", "show PD members, TiKV stores and cluster health", runCluster},
+	{"tso", "", "get a timestamp from PD", runTSO},
+	{"shell", "", "start an interactive shell with history, key completion and transactions", runShell},
+}
+
+// globalOptions 所有子命令共用的选项
+type globalOptions struct {
+	configPath    string
+	pd            string
+	apiVersion    string
+	keyspace      string
+	server        string
+	mode          string
+	output        string
+	keyEncoding   string
+	valueEncoding string
+	timeout       time.Duration
+	logLevel      string
+}
+
+// register 注册共用选项
+func (o *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", os.Getenv("TIKVADMIN_CONFIG"), "backend config file (.json, .yaml/.yml or .toml) with the cluster, TLS and client settings; TIKV_* environment overrides apply")
+	fs.StringVar(&o.pd, "pd", "", "comma-separated PD endpoints, overrides tikv.pd_endpoints")
+	fs.StringVar(&o.apiVersion, "api-version", "", "API version of the cluster (v1, v1ttl or v2), overrides tikv.api_version")
+	fs.StringVar(&o.keyspace, "keyspace", "", "keyspace to use (API v2 only), overrides tikv.keyspace")
+	fs.StringVar(&o.server, "server", os.Getenv("TIKVADMIN_SERVER"), "backend URL, e.g. http://127.0.0.1:3001; when set, requests go through its HTTP API instead of PD")
+	fs.StringVar(&o.mode, "type", modeRawKV, "data mode: rawkv or txn")
+	fs.StringVar(&o.output, "o", outputTable, "output format: table, json or ndjson")
+	fs.StringVar(&o.output, "output", outputTable, "same as -o")
+	fs.StringVar(&o.keyEncoding, "key-encoding", encodingUTF8, "encoding of keys in arguments and output: utf8, hex or base64")
+	fs.StringVar(&o.valueEncoding, "value-encoding", encodingUTF8, "encoding of values in arguments and output: utf8, hex or base64")
+	fs.DurationVar(&o.timeout, "timeout", 0, "abort the command after this long, e.g. 30s; 0 means no limit")
+	fs.StringVar(&o.logLevel, "log-level", "error", "client log level written to stderr")
+}
+
+// cli 一次命令执行的状态
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	opts   globalOptions
+
+	keys   codec
+	values codec
+	// connect 按选项创建后端，测试中替换
+	connect func(ctx context.Context, opts globalOptions) (backend, error)
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, connect: connect}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: tikvadmin <command> [flags] [args]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-13s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, `Run "tikvadmin <command> -h" for the flags of a command.`)
+	fmt.Fprintln(c.stderr, "Exit status: 0 success, 1 failure, 2 invalid arguments or config, 3 key not found.")
+}
+
+// run 执行一个子命令并返回退出码
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return exitOK
+	}
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == args[0] })
+	if i < 0 {
+		fmt.Fprintf(c.stderr, "tikvadmin: unknown command %q\n\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+
+	err := commands[i].run(c, args[1:])
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errNotFound):
+		fmt.Fprintf(c.stderr, "tikvadmin %s: %v\n", args[0], err)
+		return exitNotFound
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(c.stderr, "tikvadmin %s: %v\n", args[0], err)
+		return exitUsage
+	default:
+		fmt.Fprintf(c.stderr, "tikvadmin %s: %v\n", args[0], err)
+		return exitError
+	}
+}
+
+// newFlagSet 创建子命令的 FlagSet 并注册共用选项
+func (c *cli) newFlagSet(cmd, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: tikvadmin %s [flags] %s\n\nFlags:\n", cmd, args)
+		fs.PrintDefaults()
+	}
+	c.opts.register(fs)
+	return fs
+}
+
+// parse 解析参数并检查共用选项，返回位置参数。
+// 选项和位置参数可以交替出现，"--" 之后的参数都作为位置参数，用于以 "-" 开头的 key。
+func (c *cli) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional, rest []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, rest = args[:i], args[i+1:]
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	var err error
+	if err = validateMode(c.opts.mode); err != nil {
+		return nil, usageError{err}
+	}
+	if err = validateOutput(c.opts.output); err != nil {
+		return nil, usageError{err}
+	}
+	if c.keys, err = parseCodec(c.opts.keyEncoding); err != nil {
+		return nil, usageError{err}
+	}
+	if c.values, err = parseCodec(c.opts.valueEncoding); err != nil {
+		return nil, usageError{err}
+	}
+	if c.opts.server != "" && (c.opts.pd != "" || c.opts.apiVersion != "" || c.opts.keyspace != "") {
+		return nil, usagef("--server cannot be combined with --pd, --api-version or --keyspace; the backend uses its current cluster")
+	}
+	return append(positional, rest...), nil
+}
+
+// context 返回命令使用的 context，收到 SIGINT / SIGTERM 或超过 --timeout 时取消
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if c.opts.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// connect 设置为 --server 时使用 HTTP API，否则加载配置文件并直接连接 PD
+func connect(ctx context.Context, opts globalOptions) (backend, error) {
+	if err := logging.Setup(logging.Options{Level: opts.logLevel, Format: logging.FormatConsole}); err != nil {
+		return nil, usageError{err}
+	}
+	if opts.server != "" {
+		b, err := newHTTPBackend(opts.server)
+		if err != nil {
+			return nil, usageError{err}
+		}
+		return b, nil
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	return newDirectBackend(ctx, cfg)
+}
+
+// loadConfig 加载配置文件和环境变量，再应用命令行指定的集群
+func loadConfig(opts globalOptions) (*config.Config, error) {
+	if opts.configPath != "" {
+		if _, err := os.Stat(opts.configPath); err != nil {
+			return nil, fmt.Errorf("config file: %v", err)
+		}
+	}
+	cfg, err := config.LoadConfig(opts.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if opts.pd != "" {
+		cfg.TiKV.PDEndpoints = nil
+		for _, endpoint := range strings.Split(opts.pd, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				cfg.TiKV.PDEndpoints = append(cfg.TiKV.PDEndpoints, endpoint)
+			}
+		}
+	}
+	if opts.apiVersion != "" {
+		cfg.TiKV.APIVersion = opts.apiVersion
+	}
+	if opts.keyspace != "" {
+		cfg.TiKV.Keyspace = opts.keyspace
+	}
+	var errs config.ValidationError
+	errs.Add("", cfg.Validate())
+	if _, err := cfg.TiKV.ClusterOptions(); err != nil {
+		errs.Add("tikv.api_version", err)
+	}
+	errs.Add("tikv.client", cfg.TiKV.Client.Tuning().Validate())
+	errs.Add("", cfg.TiKV.ValidateSecurity())
+	if err := errs.Err(); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	return cfg, nil
+}
+
+// withBackend 连接集群后执行 fn，结束后关闭连接
+func (c *cli) withBackend(fn func(ctx context.Context, b backend) error) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	b, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return fn(ctx, b)
+}
+
+// dial 按选项连接集群。
+// client-go 连接 PD 时不检查 ctx，会一直重试，因此在后台连接，超时或收到信号时直接返回。
+func (c *cli) dial(ctx context.Context) (backend, error) {
+	type connected struct {
+		b   backend
+		err error
+	}
+	done := make(chan connected, 1)
+	go func() {
+		b, err := c.connect(ctx, c.opts)
+		done <- connected{b, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.b, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to connect: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 输出格式
+const (
+	outputTable  = "table"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputNDJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q: must be %s, %s or %s", format, outputTable, outputJSON, outputNDJSON)
+	}
+}
+
+// key / value 的编码方式
+const (
+	encodingUTF8   = "utf8"
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+)
+
+// codec 在命令行参数、输出和导入导出文件中表示 key / value 的编码。
+// utf8 输出时把非法的 UTF-8 字节和控制字符写成 \xNN，只用于显示；二进制数据使用 hex 或 base64 才能原样导入导出。
+type codec string
+
+func parseCodec(name string) (codec, error) {
+	switch name {
+	case encodingUTF8, encodingHex, encodingBase64:
+		return codec(name), nil
+	default:
+		return "", fmt.Errorf("invalid encoding %q: must be %s, %s or %s", name, encodingUTF8, encodingHex, encodingBase64)
+	}
+}
+
+func (c codec) encode(data []byte) string {
+	switch c {
+	case encodingHex:
+		return hex.EncodeToString(data)
+	case encodingBase64:
+		return base64.StdEncoding.EncodeToString(data)
+	default:
+		return escapeUTF8(data)
+	}
+}
+
+func (c codec) decode(s string) ([]byte, error) {
+	switch c {
+	case encodingHex:
+		return hex.DecodeString(s)
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return []byte(s), nil
+	}
+}
+
+// escapeUTF8 原样返回可打印的 UTF-8 字符，非法字节和控制字符写成 \xNN
+func escapeUTF8(data []byte) string {
+	var b strings.Builder
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if (r == utf8.RuneError && size == 1) || (r < utf8.RuneSelf && unicode.IsControl(r)) {
+			fmt.Fprintf(&b, `\x%02x`, data[0])
+		} else {
+			b.Write(data[:size])
+		}
+		data = data[size:]
+	}
+	return b.String()
+}
+
+// listPrinter 逐条输出一组记录：table 按列对齐，json 输出一个数组，ndjson 每条记录一行。
+// 记录边扫描边输出，json 数组在 Close 时才结束。
+type listPrinter struct {
+	w       io.Writer
+	format  string
+	table   *tabwriter.Writer
+	count   int
+	started bool
+	// expanded 为 true 时 table 格式每条记录占多行，每行一个字段，用于较长的 value
+	expanded bool
+	header   []string
+}
+
+func newListPrinter(w io.Writer, format string, header ...string) *listPrinter {
+	p := &listPrinter{w: w, format: format}
+	if format == outputTable {
+		p.table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(p.table, strings.Join(header, "\t"))
+	}
+	return p
+}
+
+// newExpandedPrinter 创建 table 格式的纵向输出，每条记录前有一行序号
+func newExpandedPrinter(w io.Writer, header ...string) *listPrinter {
+	return &listPrinter{
+		w:        w,
+		format:   outputTable,
+		table:    tabwriter.NewWriter(w, 0, 0, 1, ' ', 0),
+		expanded: true,
+		header:   header,
+	}
+}
+
+// Row 输出一条记录，table 格式使用 cells，其他格式使用 record
+func (p *listPrinter) Row(record any, cells ...string) error {
+	p.count++
+	switch {
+	case p.expanded:
+		fmt.Fprintf(p.w, "-[ RECORD %d ]\n", p.count)
+		for i, cell := range cells {
+			fmt.Fprintf(p.table, "%s\t| %s\n", p.header[i], cell)
+		}
+		return p.table.Flush()
+	case p.format == outputTable:
+		_, err := fmt.Fprintln(p.table, strings.Join(cells, "\t"))
+		return err
+	case p.format == outputNDJSON:
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", data)
+		return err
+	default:
+		data, err := json.MarshalIndent(record, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if !p.started {
+			sep = "[\n  "
+			p.started = true
+		}
+		_, err = fmt.Fprintf(p.w, "%s%s", sep, data)
+		return err
+	}
+}
+
+// Close 结束输出
+func (p *listPrinter) Close() error {
+	switch p.format {
+	case outputTable:
+		return p.table.Flush()
+	case outputJSON:
+		if !p.started {
+			_, err := fmt.Fprintln(p.w, "[]")
+			return err
+		}
+		_, err := fmt.Fprintln(p.w, "\n]")
+		return err
+	}
+	return nil
+}
+
+// field 表格输出时的一行字段名和值
+type field struct {
+	name  string
+	value any
+}
+
+// printObject 输出单个对象：table 每个字段一行，json 缩进输出，ndjson 输出一行
+func printObject(w io.Writer, format string, v any, fields ...field) error {
+	switch format {
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, f := range fields {
+			fmt.Fprintf(tw, "%s\t%v\n", f.name, f.value)
+		}
+		return tw.Flush()
+	case outputNDJSON:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+}
+
+// humanBytes 把字节数格式化为 KiB、MiB 等单位
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,849 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"tikv-backend/pkg/tikv"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/txnkv/transaction"
+	"golang.org/x/term"
+)
+
+const (
+	// shellScanLimit scan 语句默认最多输出的键数
+	shellScanLimit = 100
+	// completionLimit 补全时最多列出的 key 数
+	completionLimit = 50
+	// completionTimeout 补全时扫描 key 的超时时间，避免按 Tab 后长时间没有响应
+	completionTimeout = 2 * time.Second
+	// historyLimit 历史文件最多保留的行数
+	historyLimit = 1000
+)
+
+// shellCommand shell 中的一条语句
+type shellCommand struct {
+	name    string
+	args    string
+	summary string
+	// keyArg 补全 key 的参数位置，从 1 开始；0 表示不补全 key，-1 表示所有参数都是 key
+	keyArg int
+	// words 参数可选的固定取值，用于补全
+	words []string
+	run   func(s *shell, ctx context.Context, args []string) error
+}
+
+var shellCommands []shellCommand
+
+func init() {
+	// help 需要遍历 shellCommands，因此在 init 中赋值，避免初始化循环
+	shellCommands = []shellCommand{
+		{name: "get", args: "<key>", summary: "read a key", keyArg: 1, run: shellGet},
+		{name: "put", args: "<key> <value>", summary: "write a key", keyArg: 1, run: shellPut},
+		{name: "delete", args: "<key>...", summary: "delete keys", keyArg: -1, run: shellDelete},
+		{name: "scan", args: "[prefix] [limit]", summary: fmt.Sprintf("list keys under a prefix, at most %d unless a limit is given; 0 means no limit", shellScanLimit), keyArg: 1, run: shellScan},
+		{name: "begin", summary: "start a transaction; get, put, delete and scan then run in it until commit or rollback", run: shellBegin},
+		{name: "commit", summary: "commit the open transaction", run: shellCommit},
+		{name: "rollback", summary: "discard the open transaction", run: shellRollback},
+		{name: "use", args: "[pd-endpoints][/namespace]", summary: "switch to another cluster and/or key namespace; without an argument, show the current ones", run: shellUse},
+		{name: "type", args: "[rawkv|txn]", summary: "data mode of statements outside a transaction", words: []string{modeRawKV, modeTxn}, run: shellType},
+		{name: `\x`, args: "[on|off]", summary: "toggle expanded output, one line per field", words: []string{"on", "off"}, run: shellExpanded},
+		{name: "help", summary: "show this help", run: shellHelp},
+		{name: "exit", summary: "leave the shell; an open transaction is rolled back", run: shellExit},
+	}
+}
+
+// shellAliases 语句的别名
+var shellAliases = map[string]string{
+	`\?`:   "help",
+	"quit": "exit",
+	`\q`:   "exit",
+}
+
+// shell 交互式 shell 的状态。语句通过 RawKv / TxnKv 封装在当前命名空间内读写，key 不包含命名空间前缀。
+type shell struct {
+	c         *cli
+	b         *directBackend
+	mode      string
+	namespace []byte
+	raw       *tikv.RawKv
+	txnKv     *tikv.TxnKv
+	// txn begin 打开的事务，没有事务时为 nil
+	txn      *transaction.KVTxn
+	expanded bool
+	quit     bool
+	// listKeys 列出以 prefix 开头的 key，用于补全，测试中替换
+	listKeys func(ctx context.Context, prefix []byte, limit int) ([][]byte, error)
+}
+
+func runShell(c *cli, args []string) error {
+	fs := c.newFlagSet("shell", "")
+	namespace := fs.String("namespace", "", "key prefix the statements work under, in --key-encoding; empty (the default) means the whole keyspace, where the HTTP API and the other commands read and write")
+	historyPath := fs.String("history", defaultHistoryPath(), "file the command history is kept in; empty disables saving it")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+	if c.opts.server != "" {
+		return usagef("the shell works on the TiKV clients directly and cannot be used with --server")
+	}
+	ns, err := c.keys.decode(*namespace)
+	if err != nil {
+		return usagef("invalid --namespace: %v", err)
+	}
+
+	ctx, cancel := c.context()
+	b, err := c.dial(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	direct, ok := b.(*directBackend)
+	if !ok {
+		return errors.New("the shell needs a direct connection to PD")
+	}
+
+	s := newShell(c, direct, ns)
+	defer s.rollbackOpen()
+	if f, ok := c.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return s.interactive(f, *historyPath)
+	}
+	return s.script(c.stdin)
+}
+
+func newShell(c *cli, b *directBackend, namespace []byte) *shell {
+	s := &shell{c: c, b: b, mode: c.opts.mode}
+	s.listKeys = s.scanKeys
+	s.setNamespace(namespace)
+	return s
+}
+
+// setNamespace 切换命名空间，并在当前集群上重新创建 RawKv / TxnKv
+func (s *shell) setNamespace(namespace []byte) {
+	s.namespace = namespace
+	s.raw = tikv.NewRawKvWithNamespace(namespace)
+	s.txnKv = tikv.NewTxnKvWithNamespace(namespace)
+}
+
+// prompt 显示数据模式和命名空间，有打开的事务时模式后面带 *
+func (s *shell) prompt() string {
+	mode := s.mode
+	if s.txn != nil {
+		mode = modeTxn + "*"
+	}
+	return fmt.Sprintf("%s /%s> ", mode, s.c.keys.encode(s.namespace))
+}
+
+// interactive 在终端中读取语句。只在读取一行时进入 raw 模式，执行语句时 Ctrl-C 仍然发送 SIGINT，只取消当前语句。
+// 在提示符下 Ctrl-C 或 Ctrl-D 退出 shell。
+func (s *shell) interactive(f *os.File, historyPath string) error {
+	fd := int(f.Fd())
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, s.c.stdout}, "")
+	if width, height, err := term.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+	history, err := openHistory(historyPath)
+	if err != nil {
+		fmt.Fprintf(s.c.stderr, "warning: history is not saved: %v\n", err)
+	}
+	defer history.Close()
+	t.History = history
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		newLine, newPos, candidates := s.complete(line, pos)
+		if newLine == line && len(candidates) > 1 {
+			fmt.Fprintln(t, strings.Join(candidates, "  "))
+		}
+		return newLine, newPos, true
+	}
+
+	fmt.Fprintf(s.c.stdout, "Connected to %s. Type \"help\" for the statements, \"exit\" or Ctrl-D to leave.\n", strings.Join(s.b.clients.Endpoints(), ","))
+	for !s.quit {
+		t.SetPrompt(s.prompt())
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := t.ReadLine()
+		term.Restore(fd, state)
+		if err == io.EOF {
+			fmt.Fprintln(s.c.stdout)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.exec(line)
+	}
+	return nil
+}
+
+// script 从管道或文件逐行执行语句，出错时继续执行后面的语句，最后返回失败的语句数
+func (s *shell) script(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	failed := 0
+	for !s.quit && scanner.Scan() {
+		if s.exec(scanner.Text()) != nil {
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d statements failed", failed)
+	}
+	return nil
+}
+
+// exec 解析并执行一行语句，错误写到 stderr
+func (s *shell) exec(line string) error {
+	args, err := splitLine(line)
+	if err == nil && len(args) == 0 {
+		return nil
+	}
+	if err == nil {
+		cmd, ok := lookupShellCommand(args[0])
+		if !ok {
+			err = fmt.Errorf("unknown statement %q, type \"help\" for the statements", args[0])
+		} else {
+			ctx, cancel := s.c.context()
+			err = cmd.run(s, ctx, args[1:])
+			cancel()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(s.c.stderr, "error: %v\n", err)
+	}
+	return err
+}
+
+func lookupShellCommand(name string) (shellCommand, bool) {
+	if alias, ok := shellAliases[name]; ok {
+		name = alias
+	}
+	i := slices.IndexFunc(shellCommands, func(cmd shellCommand) bool { return cmd.name == name })
+	if i < 0 {
+		return shellCommand{}, false
+	}
+	return shellCommands[i], true
+}
+
+// rollbackOpen 退出时回滚还没有提交的事务
+func (s *shell) rollbackOpen() {
+	if s.txn == nil {
+		return
+	}
+	if err := s.txnKv.Rollback(s.txn); err != nil {
+		fmt.Fprintf(s.c.stderr, "warning: failed to roll back the open transaction: %v\n", err)
+		return
+	}
+	s.txn = nil
+	fmt.Fprintln(s.c.stderr, "The open transaction was rolled back.")
+}
+
+// printer 按 -o 和 \x 设置创建键值对的输出
+func (s *shell) printer() *listPrinter {
+	if s.expanded && s.c.opts.output == outputTable {
+		return newExpandedPrinter(s.c.stdout, "KEY", "VALUE")
+	}
+	return s.c.kvPrinter(false)
+}
+
+// get 在打开的事务中读取，否则按数据模式读取最新数据
+func (s *shell) get(ctx context.Context, key []byte) ([]byte, error) {
+	if s.txn == nil && s.mode == modeRawKV {
+		value, err := s.raw.Get(ctx, key)
+		if err == nil && value == nil {
+			return nil, errNotFound
+		}
+		return value, err
+	}
+
+	txn := s.txn
+	if txn == nil {
+		var err error
+		if txn, err = s.txnKv.Begin(); err != nil {
+			return nil, err
+		}
+		defer s.txnKv.Rollback(txn)
+	}
+	value, err := s.txnKv.Get(ctx, txn, key)
+	if tikverr.IsErrNotFound(err) {
+		return nil, errNotFound
+	}
+	return value, err
+}
+
+// write 在打开的事务中执行 fn；没有事务时 Txn 模式在一个新事务中执行并立即提交
+func (s *shell) write(ctx context.Context, fn func(txn *transaction.KVTxn) error) error {
+	if s.txn != nil {
+		return fn(s.txn)
+	}
+	txn, err := s.txnKv.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(txn); err != nil {
+		s.txnKv.Rollback(txn)
+		return err
+	}
+	_, err = s.txnKv.CommitWithOptions(ctx, txn, tikv.DefaultCommitOptions())
+	return err
+}
+
+// walk 按顺序遍历范围，打开的事务中能读到尚未提交的写入
+func (s *shell) walk(ctx context.Context, r tikv.KeyRange, fn tikv.ScanFunc) error {
+	switch {
+	case s.txn != nil:
+		return s.txnKv.WalkTxn(s.txn, r, fn)
+	case s.mode == modeRawKV:
+		return s.raw.Walk(ctx, r, fn)
+	default:
+		return s.txnKv.Walk(ctx, r, fn)
+	}
+}
+
+// scanKeys 列出以 prefix 开头的前 limit 个 key
+func (s *shell) scanKeys(ctx context.Context, prefix []byte, limit int) ([][]byte, error) {
+	var keys [][]byte
+	err := s.walk(ctx, tikv.PrefixRange(prefix), func(key, _ []byte) bool {
+		keys = append(keys, append([]byte{}, key...))
+		return len(keys) < limit
+	})
+	return keys, err
+}
+
+func shellGet(s *shell, ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usagef("usage: get <key>")
+	}
+	key, err := s.c.keys.decode(args[0])
+	if err != nil {
+		return err
+	}
+	value, err := s.get(ctx, key)
+	if errors.Is(err, errNotFound) {
+		fmt.Fprintln(s.c.stdout, "(not found)")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p := s.printer()
+	if err := s.c.printPair(p, key, value, false); err != nil {
+		return err
+	}
+	return p.Close()
+}
+
+func shellPut(s *shell, ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return usagef("usage: put <key> <value>")
+	}
+	key, err := s.c.keys.decode(args[0])
+	if err != nil {
+		return err
+	}
+	value, err := s.c.values.decode(args[1])
+	if err != nil {
+		return err
+	}
+	if s.txn == nil && s.mode == modeRawKV {
+		err = s.raw.Put(ctx, key, value)
+	} else {
+		err = s.write(ctx, func(txn *transaction.KVTxn) error {
+			return s.txnKv.Set(txn, key, value)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.c.stdout, "OK")
+	return nil
+}
+
+func shellDelete(s *shell, ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: delete <key>...")
+	}
+	keys := make([][]byte, 0, len(args))
+	for _, arg := range args {
+		key, err := s.c.keys.decode(arg)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	var err error
+	if s.txn == nil && s.mode == modeRawKV {
+		err = s.raw.BatchDelete(ctx, keys)
+	} else {
+		err = s.write(ctx, func(txn *transaction.KVTxn) error {
+			for _, key := range keys {
+				if err := s.txnKv.Delete(txn, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.c.stdout, "OK, %d deleted\n", len(keys))
+	return nil
+}
+
+func shellScan(s *shell, ctx context.Context, args []string) error {
+	if len(args) > 2 {
+		return usagef("usage: scan [prefix] [limit]")
+	}
+	var prefix []byte
+	if len(args) > 0 {
+		var err error
+		if prefix, err = s.c.keys.decode(args[0]); err != nil {
+			return err
+		}
+	}
+	limit := shellScanLimit
+	if len(args) > 1 {
+		var err error
+		if limit, err = strconv.Atoi(args[1]); err != nil || limit < 0 {
+			return usagef("invalid limit %q: must be a non-negative number", args[1])
+		}
+	}
+
+	p := s.printer()
+	var writeErr error
+	more := false
+	err := s.walk(ctx, tikv.PrefixRange(prefix), func(key, value []byte) bool {
+		if limit > 0 && p.count == limit {
+			more = true
+			return false
+		}
+		writeErr = s.c.printPair(p, key, value, false)
+		return writeErr == nil
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if closeErr := p.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if s.c.opts.output == outputTable {
+		if more {
+			fmt.Fprintf(s.c.stdout, "(%d keys, more remain; pass a larger limit or 0)\n", p.count)
+		} else {
+			fmt.Fprintf(s.c.stdout, "(%d keys)\n", p.count)
+		}
+	}
+	return nil
+}
+
+func shellBegin(s *shell, _ context.Context, args []string) error {
+	if len(args) != 0 {
+		return usagef("usage: begin")
+	}
+	if s.txn != nil {
+		return errors.New("a transaction is already open; commit or rollback first")
+	}
+	txn, err := s.txnKv.Begin()
+	if err != nil {
+		return err
+	}
+	s.txn = txn
+	fmt.Fprintf(s.c.stdout, "BEGIN (start ts %d)\n", txn.StartTS())
+	return nil
+}
+
+func shellCommit(s *shell, ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return usagef("usage: commit")
+	}
+	if s.txn == nil {
+		return errors.New("no open transaction")
+	}
+	// 提交失败后事务不能再使用，同样结束
+	txn := s.txn
+	s.txn = nil
+	result, err := s.txnKv.CommitWithOptions(ctx, txn, tikv.DefaultCommitOptions())
+	if err != nil {
+		return fmt.Errorf("commit failed, the transaction is closed: %v", err)
+	}
+	fmt.Fprintf(s.c.stdout, "COMMIT (commit ts %d, %s)\n", result.CommitTS, result.Protocol)
+	return nil
+}
+
+func shellRollback(s *shell, _ context.Context, args []string) error {
+	if len(args) != 0 {
+		return usagef("usage: rollback")
+	}
+	if s.txn == nil {
+		return errors.New("no open transaction")
+	}
+	txn := s.txn
+	s.txn = nil
+	if err := s.txnKv.Rollback(txn); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.c.stdout, "ROLLBACK")
+	return nil
+}
+
+// shellUse 切换集群或命名空间。集群沿用当前的 API 版本、keyspace、TLS 和客户端参数，连接验证成功后才替换当前的客户端。
+func shellUse(s *shell, ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usagef("usage: use [pd-endpoints][/namespace]")
+	}
+	if len(args) == 1 {
+		if s.txn != nil {
+			return errors.New("commit or rollback the open transaction first")
+		}
+		cluster, namespace, hasNamespace, err := parseUse(args[0], s.c.keys)
+		if err != nil {
+			return err
+		}
+		if len(cluster) > 0 {
+			if err := tikv.SwapClients(ctx, cluster, s.b.clients.Options(), tikv.DefaultDrainTimeout); err != nil {
+				return fmt.Errorf("failed to connect to %v: %v", cluster, err)
+			}
+			s.b.clients.Release()
+			s.b.clients = tikv.AcquireClients()
+		}
+		if !hasNamespace {
+			namespace = s.namespace
+		}
+		s.setNamespace(namespace)
+	}
+
+	opts := s.b.clients.Options()
+	fields := []field{
+		{"Cluster", strings.Join(s.b.clients.Endpoints(), ",")},
+		{"API version", opts.APIVersionName()},
+		{"Keyspace", opts.Keyspace},
+		{"Namespace", s.c.keys.encode(s.namespace)},
+	}
+	return printObject(s.c.stdout, outputTable, nil, fields...)
+}
+
+// parseUse 解析 use 的参数 [pd-endpoints][/namespace]。PD 地址中没有 "/"，第一个 "/" 之后都是命名空间，
+// 因此 "use pd:2379" 只切换集群，"use /app_" 只切换命名空间，"use /" 访问整个 keyspace。
+func parseUse(arg string, keys codec) (endpoints []string, namespace []byte, hasNamespace bool, err error) {
+	cluster, ns, hasNamespace := strings.Cut(arg, "/")
+	for _, endpoint := range strings.Split(cluster, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 && !hasNamespace {
+		return nil, nil, false, usagef("usage: use [pd-endpoints][/namespace]")
+	}
+	if hasNamespace {
+		if namespace, err = keys.decode(ns); err != nil {
+			return nil, nil, false, fmt.Errorf("invalid namespace: %v", err)
+		}
+	}
+	return endpoints, namespace, hasNamespace, nil
+}
+
+func shellType(s *shell, _ context.Context, args []string) error {
+	if len(args) > 1 {
+		return usagef("usage: type [rawkv|txn]")
+	}
+	if len(args) == 1 {
+		if err := validateMode(args[0]); err != nil {
+			return err
+		}
+		s.mode = args[0]
+	}
+	fmt.Fprintf(s.c.stdout, "Statements outside a transaction use %s.\n", s.mode)
+	return nil
+}
+
+func shellExpanded(s *shell, _ context.Context, args []string) error {
+	switch {
+	case len(args) == 0:
+		s.expanded = !s.expanded
+	case len(args) == 1 && (args[0] == "on" || args[0] == "off"):
+		s.expanded = args[0] == "on"
+	default:
+		return usagef(`usage: \x [on|off]`)
+	}
+	state := "off"
+	if s.expanded {
+		state = "on"
+	}
+	fmt.Fprintf(s.c.stdout, "Expanded display is %s.\n", state)
+	return nil
+}
+
+func shellHelp(s *shell, _ context.Context, _ []string) error {
+	p := newListPrinter(s.c.stdout, outputTable, "STATEMENT", "DESCRIPTION")
+	for _, cmd := range shellCommands {
+		p.Row(nil, strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.c.stdout)
+	fmt.Fprintln(s.c.stdout, `Arguments are separated by spaces; quote them with '...' or "..." (Go escapes such as \x00 work in double quotes).`)
+	fmt.Fprintln(s.c.stdout, "Keys and values use --key-encoding and --value-encoding. Press Tab to complete statements and key prefixes.")
+	return nil
+}
+
+func shellExit(s *shell, _ context.Context, _ []string) error {
+	s.quit = true
+	return nil
+}
+
+// splitLine 把一行语句按空白切分为参数。单引号内的内容原样保留，双引号内按 Go 字符串转义；
+// 相邻的引号和普通字符合并为一个参数，例如 a" b" 是 "a b"。引号外的反斜杠不是转义，因此 \x 保持原样。
+func splitLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	for i := 0; i < len(line); {
+		switch ch := line[i]; ch {
+		case ' ', '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+			i++
+		case '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated double quote")
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string %s: %v", line[i:end+1], err)
+			}
+			arg.WriteString(unquoted)
+			inArg = true
+			i = end + 1
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			arg.WriteString(line[i+1 : i+1+end])
+			inArg = true
+			i += end + 2
+		default:
+			arg.WriteByte(ch)
+			inArg = true
+			i++
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// quoteArg 在需要时给参数加上双引号，使 splitLine 能原样解析回来
+func quoteArg(arg string) string {
+	if arg != "" && utf8.ValidString(arg) && !strings.ContainsAny(arg, ` "'`) &&
+		!strings.ContainsFunc(arg, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return arg
+	}
+	return strconv.Quote(arg)
+}
+
+// complete 补全光标前的最后一个参数：第一个参数补全语句名，key 参数按前缀扫描补全，固定取值的参数补全取值。
+// 返回补全后的行和光标位置，以及所有候选项；不能补全时返回原来的行。
+func (s *shell) complete(line string, pos int) (string, int, []string) {
+	head := line[:pos]
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+	prev, err := splitLine(head[:start])
+	if err != nil {
+		return line, pos, nil
+	}
+
+	var candidates []string
+	if len(prev) == 0 {
+		for _, cmd := range shellCommands {
+			if strings.HasPrefix(cmd.name, word) {
+				candidates = append(candidates, cmd.name)
+			}
+		}
+	} else {
+		cmd, ok := lookupShellCommand(prev[0])
+		if !ok {
+			return line, pos, nil
+		}
+		if cmd.keyArg == -1 || cmd.keyArg == len(prev) {
+			candidates = s.completeKeys(word)
+		}
+		for _, w := range cmd.words {
+			if len(prev) == 1 && strings.HasPrefix(w, word) {
+				candidates = append(candidates, w)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return line, pos, nil
+	}
+
+	replacement := candidates[0] + " "
+	if len(candidates) > 1 {
+		replacement = candidates[0]
+		for _, candidate := range candidates[1:] {
+			n := 0
+			for n < len(replacement) && n < len(candidate) && replacement[n] == candidate[n] {
+				n++
+			}
+			replacement = replacement[:n]
+		}
+		if len(replacement) <= len(word) || !strings.HasPrefix(replacement, word) {
+			return line, pos, candidates
+		}
+	}
+	return head[:start] + replacement + line[pos:], start + len(replacement), candidates
+}
+
+// completeKeys 按参数中已输入的前缀扫描 key，返回可以直接作为参数的写法。
+// 参数中只有开头的引号时按未结束的字符串处理；前缀不能按 --key-encoding 解码时不补全。
+func (s *shell) completeKeys(word string) []string {
+	partial := word
+	switch {
+	case strings.HasPrefix(word, `"`):
+		unquoted, err := strconv.Unquote(word + `"`)
+		if err != nil {
+			return nil
+		}
+		partial = unquoted
+	case strings.HasPrefix(word, `'`):
+		partial = word[1:]
+	case strings.ContainsAny(word, `"'`):
+		return nil
+	}
+	prefix, err := s.c.keys.decode(partial)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	keys, err := s.listKeys(ctx, prefix, completionLimit)
+	if err != nil {
+		return nil
+	}
+	candidates := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if s.c.keys == encodingUTF8 {
+			candidates = append(candidates, quoteArg(string(key)))
+		} else {
+			candidates = append(candidates, s.c.keys.encode(key))
+		}
+	}
+	return candidates
+}
+
+// defaultHistoryPath 历史文件的默认位置：TIKVADMIN_HISTORY，否则是用户主目录下的 .tikvadmin_history
+func defaultHistoryPath() string {
+	if path, ok := os.LookupEnv("TIKVADMIN_HISTORY"); ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tikvadmin_history")
+}
+
+// fileHistory 实现 term.History，每条语句追加写入历史文件，下次启动时读回
+type fileHistory struct {
+	// entries 从旧到新
+	entries []string
+	file    *os.File
+}
+
+// openHistory 读取历史文件并打开用于追加，文件超过 historyLimit 行时只保留最新的部分。
+// path 为空或文件无法打开时返回只保存在内存中的历史和错误。
+func openHistory(path string) (*fileHistory, error) {
+	h := &fileHistory{}
+	if path == "" {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > historyLimit {
+		h.entries = h.entries[len(h.entries)-historyLimit:]
+		if err := os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600); err != nil {
+			return h, err
+		}
+	}
+	if h.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// Add 记录一条语句，忽略空行和与上一条相同的语句
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > historyLimit {
+		h.entries = h.entries[1:]
+	}
+	if h.file != nil {
+		h.file.WriteString(entry + "\n")
+	}
+}
+
+func (h *fileHistory) Len() int { return len(h.entries) }
+
+// At 返回第 idx 新的语句，0 是最新的
+func (h *fileHistory) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
+
+func (h *fileHistory) Close() error {
+	if h.file == nil {
+		return nil
+	}
+	return h.file.Close()
+}
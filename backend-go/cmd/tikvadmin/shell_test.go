@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSplitLine 检查引号、转义和 \x 这类以反斜杠开头的语句
+func TestSplitLine(t *testing.T) {
+	for line, want := range map[string][]string{
+		"  get   user:1 ":        {"get", "user:1"},
+		`put "a b" 'c "d"'`:      {"put", "a b", `c "d"`},
+		`put "k\x00\n" v`:        {"put", "k\x00\n", "v"},
+		`put a" b"c ''`:          {"put", "a bc", ""},
+		`\x on`:                  {`\x`, "on"},
+		`scan 'it'"em:" 10`:      {"scan", "item:", "10"},
+		`get "escaped \" quote"`: {"get", `escaped " quote`},
+	} {
+		got, err := splitLine(line)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("splitLine(%q) = %q, %v; want %q", line, got, err, want)
+		}
+	}
+	for _, bad := range []string{`get "abc`, `get 'abc`, `get "\q"`} {
+		if _, err := splitLine(bad); err == nil {
+			t.Errorf("splitLine(%q) should fail", bad)
+		}
+	}
+
+	for _, arg := range []string{"user:1", "a b", `x"y`, "k\x00\xff", "", `\x`} {
+		if got, err := splitLine(quoteArg(arg)); err != nil || len(got) != 1 || got[0] != arg {
+			t.Errorf("quoteArg(%q) = %s does not round trip: %q, %v", arg, quoteArg(arg), got, err)
+		}
+	}
+}
+
+// TestShellComplete 检查语句名、key 前缀和固定取值的补全
+func TestShellComplete(t *testing.T) {
+	keys := []string{"item:1", "user:10", "user:11", "user:2", "with space"}
+	s := &shell{c: &cli{keys: encodingUTF8}}
+	s.listKeys = func(_ context.Context, prefix []byte, limit int) ([][]byte, error) {
+		var matched [][]byte
+		for _, key := range keys {
+			if strings.HasPrefix(key, string(prefix)) && len(matched) < limit {
+				matched = append(matched, []byte(key))
+			}
+		}
+		return matched, nil
+	}
+
+	for _, tc := range []struct {
+		line       string
+		want       string
+		candidates int
+	}{
+		{"sc", "scan ", 1},
+		{"r", "rollback ", 1},
+		{"get us", "get user:", 3},
+		{"get user:1", "get user:1", 2},
+		{"get user:2", "get user:2 ", 1},
+		{"delete user:2 it", "delete user:2 item:1 ", 1},
+		{"get wi", `get "with space" `, 1},
+		{`get "wi`, `get "with space" `, 1},
+		{"put user:2 us", "put user:2 us", 0},
+		{"type r", "type rawkv ", 1},
+		{`\x o`, `\x o`, 2},
+		{"unknown us", "unknown us", 0},
+	} {
+		line, pos, candidates := s.complete(tc.line, len(tc.line))
+		if line != tc.want || pos != len(tc.want) || len(candidates) != tc.candidates {
+			t.Errorf("complete(%q) = %q at %d with %q; want %q with %d candidates", tc.line, line, pos, candidates, tc.want, tc.candidates)
+		}
+	}
+
+	// 光标在行中间时只补全光标前的参数，后面的内容保持不变
+	if line, pos, _ := s.complete("get it 10", 6); line != "get item:1  10" || pos != 11 {
+		t.Errorf("complete in the middle = %q at %d", line, pos)
+	}
+
+	// hex 编码时按解码后的前缀扫描
+	s.c.keys = encodingHex
+	if line, _, _ := s.complete("get 6974", 8); line != fmt.Sprintf("get %x ", "item:1") {
+		t.Errorf("hex completion = %q", line)
+	}
+}
+
+// TestParseUse 检查 use 参数中集群和命名空间的切分
+func TestParseUse(t *testing.T) {
+	for _, tc := range []struct {
+		arg          string
+		endpoints    []string
+		namespace    string
+		hasNamespace bool
+	}{
+		{"pd-0:2379, pd-1:2379", []string{"pd-0:2379", "pd-1:2379"}, "", false},
+		{"pd:2379/app_", []string{"pd:2379"}, "app_", true},
+		{"/app/v1/", nil, "app/v1/", true},
+		{"/", nil, "", true},
+	} {
+		endpoints, namespace, hasNamespace, err := parseUse(tc.arg, encodingUTF8)
+		if err != nil || !reflect.DeepEqual(endpoints, tc.endpoints) || string(namespace) != tc.namespace || hasNamespace != tc.hasNamespace {
+			t.Errorf("parseUse(%q) = %q %q %v %v", tc.arg, endpoints, namespace, hasNamespace, err)
+		}
+	}
+	if _, _, _, err := parseUse(" , ", encodingUTF8); err == nil {
+		t.Error("parseUse without endpoints or namespace should fail")
+	}
+	if _, _, _, err := parseUse("/zz", encodingHex); err == nil {
+		t.Error("an invalid hex namespace should fail")
+	}
+}
+
+// TestFileHistory 检查历史文件的追加、重新读取和行数限制
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []string{"get a", "get a", " ", "scan item:"} {
+		h.Add(entry)
+	}
+	h.Close()
+
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 2 || h.At(0) != "scan item:" || h.At(1) != "get a" {
+		t.Fatalf("reloaded history = %q", h.entries)
+	}
+	for i := 0; i < historyLimit; i++ {
+		h.Add(fmt.Sprintf("get %d", i))
+	}
+	h.Close()
+
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Len() != historyLimit || h.At(historyLimit-1) != "get 0" {
+		t.Errorf("history after the limit has %d entries, oldest %q", h.Len(), h.At(h.Len()-1))
+	}
+
+	if h, err := openHistory(""); err != nil || h.file != nil {
+		t.Errorf("empty path should keep the history in memory only: %v", err)
+	}
+}
+
+// TestExpandedPrinter 检查 \x 打开时每个字段占一行
+func TestExpandedPrinter(t *testing.T) {
+	var out bytes.Buffer
+	c := &cli{stdout: &out, keys: encodingUTF8, values: encodingUTF8}
+	p := newExpandedPrinter(&out, "KEY", "VALUE")
+	c.printPair(p, []byte("user:1"), []byte("alice"), false)
+	c.printPair(p, []byte("user:2"), []byte("bob"), false)
+	p.Close()
+
+	want := "-[ RECORD 1 ]\nKEY   | user:1\nVALUE | alice\n-[ RECORD 2 ]\nKEY   | user:2\nVALUE | bob\n"
+	if out.String() != want {
+		t.Errorf("expanded output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+// TestShellRequiresPD 检查 shell 不能通过 HTTP API 使用
+func TestShellRequiresPD(t *testing.T) {
+	if code, _ := runCommand(t, "http://127.0.0.1:1", "", "shell"); code != exitUsage {
+		t.Errorf("shell --server exit = %d, want %d", code, exitUsage)
+	}
+}
+
+// newClusterShell 连接 TIKV_PD_ENDPOINTS 指定的集群，在本次测试独有的命名空间下创建 Txn 模式、ndjson 输出的 shell。
+// 没有设置时跳过测试
+func newClusterShell(t *testing.T) (*shell, *bytes.Buffer) {
+	t.Helper()
+	pdEndpoints := os.Getenv("TIKV_PD_ENDPOINTS")
+	if pdEndpoints == "" {
+		t.Skip("TIKV_PD_ENDPOINTS not set, skipping TiKV integration test")
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{
+		stdout:  &stdout,
+		stderr:  &stderr,
+		opts:    globalOptions{pd: pdEndpoints, mode: modeTxn, output: outputNDJSON, timeout: 30 * time.Second, logLevel: "error"},
+		keys:    encodingUTF8,
+		values:  encodingUTF8,
+		connect: connect,
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	b, err := c.dial(ctx)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(b.Close)
+
+	namespace := fmt.Sprintf("tikvadmin_shell_test_%d/", time.Now().UnixNano())
+	return newShell(c, b.(*directBackend), []byte(namespace)), &stdout
+}
+
+// TestShellTransactionStatements 检查事务中的读写和扫描能看到未提交的写入，以及 rollback 和 commit 之后的结果
+func TestShellTransactionStatements(t *testing.T) {
+	s, out := newClusterShell(t)
+	// other 与 s 使用同一个命名空间，没有打开事务，用来检查事务外能看到的数据
+	other := newShell(s.c, s.b, s.namespace)
+	run := func(s *shell, line string) string {
+		t.Helper()
+		out.Reset()
+		if err := s.exec(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		return out.String()
+	}
+	pair := func(key, value string) string {
+		return fmt.Sprintf(`{"key":%q,"value":%q}`+"\n", key, value)
+	}
+	defer func() {
+		s.rollbackOpen()
+		run(other, "delete a b c")
+	}()
+
+	if got := run(s, "begin"); !strings.HasPrefix(got, "BEGIN (start ts ") || s.txn == nil {
+		t.Fatalf("begin = %q", got)
+	}
+	if got := s.prompt(); got != fmt.Sprintf("txn* /%s> ", s.namespace) {
+		t.Errorf("prompt in a transaction = %q", got)
+	}
+	if err := s.exec("begin"); err == nil {
+		t.Error("a second begin should fail while a transaction is open")
+	}
+	run(s, "put a 1")
+	run(s, "put b 2")
+	if got := run(s, "get a"); got != pair("a", "1") {
+		t.Errorf("get in the transaction = %q", got)
+	}
+	if got := run(s, "scan"); got != pair("a", "1")+pair("b", "2") {
+		t.Errorf("scan in the transaction should see the uncommitted writes: %q", got)
+	}
+	if got := run(other, "get a"); got != "(not found)\n" {
+		t.Errorf("uncommitted write is visible outside the transaction: %q", got)
+	}
+
+	if got := run(s, "rollback"); got != "ROLLBACK\n" || s.txn != nil {
+		t.Errorf("rollback = %q", got)
+	}
+	if got := run(s, "get a"); got != "(not found)\n" {
+		t.Errorf("get after rollback = %q", got)
+	}
+	if got := run(s, "scan"); got != "" {
+		t.Errorf("scan after rollback = %q", got)
+	}
+	if err := s.exec("rollback"); err == nil {
+		t.Error("rollback without an open transaction should fail")
+	}
+
+	run(s, "begin")
+	run(s, "put c 3")
+	run(s, "put a 4")
+	run(s, "delete a")
+	if got := run(s, "scan"); got != pair("c", "3") {
+		t.Errorf("scan should see the uncommitted delete: %q", got)
+	}
+	if got := run(s, "commit"); !strings.HasPrefix(got, "COMMIT (commit ts ") || s.txn != nil {
+		t.Errorf("commit = %q", got)
+	}
+	if got := run(other, "get c"); got != pair("c", "3") {
+		t.Errorf("committed write outside the transaction = %q", got)
+	}
+	if got := run(other, "scan"); got != pair("c", "3") {
+		t.Errorf("scan after commit = %q", got)
+	}
+	if err := s.exec("commit"); err == nil {
+		t.Error("commit without an open transaction should fail")
+	}
+}
+
+// TestShellRawKVStatements 检查 RawKV 模式下的读写、扫描和删除
+func TestShellRawKVStatements(t *testing.T) {
+	s, out := newClusterShell(t)
+	run := func(line string) string {
+		t.Helper()
+		out.Reset()
+		if err := s.exec(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		return out.String()
+	}
+	defer run("delete a b")
+
+	run("type rawkv")
+	if got := run("put a 1"); got != "OK\n" {
+		t.Errorf("put = %q", got)
+	}
+	run(`put b "two words"`)
+	if got := run("scan"); got != `{"key":"a","value":"1"}`+"\n"+`{"key":"b","value":"two words"}`+"\n" {
+		t.Errorf("scan = %q", got)
+	}
+	if got := run("get x"); got != "(not found)\n" {
+		t.Errorf("get of a missing key = %q", got)
+	}
+
+	if got := run("delete a b"); got != "OK, 2 deleted\n" {
+		t.Errorf("delete = %q", got)
+	}
+	if got := run("scan"); got != "" {
+		t.Errorf("scan after delete = %q", got)
+	}
+}
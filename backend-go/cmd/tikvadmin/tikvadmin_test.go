@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"tikv-backend/pkg/tikv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAPI 在内存中实现测试用到的 /api/kv 接口，只有一种模式
+type fakeAPI struct {
+	mu   sync.Mutex
+	data map[string]string
+	// unavailable 模拟没有连接集群的后端，扫描返回 503
+	unavailable bool
+}
+
+func (f *fakeAPI) reply(w http.ResponseWriter, status int, data any) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"success": status == http.StatusOK, "message": http.StatusText(status), "data": data})
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body struct {
+		Key        string   `json:"key"`
+		Value      string   `json:"value"`
+		Keys       []string `json:"keys"`
+		Operations []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"operations"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	path := strings.TrimPrefix(r.URL.Path, "/api/kv")
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		f.scan(w, r)
+	case r.Method == http.MethodGet:
+		key := strings.TrimPrefix(path, "/")
+		value, ok := f.data[key]
+		if !ok {
+			f.reply(w, http.StatusNotFound, nil)
+			return
+		}
+		f.reply(w, http.StatusOK, map[string]string{"key": key, "value": value})
+	case r.Method == http.MethodPost && path == "":
+		f.data[body.Key] = body.Value
+		f.reply(w, http.StatusOK, nil)
+	case r.Method == http.MethodPost && path == "/batch":
+		for _, op := range body.Operations {
+			f.data[op.Key] = op.Value
+		}
+		f.reply(w, http.StatusOK, map[string]any{"data": map[string]any{"failureCount": 0}})
+	case r.Method == http.MethodDelete && path == "":
+		for _, key := range body.Keys {
+			delete(f.data, key)
+		}
+		f.reply(w, http.StatusOK, nil)
+	default:
+		f.reply(w, http.StatusBadRequest, nil)
+	}
+}
+
+// scan 按 start / end / reverse / limit 返回第一页
+func (f *fakeAPI) scan(w http.ResponseWriter, r *http.Request) {
+	if f.unavailable {
+		f.reply(w, http.StatusServiceUnavailable, nil)
+		return
+	}
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	var keys []string
+	for key := range f.data {
+		if key >= query.Get("start") && (query.Get("end") == "" || key < query.Get("end")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if query.Get("reverse") == "true" {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	pairs := []map[string]string{}
+	for _, key := range keys {
+		pairs = append(pairs, map[string]string{"key": key, "value": f.data[key]})
+	}
+	f.reply(w, http.StatusOK, map[string]any{"data": pairs})
+}
+
+// newFakeServer 按后端 SetupRouter 中 /api/kv 的路由挂载 fakeAPI。
+// gin 按解码后的路径匹配，/stats 等静态路由优先于 /:key，与真实后端的行为一致
+func newFakeServer(api *fakeAPI) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/kv")
+	handler := gin.WrapH(api)
+	group.GET("", handler)
+	group.GET("/:key", handler)
+	group.POST("", handler)
+	group.DELETE("", handler)
+	group.POST("/batch", handler)
+	for _, path := range []string{"/stats", "/cluster", "/regions", "/hotspots", "/locks", "/gc", "/tso", "/config"} {
+		group.GET(path, func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"route": c.FullPath()}})
+		})
+	}
+	return httptest.NewServer(router)
+}
+
+// run 执行一条命令，返回退出码和标准输出
+func runCommand(t *testing.T, server string, stdin string, args ...string) (int, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr, connect: connect}
+	code := c.run(append(args, "--server", server))
+	if code != exitOK {
+		t.Logf("tikvadmin %v: exit %d: %s", args, code, stderr.String())
+	}
+	return code, stdout.String()
+}
+
+// TestCommandsOverHTTP 通过 HTTP API 执行读写、扫描、导入导出和范围删除
+func TestCommandsOverHTTP(t *testing.T) {
+	api := &fakeAPI{data: map[string]string{}}
+	server := newFakeServer(api)
+	defer server.Close()
+
+	if code, _ := runCommand(t, server.URL, "", "put", "user:1", "alice"); code != exitOK {
+		t.Fatalf("put exit = %d", code)
+	}
+	if code, out := runCommand(t, server.URL, "", "get", "user:1", "-o", "ndjson"); code != exitOK || out != `{"key":"user:1","value":"alice"}`+"\n" {
+		t.Errorf("get = %d %q", code, out)
+	}
+	if code, out := runCommand(t, server.URL, "", "get", "--raw", "user:1"); code != exitOK || out != "alice" {
+		t.Errorf("get --raw = %d %q", code, out)
+	}
+	if code, _ := runCommand(t, server.URL, "", "get", "user:2"); code != exitNotFound {
+		t.Errorf("missing key exit = %d, want %d", code, exitNotFound)
+	}
+
+	// 导入的键数超过一页，扫描需要翻页
+	var input strings.Builder
+	for i := 0; i < httpScanBatchSize+100; i++ {
+		fmt.Fprintf(&input, `{"key":"item:%04d","value":"v%d"}`+"\n", i, i)
+	}
+	if code, out := runCommand(t, server.URL, input.String(), "import", "--encoding", "utf8", "-o", "json"); code != exitOK || !strings.Contains(out, `"imported": 600`) {
+		t.Fatalf("import = %d %q", code, out)
+	}
+
+	code, out := runCommand(t, server.URL, "", "scan", "--prefix", "item:", "--limit", "0", "--keys-only", "-o", "ndjson")
+	if lines := strings.Count(out, "\n"); code != exitOK || lines != 600 {
+		t.Errorf("scan returned %d keys, want 600 (exit %d)", lines, code)
+	}
+	code, out = runCommand(t, server.URL, "", "scan", "--prefix", "item:", "--reverse", "--limit", "2", "-o", "json")
+	var pairs []kvRecord
+	if err := json.Unmarshal([]byte(out), &pairs); code != exitOK || err != nil || len(pairs) != 2 || pairs[0].Key != "item:0599" || *pairs[1].Value != "v598" {
+		t.Errorf("reverse scan = %d %v %s", code, err, out)
+	}
+
+	// 导出后原样导入到另一个集群
+	file := filepath.Join(t.TempDir(), "export.ndjson")
+	if code, _ := runCommand(t, server.URL, "", "export", "--prefix", "item:", "--file", file); code != exitOK {
+		t.Fatalf("export exit = %d", code)
+	}
+	exported, _ := os.ReadFile(file)
+	if first, _, _ := strings.Cut(string(exported), "\n"); first != `{"key":"aXRlbTowMDAw","value":"djA="}` {
+		t.Errorf("first exported line = %s", first)
+	}
+	other := &fakeAPI{data: map[string]string{}}
+	otherServer := newFakeServer(other)
+	defer otherServer.Close()
+	if code, _ := runCommand(t, otherServer.URL, "", "import", file); code != exitOK || len(other.data) != 600 || other.data["item:0042"] != "v42" {
+		t.Errorf("import of export = %d, %d keys", code, len(other.data))
+	}
+
+	if code, _ := runCommand(t, server.URL, "", "delete-range"); code != exitUsage {
+		t.Errorf("delete-range without a range exit = %d, want %d", code, exitUsage)
+	}
+	if code, out := runCommand(t, server.URL, "", "delete-range", "--prefix", "item:", "--dry-run"); code != exitOK || !strings.Contains(out, "600") || len(api.data) != 601 {
+		t.Errorf("dry run = %d %q, %d keys left", code, out, len(api.data))
+	}
+	if code, _ := runCommand(t, server.URL, "", "delete-range", "--prefix", "item:", "--batch-size", "64"); code != exitOK {
+		t.Errorf("delete-range exit = %d", code)
+	}
+	if !reflect.DeepEqual(api.data, map[string]string{"user:1": "alice"}) {
+		t.Errorf("data after delete-range = %d keys", len(api.data))
+	}
+}
+
+// TestGetKeysLikeRoutes 读取包含 "/" 或与其他接口同名的 key
+func TestGetKeysLikeRoutes(t *testing.T) {
+	api := &fakeAPI{data: map[string]string{"a/b": "slash", "stats": "named stats", "tso/1": "nested", "a": "prefix"}}
+	server := newFakeServer(api)
+	defer server.Close()
+
+	for key, want := range api.data {
+		if code, out := runCommand(t, server.URL, "", "get", "--raw", key); code != exitOK || out != want {
+			t.Errorf("get %q = %d %q, want %q", key, code, out, want)
+		}
+	}
+	for _, key := range []string{"config", "a/c", "a/"} {
+		if code, _ := runCommand(t, server.URL, "", "get", key); code != exitNotFound {
+			t.Errorf("get %q exit = %d, want %d", key, code, exitNotFound)
+		}
+	}
+}
+
+// TestCommandsWithoutCluster 后端没有连接集群时读取和范围操作失败，不能当作空结果
+func TestCommandsWithoutCluster(t *testing.T) {
+	api := &fakeAPI{data: map[string]string{"item:1": "v1"}, unavailable: true}
+	server := newFakeServer(api)
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "export.ndjson")
+	for _, args := range [][]string{
+		{"get", "item:1"},
+		{"scan", "--prefix", "item:"},
+		{"export", "--prefix", "item:", "--file", file},
+		{"delete-range", "--prefix", "item:"},
+	} {
+		if code, _ := runCommand(t, server.URL, "", args...); code != exitError {
+			t.Errorf("%v exit = %d, want %d", args, code, exitError)
+		}
+	}
+	if len(api.data) != 1 {
+		t.Errorf("data changed without a cluster: %d keys", len(api.data))
+	}
+}
+
+// TestParseArgs 检查选项和位置参数交替出现以及 "--" 的处理
+func TestParseArgs(t *testing.T) {
+	c := &cli{stderr: &bytes.Buffer{}}
+	fs := c.newFlagSet("delete", "<key>...")
+	args, err := c.parse(fs, []string{"a", "--type", "txn", "b", "-o", "json", "--", "-c", "--type"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "-c", "--type"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	if c.opts.mode != modeTxn || c.opts.output != outputJSON {
+		t.Errorf("options not parsed: %+v", c.opts)
+	}
+
+	for _, bad := range [][]string{
+		{"--type", "tidb"},
+		{"-o", "yaml"},
+		{"--key-encoding", "latin1"},
+		{"--server", "http://127.0.0.1:3001", "--pd", "pd:2379"},
+	} {
+		c := &cli{stderr: &bytes.Buffer{}}
+		if _, err := c.parse(c.newFlagSet("scan", ""), bad); err == nil {
+			t.Errorf("%v should be rejected", bad)
+		} else if _, ok := err.(usageError); !ok {
+			t.Errorf("%v: expected a usage error, got %v", bad, err)
+		}
+	}
+}
+
+// TestCodec 检查编码的往返和 utf8 输出的转义
+func TestCodec(t *testing.T) {
+	data := []byte("k\x00\xffé")
+	for _, name := range []string{encodingHex, encodingBase64} {
+		c, _ := parseCodec(name)
+		decoded, err := c.decode(c.encode(data))
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("%s round trip = %q, %v", name, decoded, err)
+		}
+	}
+	if got := codec(encodingUTF8).encode(data); got != `k\x00\xffé` {
+		t.Errorf("utf8 encode = %q", got)
+	}
+
+	r, err := (&rangeFlags{prefix: "a", start: "a1", end: "a5", endInclusive: true}).keyRange(encodingUTF8)
+	if err != nil || !reflect.DeepEqual(r, tikv.KeyRange{Start: []byte("a1"), End: []byte("a5\x00")}) {
+		t.Errorf("keyRange = %q, %v", r, err)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"tikv-backend/pkg/tikv"
+)
+
+// ClusterOptions returns the API version and keyspace used to connect to the cluster
+func (c TiKVConfig) ClusterOptions() (tikv.ClusterOptions, error) {
+	return tikv.ParseClusterOptions(c.APIVersion, c.Keyspace)
+}
+
+// Security returns the TLS settings for PD and TiKV connections
+func (c TiKVConfig) Security() tikv.Security {
+	return tikv.Security{
+		CAPath:    c.CAPath,
+		CertPath:  c.CertPath,
+		KeyPath:   c.KeyPath,
+		AllowedCN: c.CertAllowedCN,
+	}
+}
+
+// securityFields maps tikv.Security fields to their config paths
+var securityFields = map[string]string{
+	tikv.SecurityFieldCAPath:    "tikv.ca_path",
+	tikv.SecurityFieldCertPath:  "tikv.cert_path",
+	tikv.SecurityFieldKeyPath:   "tikv.key_path",
+	tikv.SecurityFieldAllowedCN: "tikv.cert_allowed_cn",
+}
+
+// ValidateSecurity checks the TLS settings, including that the certificate files can be read,
+// and reports the problem as a ValidationError under the field that caused it
+func (c TiKVConfig) ValidateSecurity() error {
+	err := c.Security().Validate()
+	if err == nil {
+		return nil
+	}
+	field := "tikv.ca_path"
+	var securityErr *tikv.SecurityError
+	if errors.As(err, &securityErr) {
+		field = securityFields[securityErr.Field]
+	}
+	return ValidationError{{Field: field, Message: err.Error()}}
+}
+
+// Tuning converts the client settings to tikv.ClientTuning; zero fields use the client defaults
+func (c ClientConfig) Tuning() tikv.ClientTuning {
+	return tikv.ClientTuning{
+		GRPCConnectionCount:       c.GRPCConnectionCount,
+		GRPCInitialWindowSize:     c.GRPCInitialWindowSize,
+		GRPCInitialConnWindowSize: c.GRPCInitialConnWindowSize,
+		GRPCMaxRecvMsgSize:        c.GRPCMaxRecvMsgSize,
+		GRPCMaxSendMsgSize:        c.GRPCMaxSendMsgSize,
+		GRPCKeepAliveTime:         time.Duration(c.GRPCKeepAliveTime) * time.Second,
+		GRPCKeepAliveTimeout:      time.Duration(c.GRPCKeepAliveTimeout) * time.Second,
+		MaxBatchSize:              c.MaxBatchSize,
+		MaxBatchWaitTime:          time.Duration(c.MaxBatchWaitTimeMs) * time.Millisecond,
+		BatchWaitSize:             c.BatchWaitSize,
+		RegionCacheTTL:            time.Duration(c.RegionCacheTTL) * time.Second,
+		ReadTimeout:               time.Duration(c.ReadTimeoutMs) * time.Millisecond,
+		ScanTimeout:               time.Duration(c.ScanTimeoutMs) * time.Millisecond,
+		WriteTimeout:              time.Duration(c.WriteTimeoutMs) * time.Millisecond,
+	}.WithDefaults()
+}
@@ -1,30 +1,176 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 )
 
 // Config represents the application configuration
 type Config struct {
-	TiKV TiKVConfig `json:"tikv"`
+	Server  ServerConfig  `json:"server"`
+	TiKV    TiKVConfig    `json:"tikv"`
+	Log     LogConfig     `json:"log"`
+	Tracing TracingConfig `json:"tracing"`
+	Health  HealthConfig  `json:"health"`
+}
+
+// ServerConfig contains HTTP server configuration
+type ServerConfig struct {
+	// Addr is the listen address, e.g. ":3001" or "127.0.0.1:3001"
+	Addr string `json:"addr"`
+	// TLSCertPath and TLSKeyPath enable HTTPS when both are set
+	TLSCertPath string `json:"tls_cert_path"`
+	TLSKeyPath  string `json:"tls_key_path" redact:"true"`
+	// ReadTimeout bounds reading a whole request, including the body, in seconds; 0 disables it
+	ReadTimeout int `json:"read_timeout"`
+	// WriteTimeout bounds writing a response, in seconds; 0 disables it so long scans and deletes are not cut off
+	WriteTimeout int `json:"write_timeout"`
+	// IdleTimeout is how long keep-alive connections stay open between requests, in seconds
+	IdleTimeout int `json:"idle_timeout"`
+	// MaxHeaderBytes caps the size of request headers
+	MaxHeaderBytes int `json:"max_header_bytes"`
+	// MaxBodyBytes caps the size of request bodies; larger requests get 413
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// ShutdownTimeout is how long a graceful shutdown waits for in-flight requests and jobs, in seconds
+	ShutdownTimeout int `json:"shutdown_timeout"`
+}
+
+// HealthConfig contains liveness and readiness probe configuration
+type HealthConfig struct {
+	// CanaryKey is the reserved key used by the readiness probe; RawKV checks write and delete keys under it
+	CanaryKey string `json:"canary_key"`
+	// TimeoutMs bounds each probe check, in milliseconds
+	TimeoutMs int `json:"timeout_ms"`
+}
+
+// TracingConfig contains OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// Exporter is none, otlp or file; with none, incoming trace context is still propagated but no spans are exported
+	Exporter string `json:"exporter"`
+	// Endpoint is the OTLP collector address (host:port); empty uses the OTEL_EXPORTER_OTLP_* environment variables
+	Endpoint string `json:"endpoint"`
+	// Protocol is the OTLP protocol, grpc or http
+	Protocol string `json:"protocol"`
+	// Insecure disables TLS for the OTLP exporter
+	Insecure bool `json:"insecure"`
+	// FilePath is where the file exporter appends spans, one JSON object per line
+	FilePath string `json:"file_path"`
+	// SampleRatio is the fraction of new traces to sample (0-1); requests with a sampled parent are always traced
+	SampleRatio float64 `json:"sample_ratio"`
+	// ServiceName is the service.name resource attribute reported with every span
+	ServiceName string `json:"service_name"`
+}
+
+// LogConfig contains logging configuration
+type LogConfig struct {
+	// Level is the initial log level (debug, info, warn, error); it can be changed at runtime via /log/level
+	Level string `json:"level"`
+	// Format is the log encoding, json or console
+	Format string `json:"format"`
+	// RedactKeyPrefixes lists key prefixes that are never written to the log
+	RedactKeyPrefixes []string `json:"redact_key_prefixes"`
 }
 
 // TiKVConfig contains TiKV cluster configuration
 type TiKVConfig struct {
 	PDEndpoints []string `json:"pd_endpoints"`
+	// APIVersion must match the cluster's storage settings: v1, v1ttl or v2
+	APIVersion string `json:"api_version"`
+	// Keyspace selects a named keyspace (API v2 only); empty uses the default keyspace
+	Keyspace string `json:"keyspace"`
+	// PersistEndpoints writes cluster changes made through the API back to the config file
+	PersistEndpoints bool `json:"persist_endpoints"`
+	// AsyncCommit enables async commit for Txn writes unless overridden per request
+	AsyncCommit bool `json:"async_commit"`
+	// OnePC enables one-phase commit for Txn writes unless overridden per request
+	OnePC bool `json:"one_pc"`
+	// StatsCacheTTL is how long key statistics are cached, in seconds, before a background rescan
+	StatsCacheTTL int `json:"stats_cache_ttl"`
+	// StatsScanLimit caps the number of keys a background statistics scan visits
+	StatsScanLimit int `json:"stats_scan_limit"`
+	// CAPath enables TLS for PD and TiKV connections; leave empty for plaintext
+	CAPath string `json:"ca_path"`
+	// CertPath and KeyPath hold the client certificate for mutual TLS
+	CertPath string `json:"cert_path"`
+	KeyPath  string `json:"key_path" redact:"true"`
+	// CertAllowedCN, when set, restricts the accepted PD certificate Common Names.
+	// PD HTTP calls check it on every connection; the PD and TiKV gRPC connections made by
+	// client-go are not checked. PD is probed once before each client swap, and TiKV
+	// certificates are only verified against the CA
+	CertAllowedCN []string `json:"cert_allowed_cn"`
+	// Client tunes the RawKV and Txn clients created for the cluster
+	Client ClientConfig `json:"client"`
+}
+
+// ClientConfig contains gRPC, batching, region cache and timeout settings for the TiKV clients.
+// Window and message size limits only apply to RawKV; the Txn client keeps client-go's defaults.
+type ClientConfig struct {
+	GRPCConnectionCount       uint  `json:"grpc_connection_count"`
+	GRPCInitialWindowSize     int32 `json:"grpc_initial_window_size"`
+	GRPCInitialConnWindowSize int32 `json:"grpc_initial_conn_window_size"`
+	GRPCMaxRecvMsgSize        int   `json:"grpc_max_recv_msg_size"`
+	GRPCMaxSendMsgSize        int   `json:"grpc_max_send_msg_size"`
+	// GRPCKeepAliveTime and GRPCKeepAliveTimeout are in seconds
+	GRPCKeepAliveTime    int `json:"grpc_keepalive_time"`
+	GRPCKeepAliveTimeout int `json:"grpc_keepalive_timeout"`
+	// MaxBatchSize is the most requests sent in one batch; 0 disables batching
+	MaxBatchSize uint `json:"max_batch_size"`
+	// MaxBatchWaitTimeMs is how long to wait for BatchWaitSize requests before sending a batch when TiKV is busy
+	MaxBatchWaitTimeMs int  `json:"max_batch_wait_time_ms"`
+	BatchWaitSize      uint `json:"batch_wait_size"`
+	// RegionCacheTTL is how long an idle region stays cached, in seconds
+	RegionCacheTTL int `json:"region_cache_ttl"`
+	// ReadTimeoutMs, ScanTimeoutMs and WriteTimeoutMs bound a single get, scan and put/delete; 0 means no limit
+	ReadTimeoutMs  int `json:"read_timeout_ms"`
+	ScanTimeoutMs  int `json:"scan_timeout_ms"`
+	WriteTimeoutMs int `json:"write_timeout_ms"`
 }
 
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig(configPath string) (*Config, error) {
 	// Default configuration
 	config := &Config{
+		Server: ServerConfig{
+			Addr:            ":3001",
+			ReadTimeout:     30,
+			IdleTimeout:     120,
+			MaxHeaderBytes:  1 << 20,
+			MaxBodyBytes:    32 << 20,
+			ShutdownTimeout: 30,
+		},
 		TiKV: TiKVConfig{
 			PDEndpoints: []string{
 				"127.0.0.1:2379", // default PD endpoint
 			},
+			APIVersion:     "v2",
+			StatsCacheTTL:  300,
+			StatsScanLimit: 1000000,
+			Client: ClientConfig{
+				GRPCConnectionCount:       4,
+				GRPCInitialWindowSize:     64 << 20,
+				GRPCInitialConnWindowSize: 64 << 20,
+				GRPCMaxRecvMsgSize:        512 << 20,
+				GRPCMaxSendMsgSize:        256 << 20,
+				GRPCKeepAliveTime:         30,
+				GRPCKeepAliveTimeout:      10,
+				MaxBatchSize:              128,
+				BatchWaitSize:             8,
+				RegionCacheTTL:            600,
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Protocol:    "grpc",
+			SampleRatio: 1,
+			ServiceName: "tikv-backend",
+		},
+		Health: HealthConfig{
+			CanaryKey: "__tikvadmin_health__",
+			TimeoutMs: 2000,
 		},
 	}
 
@@ -32,6 +178,10 @@ func LoadConfig(configPath string) (*Config, error) {
 	if configPath != "" {
 		if _, err := os.Stat(configPath); err == nil {
 			if err := loadFromFile(config, configPath); err != nil {
+				// Field errors are returned as is so callers can list them
+				if fieldErrs, ok := err.(ValidationError); ok {
+					return nil, fieldErrs
+				}
 				return nil, fmt.Errorf("failed to load config from file: %v", err)
 			}
 		} else if !os.IsNotExist(err) {
@@ -42,36 +192,25 @@ func LoadConfig(configPath string) (*Config, error) {
 	}
 
 	// Override with environment variables if set
-	loadFromEnv(config)
+	if err := loadFromEnv(config); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
 
-// loadFromFile loads configuration from a JSON file
+// loadFromFile loads configuration from a JSON, YAML or TOML file, chosen by extension
 func loadFromFile(config *Config, filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %v", err)
 	}
 
-	if err := json.Unmarshal(data, config); err != nil {
+	doc, err := decodeDocument(FormatOf(filePath), data)
+	if err != nil {
 		return fmt.Errorf("failed to parse config file: %v", err)
 	}
-
-	return nil
-}
-
-// loadFromEnv loads configuration from environment variables
-func loadFromEnv(config *Config) {
-	// Load PD endpoints from environment variable
-	if pdEndpoints := os.Getenv("TIKV_PD_ENDPOINTS"); pdEndpoints != "" {
-		// Split by comma and trim spaces
-		endpoints := strings.Split(pdEndpoints, ",")
-		for i, endpoint := range endpoints {
-			endpoints[i] = strings.TrimSpace(endpoint)
-		}
-		config.TiKV.PDEndpoints = endpoints
-	}
+	return decodeInto(config, doc)
 }
 
 // GetPDEndpoints returns the PD endpoints as a slice of strings
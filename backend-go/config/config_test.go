@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadConfigFormats checks that JSON, YAML and TOML files load into the same config
+func TestLoadConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"tikv": {"pd_endpoints": ["pd-0:2379", "pd-1:2379"], "client": {"read_timeout_ms": 500}}, "tracing": {"sample_ratio": 0.5}}`,
+		"config.yaml": "tikv:\n  pd_endpoints: [pd-0:2379, pd-1:2379]\n  client:\n    read_timeout_ms: 500\ntracing:\n  sample_ratio: 0.5\n",
+		"config.toml": "[tikv]\npd_endpoints = [\"pd-0:2379\", \"pd-1:2379\"]\n\n[tikv.client]\nread_timeout_ms = 500\n\n[tracing]\nsample_ratio = 0.5\n",
+	}
+	for name, content := range files {
+		cfg, err := LoadConfig(writeConfig(t, name, content))
+		if err != nil {
+			t.Errorf("%s: LoadConfig failed: %v", name, err)
+			continue
+		}
+		if want := []string{"pd-0:2379", "pd-1:2379"}; !reflect.DeepEqual(cfg.TiKV.PDEndpoints, want) {
+			t.Errorf("%s: pd_endpoints = %v, want %v", name, cfg.TiKV.PDEndpoints, want)
+		}
+		if cfg.TiKV.Client.ReadTimeoutMs != 500 || cfg.Tracing.SampleRatio != 0.5 {
+			t.Errorf("%s: values not loaded: %+v", name, cfg)
+		}
+		// Defaults are kept for fields the file does not set
+		if cfg.Server.Addr != ":3001" || cfg.TiKV.Client.GRPCConnectionCount != 4 {
+			t.Errorf("%s: defaults lost: %+v", name, cfg)
+		}
+	}
+
+	// Encode and load back in every format
+	cfg, _ := LoadConfig("")
+	for _, format := range []string{FormatJSON, FormatYAML, FormatTOML} {
+		data, err := cfg.Encode(format)
+		if err != nil {
+			t.Fatalf("%s: Encode failed: %v", format, err)
+		}
+		loaded, err := LoadConfig(writeConfig(t, "config."+format, string(data)))
+		if err != nil || !reflect.DeepEqual(loaded, cfg) {
+			t.Errorf("%s: round trip mismatch: %v\n%s", format, err, data)
+		}
+	}
+}
+
+// TestLoadConfigFieldErrors checks that unknown keys and mistyped values are reported by field
+func TestLoadConfigFieldErrors(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "config.yaml", "tikv:\n  pd_endpoint: [pd:2379]\n  client:\n    read_timeout: 5\nlogs: {}\n"))
+	var fieldErrs ValidationError
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	var fields []string
+	for _, fieldErr := range fieldErrs {
+		fields = append(fields, fieldErr.Field)
+	}
+	if want := []string{"logs", "tikv.client.read_timeout", "tikv.pd_endpoint"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+
+	_, err = LoadConfig(writeConfig(t, "config.json", `{"server": {"read_timeout": "30s"}}`))
+	if !errors.As(err, &fieldErrs) || fieldErrs[0].Field != "server.read_timeout" {
+		t.Errorf("type error should name the field, got %v", err)
+	}
+}
+
+// TestLoadFromEnv checks the naming scheme and parsing of environment overrides
+func TestLoadFromEnv(t *testing.T) {
+	vars := EnvVars()
+	for path, want := range map[string]string{
+		"server.addr":                 "TIKV_SERVER_ADDR",
+		"tikv.pd_endpoints":           "TIKV_PD_ENDPOINTS",
+		"tikv.ca_path":                "TIKV_CA_PATH",
+		"tikv.client.read_timeout_ms": "TIKV_CLIENT_READ_TIMEOUT_MS",
+		"log.redact_key_prefixes":     "TIKV_LOG_REDACT_KEY_PREFIXES",
+		"health.timeout_ms":           "TIKV_HEALTH_TIMEOUT_MS",
+	} {
+		if vars[path] != want {
+			t.Errorf("%s: env var = %q, want %q", path, vars[path], want)
+		}
+	}
+	seen := map[string]string{}
+	for path, env := range vars {
+		if other, ok := seen[env]; ok {
+			t.Errorf("%s and %s share %s", path, other, env)
+		}
+		seen[env] = path
+	}
+
+	t.Setenv("TIKV_PD_ENDPOINTS", " pd-0:2379, pd-1:2379 ,")
+	t.Setenv("TIKV_ONE_PC", "true")
+	t.Setenv("TIKV_CLIENT_MAX_BATCH_SIZE", "64")
+	t.Setenv("TIKV_TRACING_SAMPLE_RATIO", "0.25")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.TiKV.PDEndpoints, []string{"pd-0:2379", "pd-1:2379"}) || !cfg.TiKV.OnePC ||
+		cfg.TiKV.Client.MaxBatchSize != 64 || cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("environment overrides not applied: %+v", cfg)
+	}
+
+	t.Setenv("TIKV_ONE_PC", "maybe")
+	t.Setenv("TIKV_CLIENT_MAX_BATCH_SIZE", "-1")
+	_, err = LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "tikv.one_pc") || !strings.Contains(err.Error(), "tikv.client.max_batch_size") {
+		t.Errorf("invalid values should be reported by field, got %v", err)
+	}
+}
+
+// TestValidate checks that every invalid field is reported
+func TestValidate(t *testing.T) {
+	cfg, _ := LoadConfig("")
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config should be valid: %v", err)
+	}
+
+	cfg.Server.Addr = "3001"
+	cfg.Server.TLSCertPath = "/certs/server.pem"
+	cfg.TiKV.PDEndpoints = []string{"pd-0:2379", "pd-1"}
+	cfg.TiKV.Client.ReadTimeoutMs = -1
+	cfg.Health.TimeoutMs = 0
+
+	var fieldErrs ValidationError
+	if !errors.As(cfg.Validate(), &fieldErrs) {
+		t.Fatalf("expected ValidationError")
+	}
+	var fields []string
+	for _, fieldErr := range fieldErrs {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"server.addr", "server.tls_key_path", "tikv.pd_endpoints[1]", "tikv.client.read_timeout_ms", "health.timeout_ms"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+// TestValidateSecurity checks that each TLS problem is reported under the field that caused it
+func TestValidateSecurity(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	srv.Close()
+	caPath := writeConfig(t, "ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})))
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	for _, tc := range []struct {
+		tikv TiKVConfig
+		want string
+	}{
+		{TiKVConfig{CertPath: caPath, KeyPath: caPath}, "tikv.ca_path"},
+		{TiKVConfig{CAPath: missing}, "tikv.ca_path"},
+		{TiKVConfig{CAPath: caPath, KeyPath: caPath}, "tikv.cert_path"},
+		{TiKVConfig{CAPath: caPath, CertPath: missing, KeyPath: caPath}, "tikv.cert_path"},
+		{TiKVConfig{CAPath: caPath, CertPath: caPath, KeyPath: missing}, "tikv.key_path"},
+		{TiKVConfig{CAPath: caPath, CertAllowedCN: []string{""}}, "tikv.cert_allowed_cn"},
+	} {
+		var fieldErrs ValidationError
+		if err := tc.tikv.ValidateSecurity(); !errors.As(err, &fieldErrs) || len(fieldErrs) != 1 || fieldErrs[0].Field != tc.want {
+			t.Errorf("ValidateSecurity(%+v) = %v, want a %s error", tc.tikv, err, tc.want)
+		}
+	}
+	if err := (TiKVConfig{CAPath: caPath, CertAllowedCN: []string{"pd-server"}}).ValidateSecurity(); err != nil {
+		t.Errorf("valid TLS settings: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable override
+const EnvPrefix = "TIKV_"
+
+// envField is a config field together with the environment variable that overrides it
+type envField struct {
+	env   string
+	path  string
+	value reflect.Value
+}
+
+// envFields lists every overridable field of v. The variable name is EnvPrefix followed by the
+// upper-cased json path joined with "_", leaving out the "tikv" section since the prefix already
+// says it: server.addr is TIKV_SERVER_ADDR, tikv.pd_endpoints is TIKV_PD_ENDPOINTS and
+// tikv.client.read_timeout_ms is TIKV_CLIENT_READ_TIMEOUT_MS.
+func envFields(v reflect.Value, path []string) []envField {
+	var fields []envField
+	for i := 0; i < v.NumField(); i++ {
+		name := jsonName(v.Type().Field(i))
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), name)
+		if v.Field(i).Kind() == reflect.Struct {
+			fields = append(fields, envFields(v.Field(i), fieldPath)...)
+			continue
+		}
+
+		envPath := fieldPath
+		if envPath[0] == "tikv" {
+			envPath = envPath[1:]
+		}
+		fields = append(fields, envField{
+			env:   EnvPrefix + strings.ToUpper(strings.Join(envPath, "_")),
+			path:  strings.Join(fieldPath, "."),
+			value: v.Field(i),
+		})
+	}
+	return fields
+}
+
+// EnvVars returns the environment variable for every config field, keyed by the field's dotted path
+func EnvVars() map[string]string {
+	var config Config
+	vars := map[string]string{}
+	for _, field := range envFields(reflect.ValueOf(&config).Elem(), nil) {
+		vars[field.path] = field.env
+	}
+	return vars
+}
+
+// loadFromEnv overrides config with every non-empty environment variable from EnvVars.
+// Lists are comma-separated. Returns a ValidationError listing every value that cannot be parsed.
+func loadFromEnv(config *Config) error {
+	var errs ValidationError
+	for _, field := range envFields(reflect.ValueOf(config).Elem(), nil) {
+		raw := os.Getenv(field.env)
+		if raw == "" {
+			continue
+		}
+		if err := setFromString(field.value, raw); err != nil {
+			errs.Add(field.path, fmt.Errorf("invalid value %q in %s: %v", raw, field.env, err))
+		}
+	}
+	return errs.Err()
+}
+
+func setFromString(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		// Split by comma and trim spaces
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
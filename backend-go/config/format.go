@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported config file formats, chosen by file extension
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatOf returns the format of a config file from its extension: .yaml/.yml, .toml, anything else is JSON
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// decodeDocument parses a config file into a generic document.
+// Every format goes through the same document so the json tags on Config are the only schema.
+func decodeDocument(format string, data []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		// An empty file, e.g. --config /dev/null, only uses defaults and environment overrides
+		return doc, nil
+	}
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &doc)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &doc)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		// A YAML file holding only comments
+		doc = map[string]any{}
+	}
+	return normalize(doc).(map[string]any), nil
+}
+
+// encodeDocument formats a generic document; keys are written in sorted order
+func encodeDocument(format string, doc map[string]any) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(doc)
+	case FormatTOML:
+		return toml.Marshal(doc)
+	default:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+}
+
+// normalize converts decoded values to types every encoder accepts:
+// numbers become int64 or float64, map keys become strings and null values are dropped
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if value == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = normalize(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			if value != nil {
+				m[fmt.Sprint(key)] = normalize(value)
+			}
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// decodeInto applies a document on top of config, reporting unknown keys and mistyped values per field
+func decodeInto(config *Config, doc map[string]any) error {
+	var errs ValidationError
+	unknownFields(doc, reflect.TypeOf(*config), "", &errs)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return ValidationError{{Field: typeErr.Field, Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}}
+		}
+		return err
+	}
+	return nil
+}
+
+// unknownFields records every key in doc that has no matching json tag in t
+func unknownFields(doc map[string]any, t reflect.Type, prefix string, errs *ValidationError) {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := fieldByTag(t, key)
+		if !ok {
+			errs.Add(prefix+key, fmt.Errorf("unknown field"))
+			continue
+		}
+		if sub, isMap := doc[key].(map[string]any); isMap && field.Type.Kind() == reflect.Struct {
+			unknownFields(sub, field.Type, prefix+key+".", errs)
+		}
+	}
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// Encode formats the config in the given format. JSON keeps the field order of Config;
+// YAML and TOML write keys in sorted order.
+func (c *Config) Encode(format string) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format != FormatYAML && format != FormatTOML {
+		return append(data, '\n'), nil
+	}
+	doc, err := decodeDocument(FormatJSON, data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDocument(format, doc)
+}
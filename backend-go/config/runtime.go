@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WatchInterval is how often WatchFile checks the config file for changes
+const WatchInterval = 5 * time.Second
+
+// redactedValue replaces the value of fields tagged `redact:"true"`
+const redactedValue = "<redacted>"
+
+// fileStamp is the modification time and size used to detect a changed file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// WatchFile polls path every interval and calls onChange when the file is modified or created,
+// until ctx is done. A removed file does not trigger onChange.
+func WatchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last := statFile(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stamp := statFile(path)
+		if stamp == last {
+			continue
+		}
+		last = stamp
+		if stamp.exists {
+			onChange()
+		}
+	}
+}
+
+// SaveCluster writes the cluster endpoints, API version and keyspace back to the config file,
+// keeping every other setting and the file's format. Environment overrides are not written.
+// The file is replaced atomically. YAML files are edited in place, so comments and key order are kept;
+// JSON and TOML files are re-encoded with keys in sorted order, and TOML comments are lost.
+func SaveCluster(configPath string, endpoints []string, apiVersion, keyspace string) error {
+	format := FormatOf(configPath)
+	var data []byte
+	mode := os.FileMode(0o644)
+	if existing, err := os.ReadFile(configPath); err == nil {
+		data = existing
+		if info, err := os.Stat(configPath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var err error
+	if format == FormatYAML {
+		data, err = setYAMLCluster(data, endpoints, apiVersion, keyspace)
+	} else {
+		data, err = setDocumentCluster(format, data, endpoints, apiVersion, keyspace)
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), filepath.Base(configPath)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
+
+// setDocumentCluster decodes the whole file, sets the cluster keys and encodes it again
+func setDocumentCluster(format string, data []byte, endpoints []string, apiVersion, keyspace string) ([]byte, error) {
+	doc, err := decodeDocument(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	section, ok := doc["tikv"].(map[string]any)
+	if !ok {
+		section = map[string]any{}
+		doc["tikv"] = section
+	}
+	items := make([]any, len(endpoints))
+	for i, endpoint := range endpoints {
+		items[i] = endpoint
+	}
+	section["pd_endpoints"] = items
+	section["api_version"] = apiVersion
+	section["keyspace"] = keyspace
+
+	return encodeDocument(format, doc)
+}
+
+// setYAMLCluster replaces only the cluster keys in the YAML node tree, keeping comments and the order of other keys
+func setYAMLCluster(data []byte, endpoints []string, apiVersion, keyspace string) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+	if root.Kind != yaml.DocumentNode {
+		root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(root.Content) == 0 {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file: top level is not a mapping")
+	}
+
+	section := yamlMappingValue(doc, "tikv")
+	if section == nil || section.Tag == "!!null" {
+		section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setYAMLValue(doc, "tikv", section)
+	} else if section.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file: tikv is not a mapping")
+	}
+	for _, kv := range []struct {
+		key   string
+		value any
+	}{
+		{"pd_endpoints", endpoints},
+		{"api_version", apiVersion},
+		{"keyspace", keyspace},
+	} {
+		var node yaml.Node
+		if err := node.Encode(kv.value); err != nil {
+			return nil, err
+		}
+		setYAMLValue(section, kv.key, &node)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlMappingValue returns the value of key in a mapping node, or nil when the key is missing
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setYAMLValue replaces the value of key in a mapping node, keeping the old value's comments and flow style,
+// or appends the key when it is missing
+func setYAMLValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		old := mapping.Content[i+1]
+		value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		if old.Kind == value.Kind {
+			value.Style = old.Style
+		}
+		mapping.Content[i+1] = value
+		return
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// Redacted returns a copy of the config with every non-empty string field tagged `redact:"true"` replaced
+func (c *Config) Redacted() *Config {
+	copied := *c
+	redact(reflect.ValueOf(&copied).Elem())
+	return &copied
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redact(field)
+		case field.Kind() == reflect.String && v.Type().Field(i).Tag.Get("redact") == "true" && field.String() != "":
+			field.SetString(redactedValue)
+		}
+	}
+}
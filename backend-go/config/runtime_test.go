@@ -0,0 +1,193 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSaveCluster checks that only the cluster settings are rewritten and other keys are kept
+func TestSaveCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	original := `{
+  "server": {"addr": ":4000"},
+  "tikv": {"pd_endpoints": ["127.0.0.1:2379"], "stats_cache_ttl": 60, "async_commit": true},
+  "log": {"level": "debug"}
+}`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveCluster(path, []string{"pd-0:2379", "pd-1:2379"}, "v1", ""); err != nil {
+		t.Fatalf("SaveCluster failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if want := []string{"pd-0:2379", "pd-1:2379"}; !reflect.DeepEqual(cfg.TiKV.PDEndpoints, want) {
+		t.Errorf("pd_endpoints = %v, want %v", cfg.TiKV.PDEndpoints, want)
+	}
+	if cfg.TiKV.APIVersion != "v1" || cfg.TiKV.Keyspace != "" {
+		t.Errorf("api_version/keyspace = %q/%q, want v1/empty", cfg.TiKV.APIVersion, cfg.TiKV.Keyspace)
+	}
+	if cfg.Server.Addr != ":4000" || cfg.TiKV.StatsCacheTTL != 60 || !cfg.TiKV.AsyncCommit || cfg.Log.Level != "debug" {
+		t.Errorf("other settings were not preserved: %+v", cfg)
+	}
+
+	// YAML files stay YAML
+	yamlPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(yamlPath, []byte("log:\n  level: warn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCluster(yamlPath, []string{"pd:2379"}, "v2", ""); err != nil {
+		t.Fatalf("SaveCluster on a YAML file failed: %v", err)
+	}
+	if cfg, err := LoadConfig(yamlPath); err != nil || cfg.Log.Level != "warn" || cfg.TiKV.PDEndpoints[0] != "pd:2379" {
+		t.Errorf("YAML config after SaveCluster = %+v, %v", cfg, err)
+	}
+
+	// A missing file is created with only the cluster settings
+	created := filepath.Join(t.TempDir(), "new.json")
+	if err := SaveCluster(created, []string{"pd:2379"}, "v2", "ks1"); err != nil {
+		t.Fatalf("SaveCluster on a missing file failed: %v", err)
+	}
+	if cfg, err := LoadConfig(created); err != nil || cfg.TiKV.Keyspace != "ks1" {
+		t.Errorf("created config = %+v, %v", cfg, err)
+	}
+}
+
+// TestSaveClusterYAMLComments checks that saving into a YAML file keeps comments and the order of unrelated keys
+func TestSaveClusterYAMLComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := `# Admin backend config
+server:
+  addr: ":4000" # listen address
+
+tikv:
+  # PD endpoints of the production cluster
+  pd_endpoints: ["127.0.0.1:2379"]
+  async_commit: true # needs TiKV 5.0+
+
+log:
+  level: debug
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveCluster(path, []string{"pd-0:2379", "pd-1:2379"}, "v2", "ks1"); err != nil {
+		t.Fatalf("SaveCluster failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, kept := range []string{
+		"# Admin backend config",
+		"# listen address",
+		"# PD endpoints of the production cluster",
+		"# needs TiKV 5.0+",
+	} {
+		if !strings.Contains(saved, kept) {
+			t.Errorf("comment %q was lost:\n%s", kept, saved)
+		}
+	}
+	if server, tikv, log := strings.Index(saved, "server:"), strings.Index(saved, "tikv:"), strings.Index(saved, "log:"); !(server < tikv && tikv < log) {
+		t.Errorf("top-level keys were reordered:\n%s", saved)
+	}
+	if strings.Contains(saved, "127.0.0.1:2379") {
+		t.Errorf("old endpoints were not replaced:\n%s", saved)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if want := []string{"pd-0:2379", "pd-1:2379"}; !reflect.DeepEqual(cfg.TiKV.PDEndpoints, want) {
+		t.Errorf("pd_endpoints = %v, want %v", cfg.TiKV.PDEndpoints, want)
+	}
+	if cfg.TiKV.APIVersion != "v2" || cfg.TiKV.Keyspace != "ks1" {
+		t.Errorf("api_version/keyspace = %q/%q, want v2/ks1", cfg.TiKV.APIVersion, cfg.TiKV.Keyspace)
+	}
+	if cfg.Server.Addr != ":4000" || !cfg.TiKV.AsyncCommit || cfg.Log.Level != "debug" {
+		t.Errorf("other settings were not preserved: %+v", cfg)
+	}
+}
+
+// TestRedacted checks that secret fields are masked in the copy only
+func TestRedacted(t *testing.T) {
+	cfg := &Config{}
+	cfg.TiKV.CAPath = "/certs/ca.pem"
+	cfg.TiKV.KeyPath = "/certs/client-key.pem"
+	cfg.Server.TLSKeyPath = "/certs/server-key.pem"
+
+	redacted := cfg.Redacted()
+	if redacted.TiKV.KeyPath != redactedValue || redacted.Server.TLSKeyPath != redactedValue {
+		t.Errorf("secret fields not redacted: %+v", redacted)
+	}
+	if redacted.TiKV.CAPath != "/certs/ca.pem" {
+		t.Errorf("ca path should not be redacted, got %q", redacted.TiKV.CAPath)
+	}
+	if cfg.TiKV.KeyPath != "/certs/client-key.pem" {
+		t.Errorf("original config was modified")
+	}
+	if (&Config{}).Redacted().TiKV.KeyPath != "" {
+		t.Errorf("empty secret fields should stay empty")
+	}
+}
+
+// TestWatchFile checks that writes and re-creation trigger onChange but removal does not
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go WatchFile(ctx, path, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	expect := func(want bool, msg string) {
+		t.Helper()
+		select {
+		case <-changed:
+			if !want {
+				t.Fatalf("%s: unexpected change", msg)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if want {
+				t.Fatalf("%s: change not detected", msg)
+			}
+		}
+	}
+
+	expect(false, "unchanged file")
+
+	future := time.Now().Add(time.Minute)
+	os.WriteFile(path, []byte(`{"log":{}}`), 0o600)
+	os.Chtimes(path, future, future)
+	expect(true, "modified file")
+
+	os.Remove(path)
+	expect(false, "removed file")
+
+	os.WriteFile(path, []byte(`{}`), 0o600)
+	expect(true, "re-created file")
+}
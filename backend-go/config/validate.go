@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// FieldError is a problem with one config field, identified by its dotted path such as "tikv.client.read_timeout_ms"
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every invalid field found in one pass
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records err for field; a nil err is ignored. A ValidationError err is merged as is.
+func (e *ValidationError) Add(field string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case ValidationError:
+		*e = append(*e, err...)
+	default:
+		*e = append(*e, FieldError{Field: field, Message: err.Error()})
+	}
+}
+
+// Err returns nil when no field is invalid
+func (e ValidationError) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks ranges and combinations of settings that need no other package.
+// Log levels, tracing exporters, API versions, client tuning ranges and TLS files
+// are checked by the packages that use them. Returns a ValidationError listing every invalid field.
+func (c *Config) Validate() error {
+	var errs ValidationError
+	check := func(field string, ok bool, format string, args ...any) {
+		if !ok {
+			errs.Add(field, fmt.Errorf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs.Add("server.addr", fmt.Errorf("must be host:port or :port, got %q", c.Server.Addr))
+	}
+	check("server.tls_key_path", (c.Server.TLSCertPath == "") == (c.Server.TLSKeyPath == ""),
+		"tls_cert_path and tls_key_path must be set together")
+	for _, f := range []intField{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		check(f.name, f.value >= 0, "must not be negative, got %d", f.value)
+	}
+	check("server.max_header_bytes", c.Server.MaxHeaderBytes > 0, "must be positive, got %d", c.Server.MaxHeaderBytes)
+
+	check("tikv.pd_endpoints", len(c.TiKV.PDEndpoints) > 0, "at least one endpoint is required")
+	for i, endpoint := range c.TiKV.PDEndpoints {
+		check(fmt.Sprintf("tikv.pd_endpoints[%d]", i), strings.Contains(endpoint, ":"), "must be host:port, got %q", endpoint)
+	}
+	check("tikv.stats_cache_ttl", c.TiKV.StatsCacheTTL > 0, "must be positive, got %d", c.TiKV.StatsCacheTTL)
+	check("tikv.stats_scan_limit", c.TiKV.StatsScanLimit > 0, "must be positive, got %d", c.TiKV.StatsScanLimit)
+	for _, f := range []intField{
+		{"tikv.client.grpc_keepalive_time", c.TiKV.Client.GRPCKeepAliveTime},
+		{"tikv.client.grpc_keepalive_timeout", c.TiKV.Client.GRPCKeepAliveTimeout},
+		{"tikv.client.max_batch_wait_time_ms", c.TiKV.Client.MaxBatchWaitTimeMs},
+		{"tikv.client.region_cache_ttl", c.TiKV.Client.RegionCacheTTL},
+		{"tikv.client.read_timeout_ms", c.TiKV.Client.ReadTimeoutMs},
+		{"tikv.client.scan_timeout_ms", c.TiKV.Client.ScanTimeoutMs},
+		{"tikv.client.write_timeout_ms", c.TiKV.Client.WriteTimeoutMs},
+	} {
+		check(f.name, f.value >= 0, "must not be negative, got %d", f.value)
+	}
+
+	check("health.timeout_ms", c.Health.TimeoutMs > 0, "must be positive, got %d", c.Health.TimeoutMs)
+	return errs.Err()
+}
+
+// intField is a numeric setting checked by Validate
+type intField struct {
+	name  string
+	value int
+}
@@ -1,29 +1,60 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
-
+	"unicode/utf8"
+
+	"tikv-backend/config"
+	"tikv-backend/pkg/filter"
+	"tikv-backend/pkg/health"
+	"tikv-backend/pkg/hotspot"
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/pd"
 	"tikv-backend/pkg/tikv"
+	"tikv-backend/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
+	tikverr "github.com/tikv/client-go/v2/error"
 	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 var (
-	rawKvClient      *tikv.RawKvClient
-	txnClient        *tikv.TxnClient
 	currentEndpoints []string
 	endpointsMu      sync.RWMutex
+	// 统计信息缓存，切换集群后清空
+	kvStatsCache = tikv.NewStatsCache(tikv.DefaultStatsCacheTTL, tikv.DefaultStatsScanLimit)
+	// 后端处理的请求中各个 key 的访问量，用于热点分析
+	accessTracker = hotspot.NewTracker(hotspot.DefaultWindow, hotspot.DefaultBuckets, hotspot.DefaultMaxKeys)
+	// 就绪检查使用的保留 key 和单项检查的超时时间
+	canaryKey    = tikv.DefaultCanaryKey
+	probeTimeout = health.DefaultTimeout
+
+	// 请求体大小上限，小于等于 0 时不限制
+	maxBodyBytes int64 = 32 << 20
+
+	// 切换集群时没有指定 API 版本和 keyspace 时使用的默认值
+	defaultClusterOptions = tikv.DefaultClusterOptions()
+
+	// settingsMu 保护重新加载配置时会修改的 canaryKey、probeTimeout、maxBodyBytes 和 defaultClusterOptions
+	settingsMu sync.RWMutex
 )
 
 // 通用API响应结构
@@ -41,81 +72,347 @@ type PaginatedResult struct {
 	Page       int         `json:"page"`
 	Limit      int         `json:"limit"`
 	TotalPages int         `json:"totalPages"`
+	// ReadTS Txn 模式下快照读取使用的时间戳
+	ReadTS uint64 `json:"readTs,omitempty"`
+	// 以下字段只在带过滤条件扫描时返回
+	Scanned         int    `json:"scanned,omitempty"`
+	BudgetExhausted bool   `json:"budgetExhausted,omitempty"`
+	ResumeKey       string `json:"resumeKey,omitempty"`
 }
 
 // 键值对结构
 type KeyValuePair struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+	// 以下字段只在 keysOnly / preview / hash 扫描模式下返回
+	ValueSize *int   `json:"valueSize,omitempty"`
+	ValueHash string `json:"valueHash,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// 单键读取结果
+type GetKVResponse struct {
+	KeyValuePair
+	ReadTS uint64 `json:"readTs,omitempty"`
 }
 
 // 统计响应结构
 type StatsResponse struct {
-	TotalKeys int `json:"total_keys"`
-	RawkvKeys int `json:"rawkv_keys"`
-	TxnKeys   int `json:"txn_keys"`
+	TotalKeys int    `json:"total_keys"`
+	RawkvKeys int    `json:"rawkv_keys"`
+	TxnKeys   int    `json:"txn_keys"`
+	Prefix    string `json:"prefix"`
+	// 统计所用集群的 API 版本和 keyspace
+	APIVersion string `json:"api_version"`
+	Keyspace   string `json:"keyspace"`
+	// 各模式的详细统计，尚未统计完成或客户端未连接时为空
+	RawKV *tikv.KVStats `json:"rawkv,omitempty"`
+	Txn   *tikv.KVStats `json:"txn,omitempty"`
+	// Refreshing 为 true 时后台正在重新统计，稍后再请求可以拿到新结果
+	Refreshing bool `json:"refreshing"`
+}
+
+// Region 查询结果，StartKey / EndKey 为解码后的用户 key，空字符串表示没有边界
+type RegionResponse struct {
+	pd.Region
+	StartKey    string `json:"startKey"`
+	EndKey      string `json:"endKey"`
+	RawStartKey string `json:"rawStartKey"`
+	RawEndKey   string `json:"rawEndKey"`
+}
+
+type RegionsResult struct {
+	Type    string           `json:"type"`
+	Regions []RegionResponse `json:"regions"`
+	Count   int              `json:"count"`
+	// HasMore 达到 limit 时范围内还有更多 region
+	HasMore bool `json:"hasMore"`
+}
+
+// 热点 region，StartKey / EndKey 为解码后的用户 key
+type HotRegionResponse struct {
+	pd.HotRegion
+	Mode         string `json:"mode,omitempty"`
+	StoreAddress string `json:"storeAddress,omitempty"`
+	StartKey     string `json:"startKey"`
+	EndKey       string `json:"endKey"`
+	RawStartKey  string `json:"rawStartKey"`
+	RawEndKey    string `json:"rawEndKey"`
+}
+
+// 一种访问类型（读或写）的热点
+type HotspotView struct {
+	Regions      []HotRegionResponse `json:"regions"`
+	RegionsError string              `json:"regionsError,omitempty"`
+	Keys         []hotspot.Entry     `json:"keys"`
+	Prefixes     []hotspot.Entry     `json:"prefixes"`
+}
+
+type HotspotsResponse struct {
+	WindowSeconds int          `json:"windowSeconds"`
+	SortBy        string       `json:"sortBy"`
+	Read          *HotspotView `json:"read,omitempty"`
+	Write         *HotspotView `json:"write,omitempty"`
+}
+
+// 锁扫描结果
+type LocksResult struct {
+	Locks []tikv.LockInfo `json:"locks"`
+	Count int             `json:"count"`
+	// ResumeKey 结果被截断时下一次扫描的起始 key（作为 start 传入）
+	ResumeKey string `json:"resumeKey,omitempty"`
+}
+
+// 处理锁请求，Confirm 为 false 时只预览，不做任何修改
+type ResolveLocksRequest struct {
+	Locks   []LockRef `json:"locks" binding:"required,min=1"`
+	Confirm bool      `json:"confirm"`
+}
+
+// StartTS 可以是 TSO 或 RFC3339 时间
+type LockRef struct {
+	Key     string         `json:"key" binding:"required"`
+	StartTS tikv.Timestamp `json:"startTs" binding:"required"`
+}
+
+// 处理锁预览，Lock 为空表示锁已经不存在
+type LockResolvePreview struct {
+	Key          string         `json:"key"`
+	StartTS      uint64         `json:"startTs"`
+	Lock         *tikv.LockInfo `json:"lock,omitempty"`
+	WouldResolve bool           `json:"wouldResolve"`
+}
+
+// GC 状态，ServiceSafePointsError 不为空时表示无法从 PD HTTP API 读取服务 safe point
+type GCStatusResponse struct {
+	GCSafePoint            tikv.TSOInfo          `json:"gcSafePoint"`
+	ServiceSafePoints      []pd.ServiceSafePoint `json:"serviceSafePoints"`
+	ServiceSafePointsError string                `json:"serviceSafePointsError,omitempty"`
+	TSO                    tikv.TSOInfo          `json:"tso"`
+}
+
+// 精确重新统计请求
+type RecountStatsRequest struct {
+	Type   string `json:"type"`
+	Prefix string `json:"prefix"`
 }
 
 // 集群状态响应结构
 type ClusterStatusResponse struct {
 	ClusterStatus string   `json:"cluster_status"`
 	Endpoints     []string `json:"endpoints"`
+	// Topology PD 成员和 TiKV 节点信息，PD 不可达时为空
+	Topology *pd.Topology `json:"topology,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	// APIVersion、Keyspace、KeyspaceID 当前客户端使用的 API 版本和 keyspace，没有连接集群时为默认值
+	APIVersion string `json:"api_version"`
+	Keyspace   string `json:"keyspace"`
+	KeyspaceID uint32 `json:"keyspace_id"`
+	// Client 当前客户端实际使用的参数，没有连接集群时为下次连接将使用的参数
+	Client tikv.ClientTuning `json:"client"`
+}
+
+// 事务提交选项（请求级别，未设置时沿用全局配置）
+type CommitOptionsRequest struct {
+	AsyncCommit *bool `json:"asyncCommit,omitempty"`
+	OnePC       *bool `json:"onePC,omitempty"`
 }
 
 type UpdateClusterEndpointsRequest struct {
 	Endpoints string `json:"endpoints"`
+	// APIVersion 集群的 API 版本（v1、v1ttl、v2），为空时使用配置文件中的设置
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Keyspace API V2 下使用的 keyspace，不传时使用配置文件中的设置，空字符串表示默认 keyspace
+	Keyspace *string `json:"keyspace,omitempty"`
 }
 
-// InitializeTiKVClient 初始化 TiKV 客户端
-func InitializeTiKVClient(endpoints []string) error {
-	log.Printf("Initializing TiKV client with endpoints: %v", endpoints)
+// InitializeTiKVClient 按指定的 API 版本和 keyspace 初始化 TiKV 客户端
+func InitializeTiKVClient(endpoints []string, opts tikv.ClusterOptions) error {
+	logging.L().Info("initializing TiKV clients", zap.Strings("endpoints", endpoints),
+		zap.String("api_version", opts.APIVersionName()), zap.String("keyspace", opts.Keyspace))
 
 	// 设置超时上下文
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// 初始化 RawKV 客户端
-	rawClient, err := tikv.NewRawKvClient(ctx, endpoints)
-	if err != nil {
-		log.Printf("Failed to initialize RawKV client: %v", err)
+	// 新的 RawKV 和 TxnKV 客户端都创建并验证成功后才替换当前的客户端，
+	// 旧客户端等进行中的请求结束后再关闭；失败时继续使用当前的客户端
+	if err := tikv.SwapClients(ctx, endpoints, opts, tikv.DefaultDrainTimeout); err != nil {
+		logging.L().Error("failed to initialize TiKV clients", zap.Error(err))
 		return err
 	}
-	rawKvClient = rawClient
+	cluster := strings.Join(endpoints, ",")
+	metrics.SetClientConnected(cluster, "rawkv")
+	metrics.SetClientConnected(cluster, "txn")
 
-	// 初始化 TxnKV 客户端
-	txn, err := tikv.NewTxnClient(ctx, endpoints)
+	logging.L().Info("TiKV clients initialized", zap.Strings("endpoints", endpoints))
+	return nil
+}
+
+// applySecurity 检查 TLS 配置并应用到之后创建的 TiKV 客户端和 PD HTTP 客户端
+func applySecurity(security tikv.Security) error {
+	if err := security.Validate(); err != nil {
+		return err
+	}
+	tlsConfig, err := security.TLSConfig()
 	if err != nil {
-		log.Printf("Failed to initialize TxnKV client: %v", err)
 		return err
 	}
-	txnClient = txn
-
-	log.Println("✅ TiKV clients initialized successfully")
+	tikv.SetSecurity(security)
+	pd.SetTLSConfig(tlsConfig)
 	return nil
 }
 
-// 获取全局RawKV客户端实例
-func getGlobalRawKVClient() *rawkv.Client {
-	if rawKvClient == nil {
-		return nil
+// reloadSecurity 证书文件变化后重新读取证书，并用新证书重新连接当前集群；失败时继续使用现有的客户端
+func reloadSecurity(security tikv.Security) {
+	logging.L().Info("TLS certificate files changed, reloading")
+	if err := applySecurity(security); err != nil {
+		logging.L().Warn("failed to reload TLS certificates", zap.Error(err))
+		return
+	}
+	endpoints := getCurrentEndpoints()
+	if len(endpoints) == 0 {
+		return
+	}
+	clients := tikv.AcquireClients()
+	opts := clients.Options()
+	clients.Release()
+	if err := InitializeTiKVClient(endpoints, opts); err != nil {
+		logging.L().Warn("failed to reconnect with new TLS certificates, keeping existing clients", zap.Error(err))
+	}
+}
+
+// clientsMiddleware 请求开始时获取当前集群的客户端并增加引用计数，请求结束后释放。
+// 请求处理期间切换集群不会关闭该请求正在使用的客户端。
+func clientsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients := tikv.AcquireClients()
+		defer clients.Release()
+
+		ctx := context.WithValue(c.Request.Context(), clientsKey{}, clients)
+		if clients != nil {
+			ctx = tracing.WithOpTimeouts(ctx, clients.Tuning().OpTimeout)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+type clientsKey struct{}
+
+// bodyLimitMiddleware 限制请求体大小：Content-Length 超过上限时直接返回 413，
+// 未声明长度的请求体读取超过上限时报错
+func bodyLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settingsMu.RLock()
+		limit := maxBodyBytes
+		settingsMu.RUnlock()
+
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ApiResponse{
+				Success: false,
+				Message: "Request body too large",
+				Error:   fmt.Sprintf("request body exceeds %d bytes", limit),
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// requestClients 返回请求开始时获取的客户端，没有连接集群时返回 nil
+func requestClients(ctx context.Context) *tikv.Clients {
+	clients, _ := ctx.Value(clientsKey{}).(*tikv.Clients)
+	return clients
+}
+
+// clientTuningFrom 返回请求所用客户端的参数，没有连接集群时返回下次连接将使用的参数
+func clientTuningFrom(ctx context.Context) tikv.ClientTuning {
+	if clients := requestClients(ctx); clients != nil {
+		return clients.Tuning()
 	}
-	return tikv.RawKVClient
+	return tikv.CurrentClientTuning()
+}
+
+// rawClientFrom 返回请求使用的 RawKV 客户端，没有连接集群时返回 nil
+func rawClientFrom(ctx context.Context) *rawkv.Client {
+	return requestClients(ctx).RawKV()
+}
+
+// txnClientFrom 返回请求使用的 Txn 客户端，没有连接集群时返回 nil
+func txnClientFrom(ctx context.Context) *tikv.TxnClient {
+	return requestClients(ctx).Txn()
 }
 
 func prefixedKey(key string) []byte {
 	return []byte(key)
 }
 
+// prefixedRange 返回前缀对应的扫描范围，endKey 为 nil 表示没有上界
 func prefixedRange(prefix string) (startKey, endKey []byte) {
-	if prefix != "" {
-		startKey = []byte(prefix)
-		endKey = append(append([]byte{}, startKey...), 0xFF)
-		return startKey, endKey
+	r := tikv.PrefixRange(prefixedKey(prefix))
+	return r.Start, r.End
+}
+
+// scanRangeFromQuery 根据 prefix、start、end 查询参数构造扫描范围。
+// start 默认包含、end 默认不包含，可以通过 startInclusive / endInclusive 调整；
+// 同时指定前缀和起止键时取两者的交集。
+func scanRangeFromQuery(c *gin.Context) (tikv.KeyRange, error) {
+	startInclusive, err := queryBool(c, "startInclusive", true)
+	if err != nil {
+		return tikv.KeyRange{}, err
+	}
+	endInclusive, err := queryBool(c, "endInclusive", false)
+	if err != nil {
+		return tikv.KeyRange{}, err
+	}
+
+	var bounds tikv.KeyRange
+	if start := c.Query("start"); start != "" {
+		bounds.Start = prefixedKey(start)
+		if !startInclusive {
+			bounds.Start = tikv.KeyAfter(bounds.Start)
+		}
+	}
+	if end := c.Query("end"); end != "" {
+		bounds.End = prefixedKey(end)
+		if endInclusive {
+			bounds.End = tikv.KeyAfter(bounds.End)
+		}
+	}
+
+	return tikv.PrefixRange(prefixedKey(c.Query("prefix"))).Intersect(bounds), nil
+}
+
+// queryInt 解析非负整数类型的查询参数，未设置时返回默认值
+func queryInt(c *gin.Context, name string, defaultValue int) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return defaultValue, nil
 	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s parameter: %s", name, raw)
+	}
+	return v, nil
+}
 
-	startKey = []byte("")
-	endKey = []byte{0xFF, 0xFF, 0xFF, 0xFF}
-	return startKey, endKey
+// queryBool 解析布尔类型的查询参数，未设置时返回默认值
+func queryBool(c *gin.Context, name string, defaultValue bool) (bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %s", name, raw)
+	}
+	return v, nil
 }
 
 func parseEndpoints(raw string) ([]string, error) {
@@ -137,6 +434,26 @@ func parseEndpoints(raw string) ([]string, error) {
 	return endpoints, nil
 }
 
+// clusterOptionsFor 切换集群请求中没有指定的 API 版本和 keyspace 使用配置文件中的设置，
+// 指定了 v1 或 v1ttl 且没有指定 keyspace 时不使用配置中的 keyspace
+func clusterOptionsFor(req UpdateClusterEndpointsRequest) (tikv.ClusterOptions, error) {
+	settingsMu.RLock()
+	defaultClusterOptions := defaultClusterOptions
+	settingsMu.RUnlock()
+
+	apiVersion := req.APIVersion
+	keyspace := defaultClusterOptions.Keyspace
+	if apiVersion == "" {
+		apiVersion = defaultClusterOptions.APIVersionName()
+	} else if !strings.EqualFold(apiVersion, tikv.APIVersionV2) {
+		keyspace = ""
+	}
+	if req.Keyspace != nil {
+		keyspace = *req.Keyspace
+	}
+	return tikv.ParseClusterOptions(apiVersion, keyspace)
+}
+
 func setCurrentEndpoints(endpoints []string) {
 	endpointsMu.Lock()
 	currentEndpoints = append([]string{}, endpoints...)
@@ -149,10 +466,59 @@ func getCurrentEndpoints() []string {
 	return append([]string{}, currentEndpoints...)
 }
 
-// CloseTiKVClient 关闭 TiKV 客户端
-func CloseTiKVClient() {
-	log.Println("Closing TiKV client")
-	// TODO: 实现 TiKV 客户端关闭
+// parseReadTS 解析 ts 查询参数，只有 Txn 模式支持历史快照读取
+func parseReadTS(c *gin.Context, kvType string) (uint64, error) {
+	tsParam := c.Query("ts")
+	if tsParam == "" {
+		return 0, nil
+	}
+	if kvType != "txn" {
+		return 0, fmt.Errorf("ts is only supported in txn mode")
+	}
+	return tikv.ParseTimestamp(tsParam)
+}
+
+// readErrorStatus 读取时间戳不合法时返回 400，其余错误返回 500
+func readErrorStatus(err error) int {
+	if tikv.IsInvalidReadTS(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// commitOptionsFromQuery 从查询参数中解析提交选项
+func commitOptionsFromQuery(c *gin.Context) tikv.CommitOptions {
+	var req CommitOptionsRequest
+	if v, err := strconv.ParseBool(c.Query("asyncCommit")); err == nil {
+		req.AsyncCommit = &v
+	}
+	if v, err := strconv.ParseBool(c.Query("onePC")); err == nil {
+		req.OnePC = &v
+	}
+	return tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
+}
+
+// writeTxn 在一个事务中执行写操作，并按提交选项提交
+func writeTxn(ctx context.Context, opts tikv.CommitOptions, fn func(txn *txnkv.KVTxn) error) (*tikv.CommitResult, error) {
+	txnClient := txnClientFrom(ctx)
+	if txnClient == nil {
+		return nil, fmt.Errorf("transaction client not initialized")
+	}
+	txn, err := txnClient.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(txn); err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+	return tikv.CommitWithOptions(ctx, txn, opts)
+}
+
+// CloseTiKVClient 关闭所有集群的 TiKV 客户端，最多等待 drainTimeout 让仍在使用的请求结束
+func CloseTiKVClient(drainTimeout time.Duration) {
+	logging.L().Info("closing TiKV clients")
+	tikv.CloseClients(drainTimeout)
 }
 
 // SetupRouter 设置路由
@@ -160,8 +526,12 @@ func SetupRouter() *gin.Engine {
 	router := gin.New()
 
 	// 中间件
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(logging.Middleware())
+	router.Use(tracing.Middleware())
+	router.Use(logging.Recovery())
+	router.Use(metrics.Middleware())
+	router.Use(bodyLimitMiddleware())
+	router.Use(clientsMiddleware())
 
 	// CORS 中间件
 	router.Use(func(c *gin.Context) {
@@ -177,17 +547,20 @@ func SetupRouter() *gin.Engine {
 		c.Next()
 	})
 
-	// 健康检查
-	router.GET("/health", func(c *gin.Context) {
-		log.Println("🔥 HEALTH CHECK CALLED - NEW CODE")
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "ok",
-			"message": "TiKV Backend is healthy - NEW",
-		})
-	})
-	router.HEAD("/health", func(c *gin.Context) {
-		c.Status(http.StatusOK)
-	})
+	// 存活和就绪探针，/health 与 /livez 相同
+	for _, path := range []string{"/livez", "/health"} {
+		router.GET(path, handleLiveness)
+		router.HEAD(path, handleLiveness)
+	}
+	router.GET("/readyz", handleReadiness)
+	router.HEAD("/readyz", handleReadiness)
+
+	// Prometheus 指标
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// 查看和修改日志级别
+	router.GET("/log/level", gin.WrapH(logging.LevelHandler()))
+	router.PUT("/log/level", gin.WrapH(logging.LevelHandler()))
 
 	// API 路由组
 	api := router.Group("/api/kv")
@@ -210,8 +583,26 @@ func SetupRouter() *gin.Engine {
 
 		// 统计和状态
 		api.GET("/stats", handleGetStats)
+		api.POST("/stats/recount", handleRecountStats)
+		api.GET("/stats/jobs/:id", handleGetStatsJob)
+		api.DELETE("/stats/jobs/:id", handleCancelStatsJob)
 		api.GET("/cluster", handleGetClusterStatus)
+		api.GET("/regions", handleGetRegions)
+		api.GET("/hotspots", handleGetHotspots)
+
+		// 锁检查和处理（Txn 模式）
+		api.GET("/locks", handleScanLocks)
+		api.POST("/locks/resolve", handleResolveLocks)
+
+		// GC safe point、TSO 和单个 key 的 MVCC 版本（Txn 模式）
+		api.GET("/gc", handleGetGCStatus)
+		api.GET("/tso", handleGetTSO)
+		api.GET("/tso/convert", handleConvertTSO)
+		api.GET("/mvcc/:key", handleGetMvccHistory)
 		api.PUT("/cluster/endpoints", handleUpdateClusterEndpoints)
+
+		// 当前生效的配置
+		api.GET("/config", handleGetConfig)
 	}
 
 	return router
@@ -219,10 +610,18 @@ func SetupRouter() *gin.Engine {
 
 // API 处理函数
 func handleScanKVs(c *gin.Context) {
-	prefix := c.Query("prefix")
 	page := 1
 	limit := 100
-	kvType := c.Query("type")
+	kvType := c.DefaultQuery("type", "rawkv")
+	if kvType != "rawkv" && kvType != "txn" {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid type parameter",
+			Error:   "type must be rawkv or txn",
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
 
 	// 解析分页参数
 	if p := c.Query("page"); p != "" {
@@ -236,20 +635,85 @@ func handleScanKVs(c *gin.Context) {
 		}
 	}
 
-	ctx := context.Background()
-	var kvPairs []KeyValuePair
-	var total int
-	var err error
+	readTS, err := parseReadTS(c, kvType)
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid ts parameter",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
 
-	if kvType == "rawkv" && rawKvClient != nil {
-		kvPairs, total, err = scanRawKVs(ctx, prefix, page, limit)
-	} else if kvType == "txn" && txnClient != nil {
-		kvPairs, total, err = scanTxnKVs(ctx, prefix, page, limit)
-	} else {
-		// 如果没有指定类型或客户端不可用，返回空结果
-		kvPairs = []KeyValuePair{}
-		total = 0
-		err = nil
+	scanRange, err := scanRangeFromQuery(c)
+	reverse := false
+	if err == nil {
+		reverse, err = queryBool(c, "reverse", false)
+	}
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid scan range",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	valueOpts, err := scanValueOptionsFromQuery(c)
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid value options",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	scanFilter, scanBudget, err := scanFilterFromQuery(c)
+	if err == nil && scanFilter != nil && valueOpts.KeysOnly && scanFilter.NeedsValue() {
+		err = fmt.Errorf("value filters cannot be combined with keysOnly")
+	}
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid filter",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	req := scanRequest{
+		Range:      scanRange,
+		Reverse:    reverse,
+		Page:       page,
+		Limit:      limit,
+		ReadTS:     readTS,
+		Values:     valueOpts,
+		Filter:     scanFilter,
+		ScanBudget: scanBudget,
+	}
+
+	ctx := c.Request.Context()
+	var result *scanResult
+
+	switch {
+	case kvType == "rawkv" && rawClientFrom(ctx) != nil:
+		result, err = scanRawKVs(ctx, req)
+	case kvType == "txn" && txnClientFrom(ctx) != nil:
+		result, err = scanTxnKVs(ctx, req)
+	default:
+		// 客户端不可用时不能返回空结果，否则调用方会误以为范围内没有数据
+		response := ApiResponse{
+			Success: false,
+			Message: "TiKV client not initialized",
+			Error:   fmt.Sprintf("no %s client is connected", kvType),
+		}
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
 	}
 
 	if err != nil {
@@ -258,20 +722,37 @@ func handleScanKVs(c *gin.Context) {
 			Message: "Failed to scan keys: " + err.Error(),
 			Error:   err.Error(),
 		}
-		c.JSON(http.StatusInternalServerError, response)
+		c.JSON(readErrorStatus(err), response)
 		return
 	}
 
+	for _, pair := range result.Pairs {
+		size := len(pair.Value)
+		if pair.ValueSize != nil {
+			size = *pair.ValueSize
+		}
+		recordAccess(kvType, hotspot.OpRead, prefixedKey(pair.Key), size)
+	}
+
 	// 计算总页数
-	totalPages := (total + limit - 1) / limit
+	totalPages := (result.Total + limit - 1) / limit
 
 	// 构建分页结果
 	paginatedResult := PaginatedResult{
-		Data:       kvPairs,
-		Total:      total,
+		Data:       result.Pairs,
+		Total:      result.Total,
 		Page:       page,
 		Limit:      limit,
 		TotalPages: totalPages,
+		ReadTS:     result.ReadTS,
+	}
+	// 带过滤条件时返回扫描统计，预算耗尽时客户端可以从 resumeKey 继续扫描
+	if scanFilter != nil {
+		paginatedResult.Scanned = result.Scanned
+		paginatedResult.BudgetExhausted = result.Exhausted
+		if result.Exhausted {
+			paginatedResult.ResumeKey = string(result.LastKey)
+		}
 	}
 
 	// 返回标准API响应
@@ -286,15 +767,85 @@ func handleScanKVs(c *gin.Context) {
 
 func handleGetKV(c *gin.Context) {
 	key := c.Param("key")
-	kvData := KeyValuePair{
-		Key:   key,
-		Value: "",
+	kvType := c.DefaultQuery("type", "rawkv")
+	keyBytes := prefixedKey(key)
+
+	readTS, err := parseReadTS(c, kvType)
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid ts parameter",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
+	var value []byte
+	found := false
+
+	if kvType == "rawkv" && rawClient != nil {
+		// RawKV 不存在的键返回 nil
+		opCtx, op := tracing.StartOp(ctx, kvType, metrics.OpGet, tracing.Key("tikv.key", keyBytes))
+		value, err = rawClient.Get(opCtx, keyBytes)
+		op.End(err)
+		found = err == nil && value != nil
+	} else if kvType == "txn" && txnClient != nil {
+		var snapshot *txnkv.KVSnapshot
+		snapshot, readTS, err = txnClient.SnapshotAt(ctx, readTS)
+		if err == nil {
+			opCtx, op := tracing.StartOp(ctx, kvType, metrics.OpGet, tracing.Key("tikv.key", keyBytes), attribute.Int64("tikv.read_ts", int64(readTS)))
+			value, err = snapshot.Get(opCtx, keyBytes)
+			if tikverr.IsErrNotFound(err) {
+				err = nil
+			} else {
+				found = err == nil
+			}
+			op.End(err)
+		}
+	} else {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid type or client not available",
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Failed to get key: " + err.Error(),
+			Error:   err.Error(),
+		}
+		c.JSON(readErrorStatus(err), response)
+		return
+	}
+
+	recordAccess(kvType, hotspot.OpRead, keyBytes, len(value))
+	metrics.ObserveScan(kvType, 1, len(keyBytes)+len(value), len(key)+len(value))
+
+	if !found {
+		response := ApiResponse{
+			Success: false,
+			Message: "Key not found",
+		}
+		c.JSON(http.StatusNotFound, response)
+		return
 	}
 
 	response := ApiResponse{
 		Success: true,
 		Message: "Get key successful",
-		Data:    kvData,
+		Data: GetKVResponse{
+			KeyValuePair: KeyValuePair{
+				Key:   key,
+				Value: string(value),
+			},
+			ReadTS: readTS,
+		},
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -305,6 +856,7 @@ func handleCreateKV(c *gin.Context) {
 		Key   string `json:"key" binding:"required"`
 		Value string `json:"value" binding:"required"`
 		Type  string `json:"type"`
+		CommitOptionsRequest
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -316,25 +868,25 @@ func handleCreateKV(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
+	metrics.SetMode(c, req.Type)
 
-	ctx := context.Background()
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
 	var err error
+	var commitResult *tikv.CommitResult
 	keyBytes := prefixedKey(req.Key)
 
-	if req.Type == "rawkv" && rawKvClient != nil {
+	if req.Type == "rawkv" && rawClient != nil {
 		// 使用 RawKV 模式插入
-		err = tikv.RawKVClient.Put(ctx, keyBytes, []byte(req.Value))
+		opCtx, op := tracing.StartOp(ctx, req.Type, metrics.OpPut, tracing.Key("tikv.key", keyBytes))
+		err = rawClient.Put(opCtx, keyBytes, []byte(req.Value))
+		op.End(err)
 	} else if req.Type == "txn" && txnClient != nil {
 		// 使用 Transaction 模式插入
-		txn, err := txnClient.Begin()
-		if err == nil {
-			err = txn.Set(keyBytes, []byte(req.Value))
-			if err == nil {
-				err = txn.Commit(ctx)
-			} else {
-				txn.Rollback()
-			}
-		}
+		opts := tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
+		commitResult, err = writeTxn(ctx, opts, func(txn *txnkv.KVTxn) error {
+			return txn.Set(keyBytes, []byte(req.Value))
+		})
 	} else {
 		response := ApiResponse{
 			Success: false,
@@ -353,11 +905,15 @@ func handleCreateKV(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, response)
 		return
 	}
+	recordAccess(req.Type, hotspot.OpWrite, keyBytes, len(req.Value))
 
 	response := ApiResponse{
 		Success: true,
 		Message: "Create key successful",
 	}
+	if commitResult != nil {
+		response.Data = commitResult
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -367,6 +923,7 @@ func handleUpdateKV(c *gin.Context) {
 		Key   string `json:"key" binding:"required"`
 		Value string `json:"value" binding:"required"`
 		Type  string `json:"type"`
+		CommitOptionsRequest
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -378,25 +935,25 @@ func handleUpdateKV(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
+	metrics.SetMode(c, req.Type)
 
-	ctx := context.Background()
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
 	var err error
+	var commitResult *tikv.CommitResult
 	keyBytes := prefixedKey(req.Key)
 
-	if req.Type == "rawkv" && rawKvClient != nil {
+	if req.Type == "rawkv" && rawClient != nil {
 		// 使用 RawKV 模式更新
-		err = tikv.RawKVClient.Put(ctx, keyBytes, []byte(req.Value))
+		opCtx, op := tracing.StartOp(ctx, req.Type, metrics.OpPut, tracing.Key("tikv.key", keyBytes))
+		err = rawClient.Put(opCtx, keyBytes, []byte(req.Value))
+		op.End(err)
 	} else if req.Type == "txn" && txnClient != nil {
 		// 使用 Transaction 模式更新
-		txn, err := txnClient.Begin()
-		if err == nil {
-			err = txn.Set(keyBytes, []byte(req.Value))
-			if err == nil {
-				err = txn.Commit(ctx)
-			} else {
-				txn.Rollback()
-			}
-		}
+		opts := tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
+		commitResult, err = writeTxn(ctx, opts, func(txn *txnkv.KVTxn) error {
+			return txn.Set(keyBytes, []byte(req.Value))
+		})
 	} else {
 		response := ApiResponse{
 			Success: false,
@@ -415,11 +972,15 @@ func handleUpdateKV(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, response)
 		return
 	}
+	recordAccess(req.Type, hotspot.OpWrite, keyBytes, len(req.Value))
 
 	response := ApiResponse{
 		Success: true,
 		Message: "Update key successful",
 	}
+	if commitResult != nil {
+		response.Data = commitResult
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -438,23 +999,21 @@ func handleDeleteKV(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
 	var err error
+	var commitResult *tikv.CommitResult
 
-	if kvType == "rawkv" && rawKvClient != nil {
+	if kvType == "rawkv" && rawClient != nil {
 		// 使用 RawKV 模式删除
-		err = tikv.RawKVClient.Delete(ctx, keyBytes)
+		opCtx, op := tracing.StartOp(ctx, kvType, metrics.OpDelete, tracing.Key("tikv.key", keyBytes))
+		err = rawClient.Delete(opCtx, keyBytes)
+		op.End(err)
 	} else if kvType == "txn" && txnClient != nil {
 		// 使用 Transaction 模式删除
-		txn, err := txnClient.Begin()
-		if err == nil {
-			err = txn.Delete(keyBytes)
-			if err == nil {
-				err = txn.Commit(ctx)
-			} else {
-				txn.Rollback()
-			}
-		}
+		commitResult, err = writeTxn(ctx, commitOptionsFromQuery(c), func(txn *txnkv.KVTxn) error {
+			return txn.Delete(keyBytes)
+		})
 	} else {
 		response := ApiResponse{
 			Success: false,
@@ -473,11 +1032,15 @@ func handleDeleteKV(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, response)
 		return
 	}
+	recordAccess(kvType, hotspot.OpWrite, keyBytes, 0)
 
 	response := ApiResponse{
 		Success: true,
 		Message: "Delete key successful",
 	}
+	if commitResult != nil {
+		response.Data = commitResult
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -485,6 +1048,7 @@ func handleDeleteKV(c *gin.Context) {
 // BatchOperationRequest 批量操作请求
 type BatchOperationRequest struct {
 	Operations []Operation `json:"operations" binding:"required,min=1"`
+	CommitOptionsRequest
 }
 
 // Operation 单个操作
@@ -500,6 +1064,8 @@ type BatchOperationResult struct {
 	Operation string `json:"operation"`
 	Success   bool   `json:"success"`
 	Error     string `json:"error,omitempty"`
+	// Commit 仅 Txn 操作提交成功时返回
+	Commit *tikv.CommitResult `json:"commit,omitempty"`
 }
 
 // BatchOperationData 批量操作数据
@@ -517,7 +1083,6 @@ type BatchOperationResponse struct {
 }
 
 func handleBatchOperations(c *gin.Context) {
-	log.Println("🔥 NEW BATCH OPERATIONS HANDLER CALLED")
 	var req BatchOperationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response := ApiResponse{
@@ -529,7 +1094,8 @@ func handleBatchOperations(c *gin.Context) {
 		return
 	}
 
-	requestCtx := context.Background()
+	requestCtx := context.WithoutCancel(c.Request.Context())
+	commitOpts := tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
 	var results []BatchOperationResult
 
 	for _, op := range req.Operations {
@@ -545,26 +1111,20 @@ func handleBatchOperations(c *gin.Context) {
 		}
 
 		if op.Type == "rawkv" {
-			if rawKvClient == nil {
+			client := rawClientFrom(requestCtx)
+			if client == nil {
 				result.Success = false
 				result.Error = "TiKV RawKV client not initialized"
 				results = append(results, result)
 				continue
 			}
 
-			// 使用全局 RawKVClient
-			if tikv.RawKVClient == nil {
-				result.Success = false
-				result.Error = "RawKV client is nil"
-				results = append(results, result)
-				continue
-			}
-			client := tikv.RawKVClient
-
 			prefixedKey := prefixedKey(op.Key)
 
 			if operationType == "put" {
-				err := client.Put(requestCtx, prefixedKey, []byte(op.Value))
+				opCtx, kvOp := tracing.StartOp(requestCtx, op.Type, metrics.OpPut, tracing.Key("tikv.key", prefixedKey))
+				err := client.Put(opCtx, prefixedKey, []byte(op.Value))
+				kvOp.End(err)
 				if err != nil {
 					result.Success = false
 					result.Error = err.Error()
@@ -573,12 +1133,16 @@ func handleBatchOperations(c *gin.Context) {
 				}
 			} else {
 				// 删除操作 - 先检查键是否存在
-				existingValue, err := client.Get(requestCtx, prefixedKey)
+				opCtx, kvOp := tracing.StartOp(requestCtx, op.Type, metrics.OpGet, tracing.Key("tikv.key", prefixedKey))
+				existingValue, err := client.Get(opCtx, prefixedKey)
+				kvOp.End(err)
 				if err != nil || len(existingValue) == 0 {
 					result.Success = false
 					result.Error = "Key not found"
 				} else {
-					err = client.Delete(requestCtx, prefixedKey)
+					opCtx, kvOp := tracing.StartOp(requestCtx, op.Type, metrics.OpDelete, tracing.Key("tikv.key", prefixedKey))
+					err = client.Delete(opCtx, prefixedKey)
+					kvOp.End(err)
 					if err != nil {
 						result.Success = false
 						result.Error = err.Error()
@@ -589,26 +1153,18 @@ func handleBatchOperations(c *gin.Context) {
 			}
 
 		} else if op.Type == "txn" {
-			if txnClient == nil {
+			client := txnClientFrom(requestCtx)
+			if client == nil {
 				result.Success = false
 				result.Error = "TiKV TxnKV client not initialized"
 				results = append(results, result)
 				continue
 			}
 
-			// 使用全局 TxnKVClient
-			if tikv.TxnKVClient == nil {
+			txn, err := client.Begin()
+			if err != nil {
 				result.Success = false
-				result.Error = "TxnKV client is nil"
-				results = append(results, result)
-				continue
-			}
-			client := tikv.TxnKVClient
-
-			txn, err := client.Begin()
-			if err != nil {
-				result.Success = false
-				result.Error = "Failed to begin transaction: " + err.Error()
+				result.Error = "Failed to begin transaction: " + err.Error()
 				results = append(results, result)
 				continue
 			}
@@ -622,7 +1178,7 @@ func handleBatchOperations(c *gin.Context) {
 					result.Success = false
 					result.Error = err.Error()
 				} else {
-					err = txn.Commit(requestCtx)
+					result.Commit, err = tikv.CommitWithOptions(requestCtx, txn, commitOpts)
 					if err != nil {
 						result.Success = false
 						result.Error = "Failed to commit transaction: " + err.Error()
@@ -644,7 +1200,7 @@ func handleBatchOperations(c *gin.Context) {
 						result.Success = false
 						result.Error = err.Error()
 					} else {
-						err = txn.Commit(requestCtx)
+						result.Commit, err = tikv.CommitWithOptions(requestCtx, txn, commitOpts)
 						if err != nil {
 							result.Success = false
 							result.Error = "Failed to commit transaction: " + err.Error()
@@ -660,6 +1216,9 @@ func handleBatchOperations(c *gin.Context) {
 			result.Error = "Invalid operation type. Must be 'rawkv' or 'txn'"
 		}
 
+		if result.Success {
+			recordAccess(op.Type, hotspot.OpWrite, prefixedKey(op.Key), len(op.Value))
+		}
 		results = append(results, result)
 	}
 
@@ -696,6 +1255,7 @@ func handleBatchDeleteKVs(c *gin.Context) {
 	var req struct {
 		Keys []string `json:"keys" binding:"required"`
 		Type string   `json:"type"`
+		CommitOptionsRequest
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -716,28 +1276,32 @@ func handleBatchDeleteKVs(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
+	metrics.SetMode(c, req.Type)
 
-	ctx := context.Background()
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
+	commitOpts := tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
 	deletedCount := 0
 	var errors []string
+	var commits []*tikv.CommitResult
 
 	for _, key := range req.Keys {
 		var err error
 		keyBytes := prefixedKey(key)
 
-		if req.Type == "rawkv" && rawKvClient != nil {
+		if req.Type == "rawkv" && rawClient != nil {
 			// 使用 RawKV 模式删除
-			err = tikv.RawKVClient.Delete(ctx, keyBytes)
+			opCtx, op := tracing.StartOp(ctx, req.Type, metrics.OpDelete, tracing.Key("tikv.key", keyBytes))
+			err = rawClient.Delete(opCtx, keyBytes)
+			op.End(err)
 		} else if req.Type == "txn" && txnClient != nil {
 			// 使用 Transaction 模式删除
-			txn, err := txnClient.Begin()
+			var commitResult *tikv.CommitResult
+			commitResult, err = writeTxn(ctx, commitOpts, func(txn *txnkv.KVTxn) error {
+				return txn.Delete(keyBytes)
+			})
 			if err == nil {
-				err = txn.Delete(keyBytes)
-				if err == nil {
-					err = txn.Commit(ctx)
-				} else {
-					txn.Rollback()
-				}
+				commits = append(commits, commitResult)
 			}
 		} else {
 			errors = append(errors, fmt.Sprintf("Key %s: invalid type or client not available", key))
@@ -748,17 +1312,23 @@ func handleBatchDeleteKVs(c *gin.Context) {
 			errors = append(errors, fmt.Sprintf("Key %s: %v", key, err))
 		} else {
 			deletedCount++
+			recordAccess(req.Type, hotspot.OpWrite, keyBytes, 0)
 		}
 	}
 
+	data := map[string]interface{}{
+		"deletedCount": deletedCount,
+		"errorCount":   len(errors),
+		"errors":       errors,
+	}
+	if req.Type == "txn" {
+		data["commits"] = commits
+	}
+
 	response := ApiResponse{
 		Success: len(errors) == 0,
 		Message: fmt.Sprintf("Batch delete completed. Deleted: %d, Errors: %d", deletedCount, len(errors)),
-		Data: map[string]interface{}{
-			"deletedCount": deletedCount,
-			"errorCount":   len(errors),
-			"errors":       errors,
-		},
+		Data:    data,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -766,12 +1336,13 @@ func handleBatchDeleteKVs(c *gin.Context) {
 
 func handleDeleteAllKVs(c *gin.Context) {
 	kvType := c.DefaultQuery("type", "rawkv")
-	ctx := context.Background()
+	ctx := context.WithoutCancel(c.Request.Context())
+	rawClient, txnClient := rawClientFrom(ctx), txnClientFrom(ctx)
 	deletedCount := 0
 
 	switch kvType {
 	case "rawkv":
-		if rawKvClient == nil || tikv.RawKVClient == nil {
+		if rawClient == nil {
 			response := ApiResponse{
 				Success: false,
 				Message: "TiKV RawKV client not initialized",
@@ -785,7 +1356,10 @@ func handleDeleteAllKVs(c *gin.Context) {
 		scanStart := startKey
 
 		for {
-			keys, _, err := tikv.RawKVClient.Scan(ctx, scanStart, endKey, batchSize)
+			opCtx, op := tracing.StartOp(ctx, kvType, metrics.OpScan, tracing.Range(scanStart, endKey)...)
+			keys, _, err := rawClient.Scan(opCtx, scanStart, endKey, batchSize)
+			op.SetAttributes(tracing.KeyCount(len(keys)))
+			op.End(err)
 			if err != nil {
 				response := ApiResponse{
 					Success: false,
@@ -801,7 +1375,10 @@ func handleDeleteAllKVs(c *gin.Context) {
 			}
 
 			for _, key := range keys {
-				if err := tikv.RawKVClient.Delete(ctx, key); err != nil {
+				opCtx, op := tracing.StartOp(ctx, kvType, metrics.OpDelete, tracing.Key("tikv.key", key))
+				err := rawClient.Delete(opCtx, key)
+				op.End(err)
+				if err != nil {
 					response := ApiResponse{
 						Success: false,
 						Message: "Failed to delete key: " + err.Error(),
@@ -828,6 +1405,7 @@ func handleDeleteAllKVs(c *gin.Context) {
 
 		startKey, endKey := prefixedRange("")
 		batchSize := 200
+		commitOpts := commitOptionsFromQuery(c)
 
 		for {
 			txn, err := txnClient.Begin()
@@ -888,7 +1466,7 @@ func handleDeleteAllKVs(c *gin.Context) {
 				}
 			}
 
-			if err := txn.Commit(ctx); err != nil {
+			if _, err := tikv.CommitWithOptions(ctx, txn, commitOpts); err != nil {
 				response := ApiResponse{
 					Success: false,
 					Message: "Failed to commit transaction: " + err.Error(),
@@ -925,263 +1503,1396 @@ func handleDeleteAllKVs(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// AtomicTransactionRequest 原子事务请求
+type AtomicTransactionRequest struct {
+	Operations []AtomicOperation `json:"operations" binding:"required,min=1"`
+	CommitOptionsRequest
+}
+
+// AtomicOperation 原子操作
+type AtomicOperation struct {
+	Type  string `json:"type" binding:"required,oneof=put delete"`
+	Key   string `json:"key" binding:"required"`
+	Value string `json:"value,omitempty"`
+}
+
+// AtomicTransactionData 原子事务结果
+type AtomicTransactionData struct {
+	OperationCount int                `json:"operationCount"`
+	Commit         *tikv.CommitResult `json:"commit"`
+}
+
 func handleAtomicTransaction(c *gin.Context) {
-	response := ApiResponse{
-		Success: true,
-		Message: "Atomic transaction successful",
+	var req AtomicTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	metrics.SetMode(c, "txn")
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		}
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
 
-func handleGetStats(c *gin.Context) {
-	statsData := StatsResponse{
-		TotalKeys: 0,
-		RawkvKeys: 0,
-		TxnKeys:   0,
+	ctx := context.WithoutCancel(c.Request.Context())
+	opts := tikv.ResolveCommitOptions(req.AsyncCommit, req.OnePC)
+	commitResult, err := writeTxn(ctx, opts, func(txn *txnkv.KVTxn) error {
+		for _, op := range req.Operations {
+			keyBytes := prefixedKey(op.Key)
+			if op.Type == "put" {
+				if err := txn.Set(keyBytes, []byte(op.Value)); err != nil {
+					return fmt.Errorf("set key %s: %v", op.Key, err)
+				}
+			} else {
+				if err := txn.Delete(keyBytes); err != nil {
+					return fmt.Errorf("delete key %s: %v", op.Key, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		response := ApiResponse{
+			Success: false,
+			Message: "Atomic transaction failed: " + err.Error(),
+			Error:   err.Error(),
+		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	for _, op := range req.Operations {
+		recordAccess("txn", hotspot.OpWrite, prefixedKey(op.Key), len(op.Value))
 	}
 
 	response := ApiResponse{
 		Success: true,
-		Message: "Get stats successful",
-		Data:    statsData,
+		Message: "Atomic transaction successful",
+		Data: AtomicTransactionData{
+			OperationCount: len(req.Operations),
+			Commit:         commitResult,
+		},
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func handleGetClusterStatus(c *gin.Context) {
-	endpoints := getCurrentEndpoints()
+// statsScanFunc 返回统计指定模式、指定前缀时使用的扫描函数，客户端未初始化时返回错误。
+// 统计在后台进行，扫描函数运行时自己获取并释放客户端的引用，不依赖发起请求的生命周期。
+func statsScanFunc(ctx context.Context, kvType, prefix string) (tikv.StatsScanFunc, error) {
+	r := tikv.PrefixRange(prefixedKey(prefix))
 
-	clusterData := ClusterStatusResponse{
-		ClusterStatus: "healthy",
-		Endpoints:     endpoints,
+	switch kvType {
+	case "rawkv":
+		if rawClientFrom(ctx) == nil {
+			return nil, fmt.Errorf("rawkv client not initialized")
+		}
+		return func(ctx context.Context, fn tikv.ScanFunc) error {
+			clients := tikv.AcquireClients()
+			defer clients.Release()
+			client := clients.RawKV()
+			if client == nil {
+				return fmt.Errorf("rawkv client not initialized")
+			}
+			return tikv.ScanRawRange(ctx, client, r, false, tikv.DefaultScanBatchSize, fn)
+		}, nil
+	case "txn":
+		if txnClientFrom(ctx) == nil {
+			return nil, fmt.Errorf("transaction client not initialized")
+		}
+		return func(ctx context.Context, fn tikv.ScanFunc) error {
+			clients := tikv.AcquireClients()
+			defer clients.Release()
+			tc := clients.Txn()
+			if tc == nil {
+				return fmt.Errorf("transaction client not initialized")
+			}
+			snapshot, _, err := tc.SnapshotAt(ctx, 0)
+			if err != nil {
+				return err
+			}
+			return tikv.ScanSnapshotRange(snapshot, r, false, fn)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid type parameter: %s", kvType)
+	}
+}
+
+// handleGetStats 返回缓存的统计信息，缓存过期时在后台重新统计；type 为空时统计两种模式
+func handleGetStats(c *gin.Context) {
+	kvType := c.Query("type")
+	prefix := c.Query("prefix")
+	if kvType != "" && kvType != "rawkv" && kvType != "txn" {
+		response := ApiResponse{
+			Success: false,
+			Message: "Invalid type parameter",
+			Error:   "type must be rawkv or txn",
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
 	}
 
+	clusterOpts := requestClients(c.Request.Context()).Options()
+	statsData := StatsResponse{
+		Prefix:     prefix,
+		APIVersion: clusterOpts.APIVersionName(),
+		Keyspace:   clusterOpts.Keyspace,
+	}
+
+	if kvType == "" || kvType == "rawkv" {
+		if scan, err := statsScanFunc(c.Request.Context(), "rawkv", prefix); err == nil {
+			stats, refreshing := kvStatsCache.Get("rawkv", prefix, scan)
+			statsData.RawKV = stats
+			statsData.Refreshing = statsData.Refreshing || refreshing
+			if stats != nil {
+				statsData.RawkvKeys = int(stats.KeyCount)
+			}
+		}
+	}
+	if kvType == "" || kvType == "txn" {
+		if scan, err := statsScanFunc(c.Request.Context(), "txn", prefix); err == nil {
+			stats, refreshing := kvStatsCache.Get("txn", prefix, scan)
+			statsData.Txn = stats
+			statsData.Refreshing = statsData.Refreshing || refreshing
+			if stats != nil {
+				statsData.TxnKeys = int(stats.KeyCount)
+			}
+		}
+	}
+	statsData.TotalKeys = statsData.RawkvKeys + statsData.TxnKeys
+
 	response := ApiResponse{
 		Success: true,
-		Message: "Get cluster status successful",
-		Data:    clusterData,
+		Message: "Get stats successful",
+		Data:    statsData,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func handleUpdateClusterEndpoints(c *gin.Context) {
-	var req UpdateClusterEndpointsRequest
+// handleRecountStats 启动一次精确重新统计任务，通过 /stats/jobs/:id 查询进度和结果
+func handleRecountStats(c *gin.Context) {
+	var req RecountStatsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ApiResponse{
+		response := ApiResponse{
 			Success: false,
-			Message: "Invalid request payload",
+			Message: "Invalid request body",
 			Error:   err.Error(),
-		})
+		}
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	endpoints, err := parseEndpoints(req.Endpoints)
+	scan, err := statsScanFunc(c.Request.Context(), req.Type, req.Prefix)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ApiResponse{
+		response := ApiResponse{
 			Success: false,
-			Message: "Invalid endpoints format",
+			Message: "Failed to start recount",
 			Error:   err.Error(),
-		})
+		}
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	if err := InitializeTiKVClient(endpoints); err != nil {
-		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+	job, started, err := kvStatsCache.Recount(req.Type, req.Prefix, scan)
+	if err != nil {
+		response := ApiResponse{
 			Success: false,
-			Message: "Failed to connect to TiKV cluster with provided endpoints",
+			Message: "Failed to start recount",
 			Error:   err.Error(),
-		})
+		}
+		c.JSON(http.StatusTooManyRequests, response)
 		return
 	}
 
-	setCurrentEndpoints(endpoints)
-
 	response := ApiResponse{
 		Success: true,
-		Message: "Cluster endpoints updated successfully",
-		Data: ClusterStatusResponse{
-			ClusterStatus: "healthy",
-			Endpoints:     endpoints,
-		},
+		Message: "Stats recount started",
+		Data:    job,
+	}
+	if !started {
+		response.Message = "Stats recount already running"
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusAccepted, response)
 }
 
-// scanRawKVs 扫描RawKV中的键值对
-func scanRawKVs(ctx context.Context, prefix string, page, limit int) ([]KeyValuePair, int, error) {
-	// 直接获取全局RawKV客户端
-	client := getGlobalRawKVClient()
-	if client == nil {
-		log.Printf("RawKV client is nil")
-		return nil, 0, nil
+func handleGetStatsJob(c *gin.Context) {
+	job, ok := kvStatsCache.Job(c.Param("id"))
+	if !ok {
+		response := ApiResponse{
+			Success: false,
+			Message: "Stats job not found",
+			Error:   "stats job not found",
+		}
+		c.JSON(http.StatusNotFound, response)
+		return
 	}
 
-	// 计算偏移量
-	offset := (page - 1) * limit
-
-	startKey, endKey := prefixedRange(prefix)
-	if prefix != "" {
-		log.Printf("Scanning TiKV with prefix: %s", prefix)
-	} else {
-		log.Printf("Scanning TiKV without prefix")
+	response := ApiResponse{
+		Success: true,
+		Message: "Get stats job successful",
+		Data:    job,
 	}
 
-	log.Printf("Start key: %s, End key: %s", string(startKey), string(endKey))
+	c.JSON(http.StatusOK, response)
+}
 
-	// 直接使用原始客户端进行扫描
-	keys, values, err := client.Scan(ctx, startKey, endKey, offset+limit)
+// handleCancelStatsJob 取消正在运行的重新统计任务
+func handleCancelStatsJob(c *gin.Context) {
+	job, ok, err := kvStatsCache.CancelJob(c.Param("id"))
+	if !ok {
+		response := ApiResponse{
+			Success: false,
+			Message: "Stats job not found",
+			Error:   "stats job not found",
+		}
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
 	if err != nil {
-		log.Printf("TiKV scan error: %v", err)
-		return nil, 0, err
+		response := ApiResponse{
+			Success: false,
+			Message: "Failed to cancel stats job",
+			Error:   err.Error(),
+			Data:    job,
+		}
+		c.JSON(http.StatusConflict, response)
+		return
 	}
 
-	log.Printf("TiKV scan result: found %d keys", len(keys))
-
-	// 应用分页
-	start := offset
-	end := offset + limit
-	if start > len(keys) {
-		return []KeyValuePair{}, len(keys), nil
-	}
-	if end > len(keys) {
-		end = len(keys)
+	response := ApiResponse{
+		Success: true,
+		Message: "Stats job canceled",
+		Data:    job,
 	}
 
-	// 转换为KeyValuePair格式
-	var kvPairs []KeyValuePair
-	for i := start; i < end; i++ {
-		key := keys[i]
-		value := values[i]
+	c.JSON(http.StatusOK, response)
+}
 
-		valueStr := string(value)
+// defaultRegionLimit 范围查询默认返回的 region 数量
+const defaultRegionLimit = 100
 
-		kvPairs = append(kvPairs, KeyValuePair{
-			Key:   string(key),
-			Value: valueStr,
+// handleGetRegions 查询 key 或范围所在的 region。
+// 指定 key 时只返回包含该 key 的 region，否则按 prefix、start、end 构造范围，返回覆盖范围的所有 region。
+func handleGetRegions(c *gin.Context) {
+	kvType := c.DefaultQuery("type", "rawkv")
+	if kvType != "rawkv" && kvType != "txn" {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid type parameter",
+			Error:   "type must be rawkv or txn",
 		})
+		return
 	}
 
-	return kvPairs, len(keys), nil
-}
-
-// scanTxnKVs 扫描TxnKV中的键值对
-func scanTxnKVs(ctx context.Context, prefix string, page, limit int) ([]KeyValuePair, int, error) {
-	log.Printf("Transaction模式扫描: prefix=%s, page=%d, limit=%d", prefix, page, limit)
-
-	// 确保事务客户端已初始化
-	if txnClient == nil {
-		log.Printf("TxnClient is nil")
-		return nil, 0, fmt.Errorf("transaction client not initialized")
+	limit, err := queryInt(c, "limit", defaultRegionLimit)
+	if err == nil && (limit == 0 || limit > pd.MaxRegionScanLimit) {
+		err = fmt.Errorf("limit must be between 1 and %d", pd.MaxRegionScanLimit)
 	}
-
-	// 创建事务用于读取
-	txn, err := txnClient.Begin()
-	if err != nil {
-		log.Printf("Failed to begin transaction: %v", err)
-		return nil, 0, err
+	var keyRange tikv.KeyRange
+	if err == nil {
+		if key := c.Query("key"); key != "" {
+			keyRange = tikv.KeyRange{Start: prefixedKey(key), End: tikv.KeyAfter(prefixedKey(key))}
+			limit = 1
+		} else {
+			keyRange, err = scanRangeFromQuery(c)
+		}
 	}
-
-	// 使用defer确保事务回滚（因为是只读事务）
-	defer txn.Rollback()
-
-	// 构造扫描范围
-	var startKey, endKey []byte
-	if prefix != "" {
-		startKey, endKey = prefixedRange(prefix)
-	} else {
-		startKey, endKey = prefixedRange("")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid region query",
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	log.Printf("Transaction scan range: %s to %s", string(startKey), string(endKey))
-
-	// 使用迭代器扫描
-	iter, err := txn.Iter(startKey, endKey)
+	result, err := lookupRegions(c.Request.Context(), kvType, keyRange, limit)
 	if err != nil {
-		log.Printf("Failed to create iterator: %v", err)
-		return nil, 0, err
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "Failed to get regions",
+			Error:   err.Error(),
+		})
+		return
 	}
-	defer iter.Close()
 
-	var kvPairs []KeyValuePair
-	skipCount := (page - 1) * limit
-	currentCount := 0
-	totalCount := 0
-
-	// 遍历迭代器
-	for iter.Valid() {
-		totalCount++
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get regions successful",
+		Data:    result,
+	})
+}
 
-		// 跳过前面的记录实现分页
-		if currentCount >= skipCount && len(kvPairs) < limit {
-			key := iter.Key()
-			value := iter.Value()
+// lookupRegions 把用户 key 范围按 API 版本和 keyspace 编码后向 PD 查询 region，再把 region 边界解码回用户 key
+func lookupRegions(ctx context.Context, kvType string, r tikv.KeyRange, limit int) (*RegionsResult, error) {
+	keyCodec := requestClients(ctx).KeyCodec(kvType)
+	encoded := keyCodec.EncodeRegionRange(r)
 
-			kvPairs = append(kvPairs, KeyValuePair{
-				Key:   string(key),
-				Value: string(value),
-			})
-			currentCount++
-		} else if currentCount < skipCount {
-			currentCount++
-		}
+	client := pd.NewClient(requestClients(ctx).Endpoints())
+	regions, err := client.ScanRegions(ctx, encoded.Start, encoded.End, limit)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := client.StoreAddresses(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to get store addresses", zap.Error(err))
+	}
 
-		if len(kvPairs) >= limit {
-			break
+	result := &RegionsResult{
+		Type:    kvType,
+		Regions: make([]RegionResponse, 0, len(regions)),
+	}
+	for _, region := range regions {
+		startKey, _, err := keyCodec.DecodeRegionKey(region.StartKey)
+		if err != nil {
+			return nil, err
 		}
-
-		err = iter.Next()
+		endKey, _, err := keyCodec.DecodeRegionKey(region.EndKey)
 		if err != nil {
-			log.Printf("Iterator next error: %v", err)
-			break
+			return nil, err
 		}
+		region.SetStoreAddresses(addresses)
+
+		result.Regions = append(result.Regions, RegionResponse{
+			Region:      region,
+			StartKey:    string(startKey),
+			EndKey:      string(endKey),
+			RawStartKey: strings.ToUpper(hex.EncodeToString(region.StartKey)),
+			RawEndKey:   strings.ToUpper(hex.EncodeToString(region.EndKey)),
+		})
 	}
+	result.Count = len(result.Regions)
 
-	log.Printf("Transaction scan result: total=%d, returned=%d", totalCount, len(kvPairs))
-	return kvPairs, totalCount, nil
+	if len(regions) == limit {
+		lastEnd := regions[len(regions)-1].EndKey
+		result.HasMore = len(lastEnd) > 0 && (len(encoded.End) == 0 || bytes.Compare(lastEnd, encoded.End) < 0)
+	}
+	return result, nil
 }
 
-func main() {
-	// 设置 Gin 模式
-	gin.SetMode(gin.ReleaseMode)
-
-	// 创建路由
-	router := SetupRouter()
+// handleScanLocks 扫描范围内未释放的 Percolator 锁，默认同时查询每个锁对应事务的状态
+func handleScanLocks(c *gin.Context) {
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		})
+		return
+	}
 
-	// 创建 HTTP 服务器
-	srv := &http.Server{
-		Addr:    ":3001",
-		Handler: router,
+	limit, err := queryInt(c, "limit", tikv.DefaultLockScanLimit)
+	if err == nil && limit == 0 {
+		err = fmt.Errorf("limit must be greater than 0")
+	}
+	var checkStatus bool
+	if err == nil {
+		checkStatus, err = queryBool(c, "checkStatus", true)
+	}
+	var keyRange tikv.KeyRange
+	if err == nil {
+		keyRange, err = scanRangeFromQuery(c)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid lock query",
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	// 启动服务器
-	go func() {
-		log.Printf("🚀 TiKV Backend Server running on port 3001")
-		log.Printf("📚 API Documentation: http://localhost:3001/api/kv")
-		log.Printf("🏥 Health Check: http://localhost:3001/health")
+	ctx := c.Request.Context()
+	locks, resumeKey, err := txnClient.ScanLocks(ctx, keyRange, limit)
+	if err == nil && checkStatus {
+		err = txnClient.FillTxnStatus(ctx, locks)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Success: false,
+			Message: "Failed to scan locks: " + err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+	if locks == nil {
+		locks = []tikv.LockInfo{}
+	}
 
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Scan locks successful",
+		Data: LocksResult{
+			Locks:     locks,
+			Count:     len(locks),
+			ResumeKey: string(resumeKey),
+		},
+	})
+}
 
-	// 等待中断信号优雅关闭
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+// handleResolveLocks 处理过期或残留的锁。confirm 为 false 时只返回预览，
+// 确认后按 primary 上事务的状态提交或回滚，TTL 未过期的锁不会被处理。
+func handleResolveLocks(c *gin.Context) {
+	var req ResolveLocksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid request parameters",
+			Error:   err.Error(),
+		})
+		return
+	}
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !req.Confirm {
+		previews := make([]LockResolvePreview, 0, len(req.Locks))
+		for _, ref := range req.Locks {
+			lock, err := txnClient.GetLock(ctx, prefixedKey(ref.Key), uint64(ref.StartTS))
+			if err == nil && lock != nil {
+				lock.Status, err = txnClient.CheckTxnStatus(ctx, []byte(lock.Primary), lock.StartTS)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ApiResponse{
+					Success: false,
+					Message: "Failed to check lock: " + err.Error(),
+					Error:   err.Error(),
+				})
+				return
+			}
+			preview := LockResolvePreview{Key: ref.Key, StartTS: uint64(ref.StartTS), Lock: lock}
+			if lock != nil {
+				// 事务已经提交或回滚时不论 TTL 都可以处理，否则只处理 TTL 过期的锁
+				finished := lock.Status.State == tikv.TxnStateCommitted || lock.Status.State == tikv.TxnStateRolledBack
+				preview.WouldResolve = lock.Expired || finished
+			}
+			previews = append(previews, preview)
+		}
+
+		c.JSON(http.StatusOK, ApiResponse{
+			Success: true,
+			Message: "Preview only, set confirm to true to resolve these locks",
+			Data:    previews,
+		})
+		return
+	}
+
+	keys := make([][]byte, 0, len(req.Locks))
+	startTSs := make([]uint64, 0, len(req.Locks))
+	for _, ref := range req.Locks {
+		keys = append(keys, prefixedKey(ref.Key))
+		startTSs = append(startTSs, uint64(ref.StartTS))
+	}
+	results, err := txnClient.ResolveLocks(ctx, keys, startTSs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Success: false,
+			Message: "Failed to resolve locks: " + err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+	for _, r := range results {
+		fields := []zap.Field{logging.Key("key", []byte(r.Key)), zap.Uint64("start_ts", r.StartTS), zap.String("result", r.Result)}
+		if r.Error != "" {
+			fields = append(fields, zap.String("error", r.Error))
+		}
+		logging.Ctx(c).Info("resolve lock", fields...)
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Resolve locks completed",
+		Data:    results,
+	})
+}
+
+// handleGetGCStatus 返回 GC safe point、各服务的 safe point 和当前 TSO
+func handleGetGCStatus(c *gin.Context) {
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	cli := txnClient.GetClient()
+	tso, err := tikv.GetTSO(ctx, cli)
+	var safePoint uint64
+	if err == nil {
+		safePoint, err = tikv.GetGCSafePoint(ctx, cli)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Success: false,
+			Message: "Failed to get GC status: " + err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	resp := GCStatusResponse{
+		GCSafePoint:       tikv.NewTSOInfo(safePoint),
+		ServiceSafePoints: []pd.ServiceSafePoint{},
+		TSO:               tso,
+	}
+	// 服务 safe point 只能通过 PD HTTP API 获取，失败时仍然返回其余信息
+	if points, _, err := pd.NewClient(requestClients(ctx).Endpoints()).ServiceSafePoints(ctx); err != nil {
+		resp.ServiceSafePointsError = err.Error()
+	} else {
+		resp.ServiceSafePoints = points
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get GC status successful",
+		Data:    resp,
+	})
+}
+
+// handleGetTSO 通过客户端的 oracle 从 PD 获取当前 TSO
+func handleGetTSO(c *gin.Context) {
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		})
+		return
+	}
+
+	tso, err := tikv.GetTSO(c.Request.Context(), txnClient.GetClient())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Success: false,
+			Message: "Failed to get TSO: " + err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get TSO successful",
+		Data:    tso,
+	})
+}
+
+// handleConvertTSO 在 TSO 和物理时间、逻辑计数器之间转换，不需要连接集群。
+// ts 为 TSO 或 RFC3339 时间，physical 为毫秒时间戳，两者只能传一个；logical 覆盖逻辑计数器。
+func handleConvertTSO(c *gin.Context) {
+	ts, err := tsoFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid timestamp",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Convert TSO successful",
+		Data:    tikv.NewTSOInfo(ts),
+	})
+}
+
+// tsoFromQuery 解析 ts、physical 和 logical 查询参数
+func tsoFromQuery(c *gin.Context) (uint64, error) {
+	tsParam, physicalParam := c.Query("ts"), c.Query("physical")
+	if (tsParam == "") == (physicalParam == "") {
+		return 0, fmt.Errorf("exactly one of ts and physical is required")
+	}
+
+	var physical, logical int64
+	if tsParam != "" {
+		ts, err := tikv.ParseTimestamp(tsParam)
+		if err != nil {
+			return 0, err
+		}
+		info := tikv.NewTSOInfo(ts)
+		physical, logical = info.Physical, info.Logical
+	} else {
+		var err error
+		if physical, err = strconv.ParseInt(physicalParam, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid physical %q: must be milliseconds since epoch", physicalParam)
+		}
+	}
+	if logicalParam := c.Query("logical"); logicalParam != "" {
+		var err error
+		if logical, err = strconv.ParseInt(logicalParam, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid logical %q", logicalParam)
+		}
+	}
+	return tikv.ComposeTimestamp(physical, logical)
+}
+
+// handleGetMvccHistory 返回 key 在 TiKV 中保存的所有 MVCC 版本，用于排查可见性问题
+func handleGetMvccHistory(c *gin.Context) {
+	key := c.Param("key")
+	txnClient := txnClientFrom(c.Request.Context())
+	if txnClient == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "TiKV TxnKV client not initialized",
+		})
+		return
+	}
+
+	history, err := txnClient.MvccHistory(c.Request.Context(), prefixedKey(key))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Success: false,
+			Message: "Failed to get MVCC history: " + err.Error(),
+			Error:   err.Error(),
+		})
+		return
+	}
+	history.Key = key
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get MVCC history successful",
+		Data:    history,
+	})
+}
+
+// recordAccess 记录一次成功的读写，用于热点分析
+func recordAccess(kvType, op string, key []byte, size int) {
+	accessTracker.Record(kvType, op, string(key), size)
+}
+
+// defaultHotspotTop 热点默认返回的数量
+const defaultHotspotTop = 10
+
+// handleGetHotspots 返回热点 region（来自 PD）以及后端统计的热点 key 和前缀。
+// type 为 read 或 write，为空时两者都返回；sort 为 qps 或 bytes。
+func handleGetHotspots(c *gin.Context) {
+	op := c.Query("type")
+	sortBy := c.DefaultQuery("sort", hotspot.SortByQPS)
+	top, err := queryInt(c, "top", defaultHotspotTop)
+	if err == nil && top == 0 {
+		err = fmt.Errorf("top must be greater than 0")
+	}
+	if err == nil && op != "" && op != hotspot.OpRead && op != hotspot.OpWrite {
+		err = fmt.Errorf("type must be read or write")
+	}
+	if err == nil && sortBy != hotspot.SortByQPS && sortBy != hotspot.SortByBytes {
+		err = fmt.Errorf("sort must be qps or bytes")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid hotspot query",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	data := HotspotsResponse{
+		WindowSeconds: int(accessTracker.Window().Seconds()),
+		SortBy:        sortBy,
+	}
+	client := pd.NewClient(requestClients(c.Request.Context()).Endpoints())
+	if op == "" || op == hotspot.OpRead {
+		data.Read = hotspotView(c.Request.Context(), client, hotspot.OpRead, sortBy, top)
+	}
+	if op == "" || op == hotspot.OpWrite {
+		data.Write = hotspotView(c.Request.Context(), client, hotspot.OpWrite, sortBy, top)
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get hotspots successful",
+		Data:    data,
+	})
+}
+
+func hotspotView(ctx context.Context, client *pd.Client, op, sortBy string, top int) *HotspotView {
+	view := &HotspotView{}
+	view.Keys, view.Prefixes = accessTracker.Top(op, sortBy, top)
+
+	regions, err := hotRegions(ctx, client, op, sortBy, top)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to get hot regions", zap.String("op", op), zap.Error(err))
+		view.RegionsError = err.Error()
+	}
+	view.Regions = regions
+	return view
+}
+
+// hotRegions 取 PD 统计的前 top 个热点 region，并把 region 边界解码为用户 key
+func hotRegions(ctx context.Context, client *pd.Client, op, sortBy string, top int) ([]HotRegionResponse, error) {
+	stats, err := client.HotRegions(ctx, op)
+	if err != nil {
+		return []HotRegionResponse{}, err
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if sortBy == hotspot.SortByBytes {
+			return stats[i].ByteRate > stats[j].ByteRate
+		}
+		return stats[i].QueryRate > stats[j].QueryRate
+	})
+	if len(stats) > top {
+		stats = stats[:top]
+	}
+
+	addresses, err := client.StoreAddresses(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to get store addresses", zap.Error(err))
+	}
+
+	// region 边界按请求所用集群的 API 版本和 keyspace 解码
+	clients := requestClients(ctx)
+	regions := make([]HotRegionResponse, 0, len(stats))
+	for _, stat := range stats {
+		hot := HotRegionResponse{
+			HotRegion:    stat,
+			StoreAddress: addresses[stat.StoreID],
+		}
+		region, err := client.Region(ctx, stat.RegionID)
+		if err != nil {
+			// region 可能已经分裂或合并，保留统计数据
+			logging.FromContext(ctx).Warn("failed to get hot region", zap.Uint64("region_id", stat.RegionID), zap.Error(err))
+			regions = append(regions, hot)
+			continue
+		}
+
+		hot.RawStartKey = strings.ToUpper(hex.EncodeToString(region.StartKey))
+		hot.RawEndKey = strings.ToUpper(hex.EncodeToString(region.EndKey))
+		hot.Mode = clients.ModeOfRegionKey(region.StartKey)
+		if hot.Mode == "" {
+			hot.Mode = clients.ModeOfRegionKey(region.EndKey)
+		}
+		if hot.Mode != "" {
+			keyCodec := clients.KeyCodec(hot.Mode)
+			startKey, _, startErr := keyCodec.DecodeRegionKey(region.StartKey)
+			endKey, _, endErr := keyCodec.DecodeRegionKey(region.EndKey)
+			if startErr == nil && endErr == nil {
+				hot.StartKey = string(startKey)
+				hot.EndKey = string(endKey)
+			}
+		}
+		regions = append(regions, hot)
+	}
+	return regions, nil
+}
+
+// handleLiveness 存活探针，只检查进程能否处理请求，不依赖 TiKV，避免集群故障时进程被反复重启
+func handleLiveness(c *gin.Context) {
+	report := health.Run(c.Request.Context(), currentProbeTimeout(), []health.Check{
+		{Name: "server", Run: func(ctx context.Context) error { return nil }},
+	})
+	c.JSON(report.HTTPStatus(), report)
+}
+
+// handleReadiness 就绪探针，并发检查 PD、TSO、RawKV 读写和 Txn 读取，任何一项失败都返回 503
+func handleReadiness(c *gin.Context) {
+	report := health.Run(c.Request.Context(), currentProbeTimeout(), readinessChecks(c.Request.Context()))
+	if report.Status != health.StatusOK {
+		logging.Ctx(c).Warn("readiness check failed", zap.Any("checks", report.Checks))
+	}
+	c.JSON(report.HTTPStatus(), report)
+}
+
+func currentProbeTimeout() time.Duration {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return probeTimeout
+}
+
+// readinessChecks 返回请求所用集群的就绪检查项
+func readinessChecks(ctx context.Context) []health.Check {
+	endpoints := requestClients(ctx).Endpoints()
+	rawClient := rawClientFrom(ctx)
+	txn := txnClientFrom(ctx)
+	settingsMu.RLock()
+	canaryKey := canaryKey
+	settingsMu.RUnlock()
+
+	return []health.Check{
+		{Name: "pd", Run: func(ctx context.Context) error {
+			return pd.NewClient(endpoints).CheckHealth(ctx)
+		}},
+		{Name: "tso", Run: func(ctx context.Context) error {
+			if txn == nil {
+				return fmt.Errorf("TiKV TxnKV client not initialized")
+			}
+			_, err := tikv.GetTSO(ctx, txn.GetClient())
+			return err
+		}},
+		{Name: "rawkv", Run: func(ctx context.Context) error {
+			if rawClient == nil {
+				return fmt.Errorf("TiKV RawKV client not initialized")
+			}
+			return tikv.RawCanary(ctx, rawClient, canaryKey)
+		}},
+		{Name: "txn", Run: func(ctx context.Context) error {
+			if txn == nil {
+				return fmt.Errorf("TiKV TxnKV client not initialized")
+			}
+			return txn.TxnCanary(ctx, canaryKey)
+		}},
+	}
+}
+
+// handleGetClusterStatus 从 PD 查询集群拓扑，集群状态根据 PD 成员和 TiKV 节点状态推导
+func handleGetClusterStatus(c *gin.Context) {
+	response := ApiResponse{
+		Success: true,
+		Message: "Get cluster status successful",
+		Data:    clusterStatus(c, requestClients(c.Request.Context())),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func handleUpdateClusterEndpoints(c *gin.Context) {
+	var req UpdateClusterEndpointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid request payload",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	endpoints, err := parseEndpoints(req.Endpoints)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid endpoints format",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	opts, err := clusterOptionsFor(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Success: false,
+			Message: "Invalid cluster options",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := switchCluster(endpoints, opts); err != nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "Failed to connect to TiKV cluster with provided endpoints",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 集群已经切换，写回配置文件失败只记录警告
+	if err := persistCluster(endpoints, opts); err != nil {
+		logging.Ctx(c).Warn("failed to persist cluster endpoints to config file", zap.Error(err))
+	}
+
+	clients := tikv.AcquireClients()
+	defer clients.Release()
+	response := ApiResponse{
+		Success: true,
+		Message: "Cluster endpoints updated successfully",
+		Data:    clusterStatus(c, clients),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// clusterStatus 返回 clients 所连接集群的状态，拓扑查询失败时集群状态为 unreachable
+func clusterStatus(c *gin.Context, clients *tikv.Clients) ClusterStatusResponse {
+	endpoints := clients.Endpoints()
+	clusterData := ClusterStatusResponse{
+		Endpoints:  endpoints,
+		APIVersion: clients.Options().APIVersionName(),
+		Keyspace:   clients.Options().Keyspace,
+		KeyspaceID: clients.KeyspaceID(),
+		Client:     tikv.CurrentClientTuning(),
+	}
+	if clients != nil {
+		clusterData.Client = clients.Tuning()
+	}
+
+	topology, err := pd.NewClient(endpoints).Topology(c.Request.Context())
+	if err != nil {
+		logging.Ctx(c).Warn("failed to get cluster topology", zap.Error(err))
+		clusterData.ClusterStatus = pd.HealthUnreachable
+		clusterData.Error = err.Error()
+	} else {
+		clusterData.ClusterStatus = topology.Health
+		clusterData.Topology = topology
+	}
+	return clusterData
+}
+
+// scanRequest 扫描请求参数
+type scanRequest struct {
+	Range   tikv.KeyRange
+	Reverse bool
+	Page    int
+	Limit   int
+	// ReadTS 仅 Txn 模式使用，0 表示读取最新数据
+	ReadTS uint64
+	Values scanValueOptions
+	// Filter 服务端过滤条件，nil 表示不过滤
+	Filter *filter.Filter
+	// ScanBudget 最多扫描的键数，0 表示不限制
+	ScanBudget int
+}
+
+// scanFilterFromQuery 解析过滤条件和扫描预算，没有过滤条件时返回 nil
+func scanFilterFromQuery(c *gin.Context) (*filter.Filter, int, error) {
+	minValueSize, err := queryInt(c, "minValueSize", -1)
+	if err != nil {
+		return nil, 0, err
+	}
+	maxValueSize, err := queryInt(c, "maxValueSize", -1)
+	if err != nil {
+		return nil, 0, err
+	}
+	budget, err := queryInt(c, "maxScan", defaultFilterScanBudget)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := filter.New(filter.Options{
+		KeyGlob:       c.Query("keyGlob"),
+		KeyRegex:      c.Query("keyRegex"),
+		ValueContains: c.Query("valueContains"),
+		ValueRegex:    c.Query("valueRegex"),
+		JSONPath:      c.Query("jsonPath"),
+		MinValueSize:  minValueSize,
+		MaxValueSize:  maxValueSize,
+	})
+	if err != nil || f == nil {
+		return nil, 0, err
+	}
+	return f, budget, nil
+}
+
+// scanValueOptions 控制扫描结果中值的返回方式，避免传输大 value
+type scanValueOptions struct {
+	// KeysOnly 只扫描 key，TiKV 不返回 value
+	KeysOnly bool
+	// Preview 大于等于 0 时只返回 value 的前 Preview 字节和 value 大小，-1 表示返回完整 value
+	Preview int
+	// Hash 返回 value 的 SHA-256
+	Hash bool
+	// ValueSize 只返回 value 大小，服务端仍然需要读取 value
+	ValueSize bool
+}
+
+// summarized 是否只返回 value 的摘要信息
+func (o scanValueOptions) summarized() bool {
+	return o.Preview >= 0 || o.Hash || o.ValueSize
+}
+
+// scanValueOptionsFromQuery 解析 keysOnly、valueSize、preview、hash 查询参数
+func scanValueOptionsFromQuery(c *gin.Context) (scanValueOptions, error) {
+	opts := scanValueOptions{Preview: -1}
+
+	var err error
+	if opts.KeysOnly, err = queryBool(c, "keysOnly", false); err != nil {
+		return opts, err
+	}
+	if opts.Hash, err = queryBool(c, "hash", false); err != nil {
+		return opts, err
+	}
+	if opts.ValueSize, err = queryBool(c, "valueSize", false); err != nil {
+		return opts, err
+	}
+	if raw := c.Query("preview"); raw != "" {
+		preview, err := strconv.Atoi(raw)
+		if err != nil || preview < 0 {
+			return opts, fmt.Errorf("invalid preview parameter: %s", raw)
+		}
+		opts.Preview = preview
+	}
+	if opts.KeysOnly && opts.summarized() {
+		return opts, fmt.Errorf("keysOnly cannot be combined with preview, hash or valueSize")
+	}
+	return opts, nil
+}
+
+// batchSize 返回 RawKV 分批扫描的批大小，需要 value 时用较小的批次限制单次响应的大小
+func (o scanValueOptions) batchSize(want int) int {
+	if o.KeysOnly {
+		return min(want, rawkv.MaxRawKVScanLimit)
+	}
+	return min(want, tikv.DefaultScanBatchSize)
+}
+
+// makeScanPair 按返回方式构造单条扫描结果
+func makeScanPair(key, value []byte, opts scanValueOptions) KeyValuePair {
+	pair := KeyValuePair{Key: string(key)}
+	switch {
+	case opts.KeysOnly:
+	case opts.summarized():
+		size := len(value)
+		pair.ValueSize = &size
+		if opts.Preview >= 0 {
+			preview := truncateUTF8(value, opts.Preview)
+			pair.Value = string(preview)
+			pair.Truncated = len(preview) < len(value)
+		}
+		if opts.Hash {
+			sum := sha256.Sum256(value)
+			pair.ValueHash = hex.EncodeToString(sum[:])
+		}
+	default:
+		pair.Value = string(value)
+	}
+	return pair
+}
+
+// truncateUTF8 截取前 n 字节，尽量不截断多字节字符
+func truncateUTF8(value []byte, n int) []byte {
+	if len(value) <= n {
+		return value
+	}
+	cut := n
+	for cut > 0 && cut > n-utf8.UTFMax && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	if !utf8.Valid(value[:cut]) {
+		cut = n
+	}
+	return value[:cut]
+}
+
+// defaultFilterScanBudget 带过滤条件扫描时默认最多扫描的键数
+const defaultFilterScanBudget = 100000
+
+// scanResult 扫描结果
+type scanResult struct {
+	Pairs []KeyValuePair
+	// Total 匹配的键数（扫描到当前页为止）
+	Total   int
+	Scanned int
+	// Exhausted 扫描预算耗尽，范围内可能还有匹配的键
+	Exhausted bool
+	// LastKey 最后扫描到的键，用于继续扫描
+	LastKey []byte
+	ReadTS  uint64
+}
+
+// pageCollector 按页收集扫描结果，过滤条件在服务端逐条判断，scanned 记录已经扫描过的键数
+type pageCollector struct {
+	offset    int
+	limit     int
+	budget    int
+	scanned   int
+	matched   int
+	exhausted bool
+	lastKey   []byte
+	// scannedBytes / returnedBytes 读取的和返回给调用方的 key、value 字节数
+	scannedBytes  int
+	returnedBytes int
+	values        scanValueOptions
+	filter        *filter.Filter
+	pairs         []KeyValuePair
+}
+
+func newPageCollector(req scanRequest) *pageCollector {
+	return &pageCollector{
+		offset: (req.Page - 1) * req.Limit,
+		limit:  req.Limit,
+		budget: req.ScanBudget,
+		values: req.Values,
+		filter: req.Filter,
+		pairs:  []KeyValuePair{},
+	}
+}
+
+func (p *pageCollector) visit(key, value []byte) bool {
+	p.scanned++
+	p.lastKey = key
+	p.scannedBytes += len(key) + len(value)
+
+	if p.filter == nil || p.filter.Match(key, value) {
+		p.matched++
+		if p.matched > p.offset {
+			pair := makeScanPair(key, value, p.values)
+			p.pairs = append(p.pairs, pair)
+			p.returnedBytes += len(pair.Key) + len(pair.Value)
+		}
+		if len(p.pairs) >= p.limit {
+			return false
+		}
+	}
+
+	if p.budget > 0 && p.scanned >= p.budget {
+		p.exhausted = true
+		return false
+	}
+	return true
+}
+
+// rawBatchSize 不带过滤条件时只需要扫描到当前页，带过滤条件时尽量使用大批次
+func (p *pageCollector) rawBatchSize() int {
+	if p.filter != nil {
+		return p.values.batchSize(rawkv.MaxRawKVScanLimit)
+	}
+	return p.values.batchSize(p.offset + p.limit)
+}
+
+func (p *pageCollector) result(readTS uint64) *scanResult {
+	return &scanResult{
+		Pairs:     p.pairs,
+		Total:     p.matched,
+		Scanned:   p.scanned,
+		Exhausted: p.exhausted,
+		LastKey:   p.lastKey,
+		ReadTS:    readTS,
+	}
+}
+
+// scanRawKVs 扫描RawKV中的键值对
+func scanRawKVs(ctx context.Context, req scanRequest) (*scanResult, error) {
+	// 直接获取全局RawKV客户端
+	client := rawClientFrom(ctx)
+	if client == nil {
+		logging.FromContext(ctx).Warn("RawKV client is nil")
+		return &scanResult{Pairs: []KeyValuePair{}}, nil
+	}
+
+	logger := logging.FromContext(ctx).With(zap.String("mode", "rawkv"))
+	logger.Debug("scan",
+		logging.Key("start", req.Range.Start),
+		logging.Key("end", req.Range.End),
+		zap.Bool("reverse", req.Reverse),
+		zap.Int("page", req.Page),
+		zap.Int("limit", req.Limit))
+
+	var options []rawkv.RawOption
+	if req.Values.KeysOnly {
+		options = append(options, rawkv.ScanKeyOnly())
+	}
+
+	collector := newPageCollector(req)
+	opCtx, op := tracing.StartOp(ctx, "rawkv", metrics.OpScan, append(tracing.Range(req.Range.Start, req.Range.End), attribute.Bool("tikv.reverse", req.Reverse))...)
+	err := tikv.ScanRawRange(opCtx, client, req.Range, req.Reverse, collector.rawBatchSize(), collector.visit, options...)
+	op.SetAttributes(tracing.KeyCount(collector.scanned))
+	op.End(err)
+	metrics.ObserveScan("rawkv", collector.scanned, collector.scannedBytes, collector.returnedBytes)
+	if err != nil {
+		logger.Warn("scan failed", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Debug("scan finished",
+		zap.Int("scanned", collector.scanned),
+		zap.Int("matched", collector.matched),
+		zap.Int("returned", len(collector.pairs)))
+	return collector.result(0), nil
+}
+
+// scanTxnKVs 扫描TxnKV中的键值对，ReadTS 为 0 时读取最新数据，否则读取该时间戳上的历史快照
+func scanTxnKVs(ctx context.Context, req scanRequest) (*scanResult, error) {
+	logger := logging.FromContext(ctx).With(zap.String("mode", "txn"))
+	logger.Debug("scan",
+		logging.Key("start", req.Range.Start),
+		logging.Key("end", req.Range.End),
+		zap.Bool("reverse", req.Reverse),
+		zap.Int("page", req.Page),
+		zap.Int("limit", req.Limit),
+		zap.Uint64("ts", req.ReadTS))
+
+	// 确保事务客户端已初始化
+	txnClient := txnClientFrom(ctx)
+	if txnClient == nil {
+		logger.Warn("TxnClient is nil")
+		return nil, fmt.Errorf("transaction client not initialized")
+	}
+
+	// 只读扫描直接使用一致性快照，不需要开启事务
+	snapshot, readTS, err := txnClient.SnapshotAt(ctx, req.ReadTS)
+	if err != nil {
+		logger.Warn("failed to get snapshot", zap.Error(err))
+		return nil, err
+	}
+	snapshot.SetKeyOnly(req.Values.KeysOnly)
+
+	collector := newPageCollector(req)
+	_, op := tracing.StartOp(ctx, "txn", metrics.OpScan, append(tracing.Range(req.Range.Start, req.Range.End),
+		attribute.Bool("tikv.reverse", req.Reverse), attribute.Int64("tikv.read_ts", int64(readTS)))...)
+	err = tikv.ScanSnapshotRange(snapshot, req.Range, req.Reverse, collector.visit)
+	op.SetAttributes(tracing.KeyCount(collector.scanned))
+	op.End(err)
+	metrics.ObserveScan("txn", collector.scanned, collector.scannedBytes, collector.returnedBytes)
+	if err != nil {
+		logger.Warn("scan failed", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Debug("scan finished",
+		zap.Int("scanned", collector.scanned),
+		zap.Int("matched", collector.matched),
+		zap.Int("returned", len(collector.pairs)),
+		zap.Uint64("read_ts", readTS))
+	return collector.result(readTS), nil
+}
+
+func main() {
+	flag.StringVar(&configPath, "config", "config.json", "path to the config file (.json, .yaml/.yml or .toml)")
+	printConfig := flag.Bool("print-config", false, "print the effective config (defaults, config file and environment overrides) and exit")
+	checkConfig := flag.Bool("check-config", false, "validate the config and exit with status 1 if it is invalid")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(configPath)
+	if *printConfig || *checkConfig {
+		os.Exit(runConfigCommand(cfg, err, *printConfig, *checkConfig))
+	}
+	if err != nil {
+		logging.L().Fatal("failed to load config", zap.Error(err))
+	}
+
+	// 先检查整个配置，再应用日志、事务提交选项、集群的 API 版本和 keyspace、客户端参数、TLS、探针和请求体上限
+	rc, err := prepareConfig(cfg)
+	if err != nil {
+		logging.L().Fatal("invalid config", zap.Error(err))
+	}
+	if err := applyConfig(rc); err != nil {
+		logging.L().Fatal("failed to apply config", zap.Error(err))
+	}
+	startupConfig = cfg
+	defer logging.Sync()
+
+	// 链路追踪
+	shutdownTracing, err := tracing.Setup(context.Background(), rc.tracing)
+	if err != nil {
+		logging.L().Fatal("invalid tracing config", zap.Error(err))
+	}
+
+	// 证书文件变化后重新连接；配置文件变化或收到 SIGHUP 时重新加载配置
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	watchSecurity(watchCtx, rc.security)
+	go config.WatchFile(watchCtx, configPath, config.WatchInterval, func() {
+		reloadConfig(watchCtx, "file changed")
+	})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-hup:
+				reloadConfig(watchCtx, "SIGHUP")
+			}
+		}
+	}()
+
+	// Prometheus 指标
+	metrics.Register()
+
+	// 统计信息缓存
+	kvStatsCache = tikv.NewStatsCache(time.Duration(cfg.TiKV.StatsCacheTTL)*time.Second, cfg.TiKV.StatsScanLimit)
+
+	// 设置 Gin 模式
+	gin.SetMode(gin.ReleaseMode)
+
+	// 创建路由
+	router := SetupRouter()
+
+	// 创建 HTTP 服务器
+	srv := &http.Server{
+		Addr:           cfg.Server.Addr,
+		Handler:        router,
+		ReadTimeout:    time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout:   time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:    time.Duration(cfg.Server.IdleTimeout) * time.Second,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
+	// prepareConfig 已经检查 tls_cert_path 和 tls_key_path 同时设置
+	serveTLS := cfg.Server.TLSCertPath != ""
+
+	// 启动服务器
+	go func() {
+		scheme := "http"
+		if serveTLS {
+			scheme = "https"
+		}
+		logging.L().Info("TiKV backend server listening",
+			zap.String("addr", srv.Addr),
+			zap.String("api", scheme+"://"+displayAddr(srv.Addr)+"/api/kv"),
+			zap.String("health", scheme+"://"+displayAddr(srv.Addr)+"/health"))
+
+		var err error
+		if serveTLS {
+			err = srv.ListenAndServeTLS(cfg.Server.TLSCertPath, cfg.Server.TLSKeyPath)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logging.L().Fatal("failed to start server", zap.Error(err))
+		}
+	}()
+
+	// 等待中断信号优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logging.L().Info("shutting down server")
 
-	// 优雅关闭
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 优雅关闭：停止接受新请求并等待进行中的请求和后台统计结束，最后关闭集群客户端
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		logging.L().Warn("server forced to shutdown", zap.Error(err))
+	}
+	if err := kvStatsCache.Shutdown(ctx); err != nil {
+		logging.L().Warn("background stats jobs cancelled", zap.Error(err))
+	}
+	stopWatch()
+	deadline, _ := ctx.Deadline()
+	CloseTiKVClient(time.Until(deadline))
+
+	// 导出尚未发送的 span，关闭客户端可能已经用完了等待时间，单独给导出留出时间
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logging.L().Warn("failed to flush traces", zap.Error(err))
 	}
 
-	log.Println("Server exited")
+	logging.L().Info("server exited")
+}
+
+// displayAddr 把只有端口的监听地址显示为 localhost
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
 }
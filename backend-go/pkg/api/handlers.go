@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"net/http"
 
+	"tikv-backend/pkg/logging"
 	"tikv-backend/pkg/models"
+	"tikv-backend/pkg/pd"
 	"tikv-backend/pkg/tikv"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // KVController TiKV 控制器
@@ -33,7 +36,6 @@ func (c *KVController) GetKV(ctx *gin.Context) {
 	}
 
 	requestCtx := context.Background()
-	var err error
 	var value string
 
 	if typeParam == "rawkv" {
@@ -144,7 +146,6 @@ func (c *KVController) ScanKVs(ctx *gin.Context) {
 	requestCtx := context.Background()
 	var pairs []models.KeyValuePair
 	var total int
-	var err error
 
 	if query.Type == "rawkv" {
 		rawKvClient := tikv.GetRawKvClient()
@@ -157,17 +158,11 @@ func (c *KVController) ScanKVs(ctx *gin.Context) {
 		}
 
 		// 计算扫描范围
-		startKey := []byte(query.Prefix)
-		var endKey []byte
-		if query.Prefix == "" {
-			endKey = []byte{0xFF, 0xFF, 0xFF, 0xFF} // 扫描所有数据
-		} else {
-			endKey = []byte(query.Prefix + "\xFF")
-		}
+		scanRange := tikv.PrefixRange([]byte(query.Prefix))
 
 		// 分页处理
 		offset := (query.Page - 1) * query.Limit
-		keys, values, err := rawKvClient.Scan(requestCtx, startKey, endKey, offset+query.Limit)
+		keys, values, err := rawKvClient.ScanRange(requestCtx, scanRange, false, offset+query.Limit)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, models.ApiResponse{
 				Success: false,
@@ -190,7 +185,7 @@ func (c *KVController) ScanKVs(ctx *gin.Context) {
 		}
 
 		// 获取总数（简化版本，实际应用中可能需要优化）
-		allKeys, _, err := rawKvClient.Scan(requestCtx, startKey, endKey, 10000) // 限制扫描数量
+		allKeys, _, err := rawKvClient.ScanRange(requestCtx, scanRange, false, 10000) // 限制扫描数量
 		if err == nil {
 			total = len(allKeys)
 		} else {
@@ -894,28 +889,51 @@ func (c *KVController) AtomicTransaction(ctx *gin.Context) {
 	})
 }
 
-// GetStats 获取统计信息
+// statsCache 命名空间内的统计信息缓存
+var statsCache = tikv.NewStatsCache(tikv.DefaultStatsCacheTTL, tikv.DefaultStatsScanLimit)
+
+// GetStats 获取统计信息，统计结果在后台计算并缓存，prefix 为命名空间内的前缀
 func (c *KVController) GetStats(ctx *gin.Context) {
-	// 简化版本，实际应用中可以获取更详细的统计信息
-	rawKVConnected := tikv.IsConnected()
-	txnConnected := tikv.IsConnected()
+	prefix := ctx.Query("prefix")
+	connected := tikv.IsConnected()
 
 	stats := models.TiKVStats{
 		RawKV: models.RawKVStats{
-			SampleKeys: 0, // 可以通过扫描来计算
-			Connected:  rawKVConnected,
+			Connected: connected,
 		},
 		Txn: models.TxnStats{
-			SampleKeys: 0, // 可以通过扫描来计算
-			Connected:  txnConnected,
+			Connected: connected,
 		},
 		Overall: models.OverallStats{
-			Connected:  tikv.IsConnected(),
-			APIVersion: "v2",
-			Mode:       "production (connected to real TiKV cluster)",
+			Connected:  connected,
+			APIVersion: tikv.GetClusterOptions().APIVersionName(),
+			Mode:       "disconnected",
 		},
 	}
 
+	if connected {
+		r := tikv.PrefixRange([]byte(prefix))
+		rawKvClient := tikv.GetRawKvClient()
+		txnKvClient := tikv.GetTxnKvClient()
+
+		rawStats, rawRefreshing := statsCache.Get("rawkv", prefix, func(ctx context.Context, fn tikv.ScanFunc) error {
+			return rawKvClient.Walk(ctx, r, fn)
+		})
+		txnStats, txnRefreshing := statsCache.Get("txn", prefix, func(ctx context.Context, fn tikv.ScanFunc) error {
+			return txnKvClient.Walk(ctx, r, fn)
+		})
+
+		stats.RawKV.Details, stats.RawKV.Refreshing = rawStats, rawRefreshing
+		stats.Txn.Details, stats.Txn.Refreshing = txnStats, txnRefreshing
+		if rawStats != nil {
+			stats.RawKV.SampleKeys = int(rawStats.KeyCount)
+		}
+		if txnStats != nil {
+			stats.Txn.SampleKeys = int(txnStats.KeyCount)
+		}
+		stats.Overall.Mode = "connected"
+	}
+
 	ctx.JSON(http.StatusOK, models.ApiResponse{
 		Success: true,
 		Message: "Stats retrieved successfully",
@@ -923,24 +941,33 @@ func (c *KVController) GetStats(ctx *gin.Context) {
 	})
 }
 
-// GetClusterStatus 获取集群状态
+// GetClusterStatus 获取集群状态，从 PD 查询集群拓扑并推导健康状态
 func (c *KVController) GetClusterStatus(ctx *gin.Context) {
-	endpoints := []string{
-		"172.16.0.10:2379",
-		"172.16.0.20:2379",
-		"172.16.0.30:2379",
-	}
+	endpoints := tikv.GetPDEndpoints()
 
 	status := models.ClusterStatus{
 		Connected:  tikv.IsConnected(),
-		Mode:       "production",
+		Mode:       "disconnected",
 		Endpoints:  endpoints,
-		APIVersion: "v2",
-		ClusterInfo: &models.ClusterInfo{
-			RegionID: "unknown",
-			Leader:   "unknown",
-			Peers:    0,
-		},
+		APIVersion: tikv.GetClusterOptions().APIVersionName(),
+	}
+	if status.Connected {
+		status.Mode = "connected"
+	}
+
+	topology, err := pd.NewClient(endpoints).Topology(ctx.Request.Context())
+	if err != nil {
+		status.Health = pd.HealthUnreachable
+		status.Error = err.Error()
+	} else {
+		status.Health = topology.Health
+		status.Topology = topology
+		status.ClusterInfo = &models.ClusterInfo{
+			ClusterID: topology.ClusterID,
+			Leader:    topology.Leader,
+			Peers:     len(topology.Members),
+			Stores:    len(topology.Stores) - topology.StoreStates[pd.StoreStateTombstone],
+		}
 	}
 
 	ctx.JSON(http.StatusOK, models.ApiResponse{
@@ -952,7 +979,7 @@ func (c *KVController) GetClusterStatus(ctx *gin.Context) {
 
 // DeleteAllKVs 删除所有键值对
 func (c *KVController) DeleteAllKVs(ctx *gin.Context) {
-	fmt.Printf("DEBUG: DeleteAllKVs called with type: %s\n", ctx.DefaultQuery("type", "rawkv"))
+	logging.Ctx(ctx).Debug("delete all keys", zap.String("mode", ctx.DefaultQuery("type", "rawkv")))
 	typeParam := ctx.DefaultQuery("type", "rawkv")
 
 	requestCtx := context.Background()
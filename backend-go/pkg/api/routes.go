@@ -1,10 +1,12 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 
+	"tikv-backend/pkg/logging"
+
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // SetupRouter 设置路由
@@ -12,8 +14,8 @@ func SetupRouter() *gin.Engine {
 	router := gin.New()
 
 	// 中间件
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(logging.Middleware())
+	router.Use(logging.Recovery())
 
 	// CORS 中间件
 	router.Use(func(c *gin.Context) {
@@ -66,9 +68,8 @@ func SetupRouter() *gin.Engine {
 	}
 
 	// 打印所有注册的路由
-	fmt.Println("Registered routes:")
 	for _, route := range router.Routes() {
-		fmt.Printf("%s %s\n", route.Method, route.Path)
+		logging.L().Debug("registered route", zap.String("method", route.Method), zap.String("path", route.Path))
 	}
 
 	return router
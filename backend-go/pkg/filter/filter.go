@@ -0,0 +1,147 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Options 扫描结果的过滤条件，所有条件同时满足才算匹配，空值表示不限制
+type Options struct {
+	KeyGlob       string
+	KeyRegex      string
+	ValueContains string
+	ValueRegex    string
+	// JSONPath JSON value 的谓词，例如 $.status == "pending" && $.amount > 100
+	JSONPath string
+	// MinValueSize / MaxValueSize value 字节数范围，-1 表示不限制
+	MinValueSize int
+	MaxValueSize int
+}
+
+// Filter 编译后的过滤条件
+type Filter struct {
+	keyPatterns   []*regexp.Regexp
+	valueContains []byte
+	valueRegex    *regexp.Regexp
+	predicates    []predicate
+	minValueSize  int
+	maxValueSize  int
+}
+
+// New 编译过滤条件，没有任何条件时返回 nil
+func New(opts Options) (*Filter, error) {
+	f := &Filter{
+		minValueSize: opts.MinValueSize,
+		maxValueSize: opts.MaxValueSize,
+	}
+
+	if opts.KeyGlob != "" {
+		re, err := regexp.Compile(globToRegex(opts.KeyGlob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key glob %q: %v", opts.KeyGlob, err)
+		}
+		f.keyPatterns = append(f.keyPatterns, re)
+	}
+	if opts.KeyRegex != "" {
+		re, err := regexp.Compile(opts.KeyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key regex %q: %v", opts.KeyRegex, err)
+		}
+		f.keyPatterns = append(f.keyPatterns, re)
+	}
+	if opts.ValueContains != "" {
+		f.valueContains = []byte(opts.ValueContains)
+	}
+	if opts.ValueRegex != "" {
+		re, err := regexp.Compile(opts.ValueRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value regex %q: %v", opts.ValueRegex, err)
+		}
+		f.valueRegex = re
+	}
+	if opts.JSONPath != "" {
+		predicates, err := parsePredicates(opts.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON path predicate %q: %v", opts.JSONPath, err)
+		}
+		f.predicates = predicates
+	}
+	if f.minValueSize >= 0 && f.maxValueSize >= 0 && f.minValueSize > f.maxValueSize {
+		return nil, fmt.Errorf("min value size %d is greater than max value size %d", f.minValueSize, f.maxValueSize)
+	}
+
+	if f.isEmpty() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func (f *Filter) isEmpty() bool {
+	return len(f.keyPatterns) == 0 && f.valueContains == nil && f.valueRegex == nil &&
+		len(f.predicates) == 0 && f.minValueSize < 0 && f.maxValueSize < 0
+}
+
+// NeedsValue 是否有条件需要读取 value，这种情况下不能使用 keysOnly 扫描
+func (f *Filter) NeedsValue() bool {
+	return f.valueContains != nil || f.valueRegex != nil || len(f.predicates) > 0 ||
+		f.minValueSize >= 0 || f.maxValueSize >= 0
+}
+
+// Match 判断键值对是否满足所有条件，先判断代价较小的条件
+func (f *Filter) Match(key, value []byte) bool {
+	for _, re := range f.keyPatterns {
+		if !re.Match(key) {
+			return false
+		}
+	}
+	if f.minValueSize >= 0 && len(value) < f.minValueSize {
+		return false
+	}
+	if f.maxValueSize >= 0 && len(value) > f.maxValueSize {
+		return false
+	}
+	if f.valueContains != nil && !bytes.Contains(value, f.valueContains) {
+		return false
+	}
+	if f.valueRegex != nil && !f.valueRegex.Match(value) {
+		return false
+	}
+	if len(f.predicates) > 0 && !matchPredicates(f.predicates, value) {
+		return false
+	}
+	return true
+}
+
+// globToRegex 把 glob 转换为正则，* 和 ? 可以匹配包括 / 在内的任意字符
+func globToRegex(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	inClass := false
+	classStart := false
+	for _, r := range glob {
+		switch {
+		case inClass:
+			if classStart && r == '!' {
+				r = '^'
+			} else if r == ']' {
+				inClass = false
+			}
+			classStart = false
+			sb.WriteRune(r)
+		case r == '*':
+			sb.WriteString("(?s:.*)")
+		case r == '?':
+			sb.WriteString("(?s:.)")
+		case r == '[':
+			inClass = true
+			classStart = true
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package filter
+
+import "testing"
+
+// TestFilterMatch 测试 key 模式、value 谓词和 value 大小条件
+func TestFilterMatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  Options
+		key   string
+		value string
+		want  bool
+	}{
+		{"glob匹配", Options{KeyGlob: "order_*_2024", MinValueSize: -1, MaxValueSize: -1}, "order_42_2024", "", true},
+		{"glob不匹配", Options{KeyGlob: "order_?", MinValueSize: -1, MaxValueSize: -1}, "order_42", "", false},
+		{"glob字符集取反", Options{KeyGlob: "user_[!0-9]*", MinValueSize: -1, MaxValueSize: -1}, "user_abc", "", true},
+		{"key正则", Options{KeyRegex: `^user_\d+$`, MinValueSize: -1, MaxValueSize: -1}, "user_7", "", true},
+		{"value子串", Options{ValueContains: "error", MinValueSize: -1, MaxValueSize: -1}, "log_1", "fatal error", true},
+		{"value正则", Options{ValueRegex: `^\{`, MinValueSize: -1, MaxValueSize: -1}, "k", "plain", false},
+		{"JSON等于", Options{JSONPath: `$.status == "pending"`, MinValueSize: -1, MaxValueSize: -1}, "order_1", `{"status":"pending"}`, true},
+		{"JSON不等于", Options{JSONPath: `$.status == "pending"`, MinValueSize: -1, MaxValueSize: -1}, "order_2", `{"status":"paid"}`, false},
+		{"JSON数字比较", Options{JSONPath: `$.items[0].qty >= 2 && $.status != "paid"`, MinValueSize: -1, MaxValueSize: -1}, "order_3", `{"status":"pending","items":[{"qty":3}]}`, true},
+		{"JSON路径存在", Options{JSONPath: `$.meta["trace id"]`, MinValueSize: -1, MaxValueSize: -1}, "k", `{"meta":{"trace id":"x"}}`, true},
+		{"非法JSON", Options{JSONPath: `$.status == "pending"`, MinValueSize: -1, MaxValueSize: -1}, "k", `not json`, false},
+		{"value过小", Options{MinValueSize: 4, MaxValueSize: -1}, "k", "abc", false},
+		{"value过大", Options{MinValueSize: -1, MaxValueSize: 2}, "k", "abc", false},
+		{"value大小范围内", Options{MinValueSize: 1, MaxValueSize: 3}, "k", "abc", true},
+	}
+
+	for _, tc := range cases {
+		f, err := New(tc.opts)
+		if err != nil {
+			t.Fatalf("%s: 编译过滤条件失败: %v", tc.name, err)
+		}
+		if got := f.Match([]byte(tc.key), []byte(tc.value)); got != tc.want {
+			t.Errorf("%s: Match(%q, %q) = %v, 期望 %v", tc.name, tc.key, tc.value, got, tc.want)
+		}
+	}
+}
+
+// TestNew 测试空条件、只有 key 条件和非法表达式
+func TestNew(t *testing.T) {
+	f, err := New(Options{MinValueSize: -1, MaxValueSize: -1})
+	if err != nil || f != nil {
+		t.Fatalf("没有条件时应返回 nil, got %v, %v", f, err)
+	}
+
+	f, err = New(Options{KeyGlob: "a*", MinValueSize: -1, MaxValueSize: -1})
+	if err != nil {
+		t.Fatalf("编译过滤条件失败: %v", err)
+	}
+	if f.NeedsValue() {
+		t.Error("只有 key 条件时不应需要 value")
+	}
+
+	for _, opts := range []Options{
+		{KeyRegex: "(", MinValueSize: -1, MaxValueSize: -1},
+		{JSONPath: "status == 1", MinValueSize: -1, MaxValueSize: -1},
+		{JSONPath: "$.status ==", MinValueSize: -1, MaxValueSize: -1},
+		{MinValueSize: 5, MaxValueSize: 1},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) 应返回错误", opts)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// predicate JSON 路径谓词，例如 $.order.status == "pending"；op 为空表示只要求路径存在
+type predicate struct {
+	path    []pathSegment
+	op      string
+	operand interface{}
+}
+
+// pathSegment 路径中的一段，field 和 index 二选一
+type pathSegment struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+var comparisonOps = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// parsePredicates 解析用 && 连接的谓词
+func parsePredicates(expr string) ([]predicate, error) {
+	var predicates []predicate
+	rest := strings.TrimSpace(expr)
+	for {
+		p, remaining, err := parsePredicate(rest)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+
+		remaining = strings.TrimSpace(remaining)
+		if remaining == "" {
+			return predicates, nil
+		}
+		if !strings.HasPrefix(remaining, "&&") {
+			return nil, fmt.Errorf("expected && near %q", remaining)
+		}
+		rest = strings.TrimSpace(remaining[2:])
+	}
+}
+
+func parsePredicate(expr string) (predicate, string, error) {
+	var p predicate
+	path, rest, err := parsePath(expr)
+	if err != nil {
+		return p, "", err
+	}
+	p.path = path
+
+	rest = strings.TrimSpace(rest)
+	for _, op := range comparisonOps {
+		if strings.HasPrefix(rest, op) {
+			p.op = op
+			rest = strings.TrimSpace(rest[len(op):])
+			break
+		}
+	}
+	if p.op == "" {
+		return p, rest, nil
+	}
+
+	// 操作数是一个 JSON 字面量：字符串、数字、true/false/null
+	dec := json.NewDecoder(strings.NewReader(rest))
+	if err := dec.Decode(&p.operand); err != nil {
+		return p, "", fmt.Errorf("invalid operand near %q: %v", rest, err)
+	}
+	if (p.op != "==" && p.op != "!=") && !isOrdered(p.operand) {
+		return p, "", fmt.Errorf("operator %s requires a number or string operand", p.op)
+	}
+	return p, rest[dec.InputOffset():], nil
+}
+
+// parsePath 解析 $.a.b[0]["c d"] 形式的路径
+func parsePath(expr string) ([]pathSegment, string, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, "", fmt.Errorf("path must start with $")
+	}
+	var segments []pathSegment
+	i := 1
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			j := i + 1
+			for j < len(expr) && isFieldChar(expr[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, "", fmt.Errorf("empty field name at offset %d", i)
+			}
+			segments = append(segments, pathSegment{field: expr[i+1 : j]})
+			i = j
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unclosed [ at offset %d", i)
+			}
+			inner := strings.TrimSpace(expr[i+1 : i+end])
+			if unquoted, err := strconv.Unquote(inner); err == nil {
+				segments = append(segments, pathSegment{field: unquoted})
+			} else if idx, err := strconv.Atoi(inner); err == nil && idx >= 0 {
+				segments = append(segments, pathSegment{index: idx, isIndex: true})
+			} else {
+				return nil, "", fmt.Errorf("invalid subscript [%s]", inner)
+			}
+			i += end + 1
+		default:
+			return segments, expr[i:], nil
+		}
+	}
+	return segments, "", nil
+}
+
+func isFieldChar(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isOrdered(v interface{}) bool {
+	switch v.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+// matchPredicates value 不是合法 JSON 时视为不匹配
+func matchPredicates(predicates []predicate, value []byte) bool {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(value))
+	if err := dec.Decode(&doc); err != nil {
+		return false
+	}
+	for _, p := range predicates {
+		if !p.match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p predicate) match(doc interface{}) bool {
+	actual, ok := resolve(doc, p.path)
+	if !ok {
+		// 路径不存在时只有 != 成立
+		return p.op == "!="
+	}
+
+	switch p.op {
+	case "":
+		return true
+	case "==":
+		return reflect.DeepEqual(actual, p.operand)
+	case "!=":
+		return !reflect.DeepEqual(actual, p.operand)
+	}
+
+	cmp, ok := compare(actual, p.operand)
+	if !ok {
+		return false
+	}
+	switch p.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func resolve(doc interface{}, path []pathSegment) (interface{}, bool) {
+	current := doc
+	for _, seg := range path {
+		if seg.isIndex {
+			arr, ok := current.([]interface{})
+			if !ok || seg.index >= len(arr) {
+				return nil, false
+			}
+			current = arr[seg.index]
+			continue
+		}
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[seg.field]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// compare 只比较同类型的数字或字符串
+func compare(a, b interface{}) (int, bool) {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	}
+	return 0, false
+}
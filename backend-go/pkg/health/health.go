@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 检查结果状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DefaultTimeout 单项检查的默认超时时间
+const DefaultTimeout = 2 * time.Second
+
+// Check 一项检查，Run 返回错误表示检查失败
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult 单项检查的结果
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report 一次探测的结果，所有检查都通过时 Status 为 ok
+type Report struct {
+	Status    string        `json:"status"`
+	Checks    []CheckResult `json:"checks"`
+	LatencyMs float64       `json:"latencyMs"`
+}
+
+// HTTPStatus 返回探测结果对应的 HTTP 状态码，失败时为 503，便于 Kubernetes 和负载均衡器判断
+func (r *Report) HTTPStatus() int {
+	if r.Status == StatusOK {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+// Run 并发执行所有检查，每项检查最多运行 timeout，结果按传入的顺序排列
+func Run(ctx context.Context, timeout time.Duration, checks []Check) *Report {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	start := time.Now()
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, timeout, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: results, LatencyMs: millis(time.Since(start))}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return report
+}
+
+// runCheck 执行单项检查，超时后不再等待检查返回，同时把 panic 记为失败
+func runCheck(ctx context.Context, timeout time.Duration, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := CheckResult{Name: check.Name, Status: StatusOK, LatencyMs: millis(time.Since(start))}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRun 测试检查结果的汇总、超时和 panic 处理
+func TestRun(t *testing.T) {
+	ok := Check{Name: "ok", Run: func(ctx context.Context) error { return nil }}
+
+	report := Run(context.Background(), time.Second, []Check{ok, ok})
+	if report.Status != StatusOK || report.HTTPStatus() != http.StatusOK || len(report.Checks) != 2 {
+		t.Fatalf("所有检查通过时应返回 ok: %+v", report)
+	}
+
+	start := time.Now()
+	report = Run(context.Background(), 50*time.Millisecond, []Check{
+		ok,
+		{Name: "error", Run: func(ctx context.Context) error { return errors.New("region unavailable") }},
+		{Name: "slow", Run: func(ctx context.Context) error { time.Sleep(time.Second); return nil }},
+		{Name: "panic", Run: func(ctx context.Context) error { panic("boom") }},
+	})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("检查应在超时后返回，实际耗时 %v", elapsed)
+	}
+	if report.Status != StatusFail || report.HTTPStatus() != http.StatusServiceUnavailable {
+		t.Errorf("有检查失败时应返回 fail: %+v", report)
+	}
+
+	want := []struct {
+		name, status, err string
+	}{
+		{"ok", StatusOK, ""},
+		{"error", StatusFail, "region unavailable"},
+		{"slow", StatusFail, "timed out after 50ms"},
+		{"panic", StatusFail, "panic: boom"},
+	}
+	for i, w := range want {
+		got := report.Checks[i]
+		if got.Name != w.name || got.Status != w.status || got.Error != w.err {
+			t.Errorf("检查 %d = %+v, 期望 %+v", i, got, w)
+		}
+	}
+	if report.Checks[2].LatencyMs < 50 {
+		t.Errorf("超时检查的耗时 = %vms, 期望不小于 50ms", report.Checks[2].LatencyMs)
+	}
+}
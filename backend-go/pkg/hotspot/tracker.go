@@ -0,0 +1,226 @@
+package hotspot
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWindow 统计访问量的滑动窗口长度
+	DefaultWindow = 5 * time.Minute
+	// DefaultBuckets 滑动窗口划分的桶数
+	DefaultBuckets = 30
+	// DefaultMaxKeys 每个桶最多记录的不同 key 数量和不同前缀数量，key 超过上限后只累计前缀
+	DefaultMaxKeys = 10000
+)
+
+// 访问类型
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+)
+
+// 排序方式
+const (
+	SortByQPS   = "qps"
+	SortByBytes = "bytes"
+)
+
+// prefixSeparators 用于划分 key 前缀的分隔符，前缀包含第一个分隔符
+const prefixSeparators = ":_/|."
+
+// Entry 一个 key 或前缀在窗口内的访问量
+type Entry struct {
+	Mode        string  `json:"mode"`
+	Key         string  `json:"key"`
+	Count       uint64  `json:"count"`
+	Bytes       uint64  `json:"bytes"`
+	QPS         float64 `json:"qps"`
+	BytesPerSec float64 `json:"bytesPerSec"`
+}
+
+type counter struct {
+	count uint64
+	bytes uint64
+}
+
+type entryKey struct {
+	mode string
+	key  string
+}
+
+// bucket 滑动窗口中的一个时间段
+type bucket struct {
+	start    time.Time
+	keys     map[string]map[entryKey]*counter
+	prefixes map[string]map[entryKey]*counter
+}
+
+func (b *bucket) reset(start time.Time) {
+	b.start = start
+	b.keys = map[string]map[entryKey]*counter{OpRead: {}, OpWrite: {}}
+	b.prefixes = map[string]map[entryKey]*counter{OpRead: {}, OpWrite: {}}
+}
+
+// Tracker 记录后端处理的请求中每个 key 和前缀的访问次数和字节数，按滑动窗口统计
+type Tracker struct {
+	mu          sync.Mutex
+	bucketWidth time.Duration
+	buckets     []bucket
+	maxKeys     int
+	startedAt   time.Time
+	now         func() time.Time
+}
+
+// NewTracker 创建访问统计，window 为滑动窗口长度，buckets 为窗口划分的桶数
+func NewTracker(window time.Duration, buckets int, maxKeys int) *Tracker {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if buckets <= 0 {
+		buckets = DefaultBuckets
+	}
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	t := &Tracker{
+		bucketWidth: window / time.Duration(buckets),
+		buckets:     make([]bucket, buckets),
+		maxKeys:     maxKeys,
+		now:         time.Now,
+	}
+	t.startedAt = t.now()
+	return t
+}
+
+// Window 返回滑动窗口长度
+func (t *Tracker) Window() time.Duration {
+	return t.bucketWidth * time.Duration(len(t.buckets))
+}
+
+// PrefixOf 返回 key 的前缀，取到第一个分隔符为止（包含分隔符），没有分隔符时返回整个 key
+func PrefixOf(key string) string {
+	if i := strings.IndexAny(key, prefixSeparators); i >= 0 {
+		return key[:i+1]
+	}
+	return key
+}
+
+// Record 记录一次访问，size 为读写的字节数
+func (t *Tracker) Record(mode, op, key string, size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	start := now.Truncate(t.bucketWidth)
+	b := &t.buckets[int(start.UnixNano()/int64(t.bucketWidth))%len(t.buckets)]
+	if !b.start.Equal(start) {
+		b.reset(start)
+	}
+
+	keys := b.keys[op]
+	if keys == nil {
+		return
+	}
+	k := entryKey{mode: mode, key: key}
+	if c := keys[k]; c != nil {
+		c.count++
+		c.bytes += uint64(size)
+	} else if len(keys) < t.maxKeys {
+		keys[k] = &counter{count: 1, bytes: uint64(size)}
+	}
+
+	prefixes := b.prefixes[op]
+	p := entryKey{mode: mode, key: PrefixOf(key)}
+	if c := prefixes[p]; c != nil {
+		c.count++
+		c.bytes += uint64(size)
+	} else if len(prefixes) < t.maxKeys {
+		prefixes[p] = &counter{count: 1, bytes: uint64(size)}
+	}
+}
+
+// Top 返回窗口内访问量最高的 n 个 key 和 n 个前缀
+func (t *Tracker) Top(op, sortBy string, n int) (keys []Entry, prefixes []Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	windowStart := now.Truncate(t.bucketWidth).Add(-t.Window() + t.bucketWidth)
+
+	keyTotals := make(map[entryKey]*counter)
+	prefixTotals := make(map[entryKey]*counter)
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		if b.start.IsZero() || b.start.Before(windowStart) {
+			continue
+		}
+		merge(keyTotals, b.keys[op])
+		merge(prefixTotals, b.prefixes[op])
+	}
+
+	// 刚启动时窗口还没有填满，按实际经过的时间计算速率
+	seconds := t.Window().Seconds()
+	if elapsed := now.Sub(t.startedAt).Seconds(); elapsed < seconds {
+		seconds = elapsed
+	}
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return topEntries(keyTotals, sortBy, n, seconds), topEntries(prefixTotals, sortBy, n, seconds)
+}
+
+// Reset 清空所有统计，切换集群后调用
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buckets = make([]bucket, len(t.buckets))
+	t.startedAt = t.now()
+}
+
+func merge(dst map[entryKey]*counter, src map[entryKey]*counter) {
+	for k, c := range src {
+		if total := dst[k]; total != nil {
+			total.count += c.count
+			total.bytes += c.bytes
+		} else {
+			dst[k] = &counter{count: c.count, bytes: c.bytes}
+		}
+	}
+}
+
+func topEntries(totals map[entryKey]*counter, sortBy string, n int, seconds float64) []Entry {
+	entries := make([]Entry, 0, len(totals))
+	for k, c := range totals {
+		entries = append(entries, Entry{
+			Mode:        k.mode,
+			Key:         k.key,
+			Count:       c.count,
+			Bytes:       c.bytes,
+			QPS:         float64(c.count) / seconds,
+			BytesPerSec: float64(c.bytes) / seconds,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if sortBy == SortByBytes && a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Key < b.Key
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
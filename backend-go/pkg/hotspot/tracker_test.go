@@ -0,0 +1,79 @@
+package hotspot
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTrackerTop 测试 key 和前缀的排序以及滑动窗口的过期
+func TestTrackerTop(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewTracker(time.Minute, 6, 0)
+	tracker.now = func() time.Time { return now }
+	tracker.startedAt = now.Add(-time.Hour)
+
+	for i := 0; i < 30; i++ {
+		tracker.Record("rawkv", OpRead, "user:1", 10)
+	}
+	for i := 0; i < 20; i++ {
+		tracker.Record("rawkv", OpRead, "user:2", 100)
+	}
+	tracker.Record("txn", OpRead, "order_1", 5000)
+	tracker.Record("rawkv", OpWrite, "user:1", 10)
+
+	keys, prefixes := tracker.Top(OpRead, SortByQPS, 2)
+	if len(keys) != 2 || keys[0].Key != "user:1" || keys[1].Key != "user:2" {
+		t.Fatalf("按 QPS 排序错误: %+v", keys)
+	}
+	if keys[0].Count != 30 || keys[0].QPS != 0.5 {
+		t.Errorf("QPS 计算错误: %+v", keys[0])
+	}
+	if prefixes[0].Key != "user:" || prefixes[0].Count != 50 || prefixes[0].Bytes != 2300 {
+		t.Errorf("前缀统计错误: %+v", prefixes)
+	}
+
+	keys, _ = tracker.Top(OpRead, SortByBytes, 1)
+	if keys[0].Key != "order_1" || keys[0].Mode != "txn" {
+		t.Errorf("按字节排序错误: %+v", keys)
+	}
+
+	// 超出窗口的访问不再统计
+	now = now.Add(2 * time.Minute)
+	tracker.Record("rawkv", OpRead, "user:3", 1)
+	keys, _ = tracker.Top(OpRead, SortByQPS, 10)
+	if len(keys) != 1 || keys[0].Key != "user:3" {
+		t.Errorf("窗口外的访问应被丢弃: %+v", keys)
+	}
+}
+
+// TestTrackerMaxKeys 测试 key 数量超过上限时仍然累计前缀
+func TestTrackerMaxKeys(t *testing.T) {
+	tracker := NewTracker(time.Minute, 1, 2)
+	for _, key := range []string{"a:1", "a:2", "a:3"} {
+		tracker.Record("rawkv", OpWrite, key, 1)
+	}
+
+	keys, prefixes := tracker.Top(OpWrite, SortByQPS, 10)
+	if len(keys) != 2 {
+		t.Errorf("key 数量应受上限限制: %+v", keys)
+	}
+	if len(prefixes) != 1 || prefixes[0].Count != 3 {
+		t.Errorf("前缀应包含所有访问: %+v", prefixes)
+	}
+}
+
+// TestTrackerMaxPrefixes 测试前缀数量也受上限限制，已记录的前缀继续累计
+func TestTrackerMaxPrefixes(t *testing.T) {
+	tracker := NewTracker(time.Minute, 1, 2)
+	for _, key := range []string{"a:1", "b:1", "c:1", "a:2", "d"} {
+		tracker.Record("rawkv", OpRead, key, 1)
+	}
+
+	_, prefixes := tracker.Top(OpRead, SortByQPS, 10)
+	if len(prefixes) != 2 {
+		t.Fatalf("前缀数量应受上限限制: %+v", prefixes)
+	}
+	if prefixes[0].Key != "a:" || prefixes[0].Count != 2 {
+		t.Errorf("已记录的前缀应继续累计: %+v", prefixes)
+	}
+}
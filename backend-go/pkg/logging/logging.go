@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	pinglog "github.com/pingcap/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志格式
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Options 日志配置
+type Options struct {
+	// Level 初始日志级别，运行时可以通过 LevelHandler 修改
+	Level string
+	// Format 为 json 或 console
+	Format string
+	// RedactKeyPrefixes 以这些前缀开头的 key 不会出现在日志中
+	RedactKeyPrefixes []string
+}
+
+// Validate 检查日志级别和格式
+func (o Options) Validate() error {
+	if o.Level != "" {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(o.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %v", o.Level, err)
+		}
+	}
+	switch o.Format {
+	case "", FormatJSON, FormatConsole:
+	default:
+		return fmt.Errorf("invalid log format %q: must be %s or %s", o.Format, FormatJSON, FormatConsole)
+	}
+	return nil
+}
+
+var (
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	mu     sync.RWMutex
+	logger = newLogger(FormatJSON)
+)
+
+func newLogger(format string) *zap.Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	if format == FormatConsole {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+	core := zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), level)
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
+}
+
+// Setup 按配置初始化全局日志，client-go 的日志也会使用同一个 logger 和级别
+func Setup(opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.Level != "" {
+		_ = level.UnmarshalText([]byte(opts.Level))
+	}
+	SetRedactedKeyPrefixes(opts.RedactKeyPrefixes)
+
+	l := newLogger(opts.Format)
+	mu.Lock()
+	logger = l
+	mu.Unlock()
+
+	zap.ReplaceGlobals(l)
+	pinglog.ReplaceGlobals(l, &pinglog.ZapProperties{Core: l.Core(), Syncer: zapcore.Lock(os.Stderr), Level: level})
+	return nil
+}
+
+// L 返回全局 logger
+func L() *zap.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
+}
+
+// Sync 刷新缓冲的日志，退出前调用
+func Sync() {
+	_ = L().Sync()
+}
+
+// Level 返回当前的日志级别
+func Level() string {
+	return level.Level().String()
+}
+
+// LevelHandler 返回查看和修改日志级别的 HTTP 处理函数。
+// GET 返回 {"level":"info"}，PUT 接收同样格式的 JSON 或 level 表单参数。
+func LevelHandler() http.Handler {
+	return level
+}
+
+type requestIDKey struct{}
+
+// WithRequestID 把请求 ID 放入 context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 返回 context 中的请求 ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext 返回带有请求 ID 的 logger
+func FromContext(ctx context.Context) *zap.Logger {
+	if id := RequestID(ctx); id != "" {
+		return L().With(zap.String("request_id", id))
+	}
+	return L()
+}
+
+var (
+	redactMu       sync.RWMutex
+	redactPrefixes []string
+)
+
+// SetRedactedKeyPrefixes 设置需要脱敏的 key 前缀
+func SetRedactedKeyPrefixes(prefixes []string) {
+	cleaned := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			cleaned = append(cleaned, p)
+		}
+	}
+
+	redactMu.Lock()
+	redactPrefixes = cleaned
+	redactMu.Unlock()
+}
+
+// maxLoggedKeyLen 日志中 key 的最大长度，超过的部分会被截断
+const maxLoggedKeyLen = 128
+
+// RedactKey 返回可以写入日志的 key：匹配脱敏前缀时只保留长度，过长时截断
+func RedactKey(key []byte) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	for _, p := range redactPrefixes {
+		if strings.HasPrefix(string(key), p) {
+			return fmt.Sprintf("<redacted len=%d>", len(key))
+		}
+	}
+	if len(key) > maxLoggedKeyLen {
+		return fmt.Sprintf("%q...(len=%d)", key[:maxLoggedKeyLen], len(key))
+	}
+	return fmt.Sprintf("%q", key)
+}
+
+// Key 返回经过脱敏的 key 字段。日志中只能出现 key，value 一律不记录。
+func Key(name string, key []byte) zap.Field {
+	return zap.String(name, RedactKey(key))
+}
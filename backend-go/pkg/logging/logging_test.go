@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestRedactKey 测试敏感前缀的 key 只保留长度，过长的 key 被截断
+func TestRedactKey(t *testing.T) {
+	SetRedactedKeyPrefixes([]string{"secret:", " ", "token_"})
+	defer SetRedactedKeyPrefixes(nil)
+
+	long := make([]byte, 200)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	cases := []struct {
+		key  []byte
+		want string
+	}{
+		{key: []byte("user:1"), want: `"user:1"`},
+		{key: []byte("secret:password"), want: "<redacted len=15>"},
+		{key: []byte("token_abc"), want: "<redacted len=9>"},
+		{key: []byte("a\x00b"), want: `"a\x00b"`},
+		{key: long, want: `"` + string(long[:maxLoggedKeyLen]) + `"...(len=200)`},
+	}
+	for _, tc := range cases {
+		if got := RedactKey(tc.key); got != tc.want {
+			t.Errorf("RedactKey(%q) = %s, 期望 %s", tc.key, got, tc.want)
+		}
+	}
+}
+
+// TestMiddleware 测试请求 ID 的传递和生成，以及访问日志不包含路径中的 key
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	mu.Lock()
+	previous := logger
+	logger = zap.New(core)
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		logger = previous
+		mu.Unlock()
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/kv/:key", func(c *gin.Context) {
+		Ctx(c).Info("handler")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/kv/secret:1?type=rawkv", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "req-42" {
+		t.Errorf("响应头中的请求 ID = %q, 期望 req-42", got)
+	}
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("日志条数 = %d, 期望 2", len(entries))
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		if fields["request_id"] != "req-42" {
+			t.Errorf("日志 %q 缺少请求 ID: %v", e.Message, fields)
+		}
+		for _, v := range fields {
+			if s, ok := v.(string); ok && s == "/api/kv/secret:1?type=rawkv" {
+				t.Errorf("日志 %q 包含请求路径", e.Message)
+			}
+		}
+	}
+	if route := entries[1].ContextMap()["route"]; route != "/api/kv/:key" {
+		t.Errorf("访问日志的路由 = %v, 期望路由模板", route)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/kv/a", nil))
+	if id := w.Header().Get(RequestIDHeader); len(id) != 16 {
+		t.Errorf("生成的请求 ID = %q, 期望 16 位十六进制", id)
+	}
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen 客户端传入的请求 ID 超过该长度时重新生成
+const maxRequestIDLen = 128
+
+// Middleware 为每个请求分配请求 ID 并记录访问日志。
+// 请求 ID 取自 X-Request-ID 请求头，没有时生成一个，并写回响应头。
+// 访问日志只记录路由模板，不记录路径和查询参数，避免 key 出现在日志中。
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := []zap.Field{
+			zap.String("request_id", id),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			fields = append(fields, zap.String("errors", errs.String()))
+		}
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			L().Warn("request", fields...)
+		} else {
+			L().Info("request", fields...)
+		}
+	}
+}
+
+// Recovery 捕获处理函数中的 panic，记录日志（包含调用栈）后返回 500。
+// 与 gin.Recovery 不同，不会把请求内容写入日志。
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				FromContext(c.Request.Context()).Error("panic while handling request",
+					zap.String("route", c.FullPath()),
+					zap.Any("panic", r),
+				)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// Ctx 返回当前请求的 logger
+func Ctx(c *gin.Context) *zap.Logger {
+	return FromContext(c.Request.Context())
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("150405.000000")))
+	}
+	return hex.EncodeToString(b)
+}
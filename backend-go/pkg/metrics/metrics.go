@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	clientmetrics "github.com/tikv/client-go/v2/metrics"
+)
+
+const namespace = "tikvadmin"
+
+// TiKV 操作类型
+const (
+	OpGet    = "get"
+	OpScan   = "scan"
+	OpPut    = "put"
+	OpDelete = "delete"
+	OpCommit = "commit"
+
+	OpScanLock    = "scan_lock"
+	OpResolveLock = "resolve_lock"
+	OpMvcc        = "mvcc"
+)
+
+// 后台任务和会话的类型
+const (
+	JobStatsRefresh = "stats_refresh"
+	JobStatsRecount = "stats_recount"
+	SessionHTTP     = "http"
+)
+
+// modeKey 请求体中携带模式的处理函数通过 SetMode 写入 gin.Context
+const modeKey = "metrics.mode"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route, method, mode and status code.",
+	}, []string{"route", "method", "mode", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route, method and mode.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "mode"})
+
+	tikvDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tikv_request_duration_seconds",
+		Help:      "Latency of TiKV calls made by the backend, by mode and operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 18),
+	}, []string{"mode", "op"})
+
+	tikvErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tikv_request_errors_total",
+		Help:      "Failed TiKV calls made by the backend, by mode and operation.",
+	}, []string{"mode", "op"})
+
+	txnRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "txn_retries_total",
+		Help:      "Requests retried while committing transactions, by backoff type.",
+	}, []string{"type"})
+
+	txnConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "txn_conflicts_total",
+		Help:      "Transaction commits that failed with a write conflict.",
+	})
+
+	scannedKeys = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scanned_keys_total",
+		Help:      "Keys read from TiKV by gets and scans, by mode.",
+	}, []string{"mode"})
+
+	scannedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scanned_bytes_total",
+		Help:      "Key and value bytes read from TiKV by gets and scans, by mode.",
+	}, []string{"mode"})
+
+	returnedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "returned_bytes_total",
+		Help:      "Key and value bytes returned to API clients by gets and scans, by mode.",
+	}, []string{"mode"})
+
+	activeJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_jobs",
+		Help:      "Background jobs currently running, by kind.",
+	}, []string{"kind"})
+
+	activeSessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Sessions currently open, by kind.",
+	}, []string{"kind"})
+
+	clientConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "client_connected",
+		Help:      "Set to 1 for each connected TiKV client, by cluster PD endpoints and mode; removed when the client is replaced by another cluster.",
+	}, []string{"cluster", "mode"})
+
+	registerOnce sync.Once
+)
+
+// Register 把后端的指标和 client-go 自带的指标注册到默认的 registry，重复调用无影响
+func Register() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(
+			httpRequests, httpDuration,
+			tikvDuration, tikvErrors,
+			txnRetries, txnConflicts,
+			scannedKeys, scannedBytes, returnedBytes,
+			activeJobs, activeSessions,
+			clientConnected,
+		)
+		clientmetrics.RegisterMetrics()
+	})
+}
+
+// Handler 返回 Prometheus 格式的 /metrics 处理函数
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware 统计每个 HTTP 请求的数量和耗时。
+// mode 取自 SetMode 设置的值，没有设置时取 type 查询参数。
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		activeSessions.WithLabelValues(SessionHTTP).Inc()
+		defer activeSessions.WithLabelValues(SessionHTTP).Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		mode := Mode(c)
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(route, c.Request.Method, mode, status).Inc()
+		httpDuration.WithLabelValues(route, c.Request.Method, mode).Observe(time.Since(start).Seconds())
+	}
+}
+
+// SetMode 记录请求体中指定的模式（rawkv 或 txn）
+func SetMode(c *gin.Context, mode string) {
+	c.Set(modeKey, mode)
+}
+
+// Mode 返回请求的模式：SetMode 设置的值，没有设置时取 type 查询参数。
+// 标签取值必须有限，rawkv 和 txn 以外的非空值统一记为 other。
+func Mode(c *gin.Context) string {
+	mode := c.GetString(modeKey)
+	if mode == "" {
+		mode = c.Query("type")
+	}
+	switch mode {
+	case "", "rawkv", "txn":
+		return mode
+	default:
+		return "other"
+	}
+}
+
+// ObserveTiKV 记录一次 TiKV 调用的耗时，err 不为空时计为失败
+func ObserveTiKV(mode, op string, start time.Time, err error) {
+	tikvDuration.WithLabelValues(mode, op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		tikvErrors.WithLabelValues(mode, op).Inc()
+	}
+}
+
+// ObserveTxnRetries 记录事务提交过程中的重试，每个元素为一次重试的 backoff 类型
+func ObserveTxnRetries(backoffTypes []string) {
+	for _, t := range backoffTypes {
+		txnRetries.WithLabelValues(t).Inc()
+	}
+}
+
+// ObserveTxnConflict 记录一次写冲突
+func ObserveTxnConflict() {
+	txnConflicts.Inc()
+}
+
+// ObserveScan 记录扫描读取的键数、字节数以及返回给调用方的字节数
+func ObserveScan(mode string, keys, scanned, returned int) {
+	scannedKeys.WithLabelValues(mode).Add(float64(keys))
+	scannedBytes.WithLabelValues(mode).Add(float64(scanned))
+	returnedBytes.WithLabelValues(mode).Add(float64(returned))
+}
+
+// JobStarted 记录一个后台任务开始，返回的函数在任务结束时调用
+func JobStarted(kind string) (finished func()) {
+	activeJobs.WithLabelValues(kind).Inc()
+	return func() {
+		activeJobs.WithLabelValues(kind).Dec()
+	}
+}
+
+// SetClientConnected 记录集群某个模式的客户端已连接，cluster 为 PD 地址
+func SetClientConnected(cluster, mode string) {
+	clientConnected.WithLabelValues(cluster, mode).Set(1)
+}
+
+// DeleteClientConnected 删除集群某个模式的连接状态，切换到其他集群后调用，避免旧集群的序列一直保留
+func DeleteClientConnected(cluster, mode string) {
+	clientConnected.DeleteLabelValues(cluster, mode)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMiddleware 测试请求按路由模板和模式统计，请求体中的模式优先于查询参数，未知的模式记为 other
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/kv/:key", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	router.POST("/api/kv", func(c *gin.Context) {
+		SetMode(c, "txn")
+		c.Status(http.StatusOK)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/kv/user_1?type=rawkv", nil),
+		httptest.NewRequest(http.MethodGet, "/api/kv/user_2?type=rawkv", nil),
+		httptest.NewRequest(http.MethodPost, "/api/kv?type=rawkv", nil),
+		httptest.NewRequest(http.MethodGet, "/api/kv/user_3?type=random-1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/kv/user_4?type=random-2", nil),
+		httptest.NewRequest(http.MethodGet, "/nowhere", nil),
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	cases := []struct {
+		labels []string
+		want   float64
+	}{
+		{labels: []string{"/api/kv/:key", "GET", "rawkv", "404"}, want: 2},
+		{labels: []string{"/api/kv", "POST", "txn", "200"}, want: 1},
+		{labels: []string{"/api/kv/:key", "GET", "other", "404"}, want: 2},
+		{labels: []string{"unmatched", "GET", "", "404"}, want: 1},
+	}
+	for _, tc := range cases {
+		if got := testutil.ToFloat64(httpRequests.WithLabelValues(tc.labels...)); got != tc.want {
+			t.Errorf("请求数 %v = %v, 期望 %v", tc.labels, got, tc.want)
+		}
+	}
+	if got := testutil.ToFloat64(activeSessions.WithLabelValues(SessionHTTP)); got != 0 {
+		t.Errorf("请求结束后活跃会话应为 0，实际为 %v", got)
+	}
+}
+
+// TestObserve 测试 TiKV 调用、后台任务和连接状态的统计
+func TestObserve(t *testing.T) {
+	ObserveTiKV("rawkv", OpPut, time.Now(), nil)
+	ObserveTiKV("rawkv", OpPut, time.Now(), errors.New("region unavailable"))
+	if got := testutil.ToFloat64(tikvErrors.WithLabelValues("rawkv", OpPut)); got != 1 {
+		t.Errorf("失败次数 = %v, 期望 1", got)
+	}
+
+	finished := JobStarted(JobStatsRecount)
+	if got := testutil.ToFloat64(activeJobs.WithLabelValues(JobStatsRecount)); got != 1 {
+		t.Errorf("运行中的任务 = %v, 期望 1", got)
+	}
+	finished()
+	if got := testutil.ToFloat64(activeJobs.WithLabelValues(JobStatsRecount)); got != 0 {
+		t.Errorf("任务结束后运行中的任务 = %v, 期望 0", got)
+	}
+
+	SetClientConnected("pd:2379", "txn")
+	if got := testutil.ToFloat64(clientConnected.WithLabelValues("pd:2379", "txn")); got != 1 {
+		t.Errorf("连接状态 = %v, 期望 1", got)
+	}
+	DeleteClientConnected("pd:2379", "txn")
+	if got := testutil.CollectAndCount(clientConnected); got != 0 {
+		t.Errorf("删除后仍有 %d 个连接状态序列", got)
+	}
+}
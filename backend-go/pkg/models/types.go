@@ -1,5 +1,10 @@
 package models
 
+import (
+	"tikv-backend/pkg/pd"
+	"tikv-backend/pkg/tikv"
+)
+
 // KeyValuePair 键值对
 type KeyValuePair struct {
 	Key   string `json:"key"`
@@ -120,12 +125,18 @@ type TiKVStats struct {
 type RawKVStats struct {
 	SampleKeys int  `json:"sampleKeys"`
 	Connected   bool `json:"connected"`
+	// Details 详细统计，尚未统计完成时为空
+	Details    *tikv.KVStats `json:"details,omitempty"`
+	Refreshing bool          `json:"refreshing"`
 }
 
 // TxnStats Txn 统计
 type TxnStats struct {
 	SampleKeys int  `json:"sampleKeys"`
 	Connected   bool `json:"connected"`
+	// Details 详细统计，尚未统计完成时为空
+	Details    *tikv.KVStats `json:"details,omitempty"`
+	Refreshing bool          `json:"refreshing"`
 }
 
 // OverallStats 整体统计
@@ -141,12 +152,19 @@ type ClusterStatus struct {
 	Mode         string `json:"mode"`
 	Endpoints    []string `json:"endpoints"`
 	APIVersion   string `json:"apiVersion"`
+	Health       string `json:"health"`
+	Error        string `json:"error,omitempty"`
 	ClusterInfo  *ClusterInfo `json:"clusterInfo,omitempty"`
+	Topology     *pd.Topology `json:"topology,omitempty"`
 }
 
 // ClusterInfo 集群信息
 type ClusterInfo struct {
-	RegionID string `json:"regionId"`
-	Leader   string `json:"leader"`
-	Peers    int    `json:"peers"`
+	ClusterID string `json:"clusterId"`
+	// Leader PD leader 名称
+	Leader string `json:"leader"`
+	// Peers PD 成员数量
+	Peers int `json:"peers"`
+	// Stores 未下线的 TiKV 节点数量
+	Stores int `json:"stores"`
 }
\ No newline at end of file
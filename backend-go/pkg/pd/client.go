@@ -0,0 +1,138 @@
+package pd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 单次 PD HTTP 请求的超时时间
+const DefaultTimeout = 5 * time.Second
+
+var (
+	transportMu sync.RWMutex
+	// transport 为 nil 时使用 http.DefaultTransport
+	transport *http.Transport
+)
+
+// SetTLSConfig 设置访问 PD HTTP API 使用的 TLS 配置，nil 表示使用明文 HTTP。
+// 之后创建的客户端共用同一个连接池，没有协议的地址默认使用 https。
+func SetTLSConfig(cfg *tls.Config) {
+	var t *http.Transport
+	if cfg != nil {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = cfg
+	}
+
+	transportMu.Lock()
+	previous := transport
+	transport = t
+	transportMu.Unlock()
+
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+}
+
+// CurrentTLSConfig 返回 SetTLSConfig 设置的 TLS 配置，使用明文 HTTP 时返回 nil
+func CurrentTLSConfig() *tls.Config {
+	if t := currentTransport(); t != nil {
+		return t.TLSClientConfig
+	}
+	return nil
+}
+
+func currentTransport() *http.Transport {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return transport
+}
+
+// Client PD HTTP API 客户端，按顺序尝试各个 PD 节点，直到有一个返回成功
+type Client struct {
+	endpoints []string
+	http      *http.Client
+}
+
+// NewClient 创建 PD HTTP API 客户端，endpoints 与 TiKV 客户端使用的 PD 地址相同，
+// 没有协议时默认使用 http，设置了 TLS 配置时使用 https
+func NewClient(endpoints []string) *Client {
+	t := currentTransport()
+	scheme := "http://"
+	httpClient := &http.Client{Timeout: DefaultTimeout}
+	if t != nil {
+		scheme = "https://"
+		httpClient.Transport = t
+	}
+
+	urls := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if endpoint == "" {
+			continue
+		}
+		if !strings.Contains(endpoint, "://") {
+			endpoint = scheme + endpoint
+		}
+		urls = append(urls, endpoint)
+	}
+
+	return &Client{
+		endpoints: urls,
+		http:      httpClient,
+	}
+}
+
+// Endpoints 返回规范化之后的 PD 地址
+func (c *Client) Endpoints() []string {
+	return append([]string{}, c.endpoints...)
+}
+
+// get 请求 PD HTTP API 并解析 JSON 结果，所有节点都失败时返回最后一个错误
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	if len(c.endpoints) == 0 {
+		return fmt.Errorf("no PD endpoints configured")
+	}
+
+	var lastErr error
+	for _, endpoint := range c.endpoints {
+		if lastErr = c.getFrom(ctx, endpoint+path, out); lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+func (c *Client) getFrom(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("GET %s: failed to decode response: %v", url, err)
+	}
+	return nil
+}
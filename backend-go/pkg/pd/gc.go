@@ -0,0 +1,43 @@
+package pd
+
+import (
+	"context"
+	"fmt"
+)
+
+// ServiceSafePoint 各个服务（如 BR、TiCDC、Lightning）注册的 GC safe point，
+// GC safe point 不会超过其中最小的值。ExpiredAt 为 Unix 时间（秒）。
+type ServiceSafePoint struct {
+	ServiceID string `json:"serviceId"`
+	ExpiredAt int64  `json:"expiredAt"`
+	SafePoint uint64 `json:"safePoint"`
+}
+
+type serviceSafePoint struct {
+	ServiceID string `json:"service_id"`
+	ExpiredAt int64  `json:"expired_at"`
+	SafePoint uint64 `json:"safe_point"`
+}
+
+type gcSafePointResponse struct {
+	ServiceSafePoints []serviceSafePoint `json:"service_gc_safe_points"`
+	GCSafePoint       uint64             `json:"gc_safe_point"`
+}
+
+// ServiceSafePoints 返回所有服务的 GC safe point 以及 PD 记录的 GC safe point
+func (c *Client) ServiceSafePoints(ctx context.Context) ([]ServiceSafePoint, uint64, error) {
+	var resp gcSafePointResponse
+	if err := c.get(ctx, "/pd/api/v1/gc/safepoint", &resp); err != nil {
+		return nil, 0, fmt.Errorf("failed to get service safe points: %v", err)
+	}
+
+	points := make([]ServiceSafePoint, 0, len(resp.ServiceSafePoints))
+	for _, p := range resp.ServiceSafePoints {
+		points = append(points, ServiceSafePoint{
+			ServiceID: p.ServiceID,
+			ExpiredAt: p.ExpiredAt,
+			SafePoint: p.SafePoint,
+		})
+	}
+	return points, resp.GCSafePoint, nil
+}
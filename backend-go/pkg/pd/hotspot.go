@@ -0,0 +1,92 @@
+package pd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// HotRegion PD 统计的热点 region，速率为每秒的值
+type HotRegion struct {
+	RegionID  uint64  `json:"regionId"`
+	StoreID   uint64  `json:"storeId"`
+	HotDegree int     `json:"hotDegree"`
+	ByteRate  float64 `json:"byteRate"`
+	KeyRate   float64 `json:"keyRate"`
+	QueryRate float64 `json:"queryRate"`
+}
+
+type hotPeerStat struct {
+	StoreID   uint64  `json:"store_id"`
+	RegionID  uint64  `json:"region_id"`
+	HotDegree int     `json:"hot_degree"`
+	ByteRate  float64 `json:"flow_bytes"`
+	KeyRate   float64 `json:"flow_keys"`
+	QueryRate float64 `json:"flow_query"`
+}
+
+type hotPeersStat struct {
+	Stats []hotPeerStat `json:"statistics"`
+}
+
+type hotRegionsResponse struct {
+	AsLeader map[string]*hotPeersStat `json:"as_leader"`
+}
+
+// HotRegions 返回 PD 统计的热读或热写 region，按 leader 统计，每个 region 只出现一次
+func (c *Client) HotRegions(ctx context.Context, op string) ([]HotRegion, error) {
+	if op != "read" && op != "write" {
+		return nil, fmt.Errorf("invalid hotspot type %q", op)
+	}
+
+	var resp hotRegionsResponse
+	if err := c.get(ctx, "/pd/api/v1/hotspot/regions/"+op, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get hot %s regions: %v", op, err)
+	}
+
+	byRegion := make(map[uint64]HotRegion)
+	for _, store := range resp.AsLeader {
+		if store == nil {
+			continue
+		}
+		for _, s := range store.Stats {
+			if existing, ok := byRegion[s.RegionID]; ok && existing.QueryRate+existing.ByteRate >= s.QueryRate+s.ByteRate {
+				continue
+			}
+			byRegion[s.RegionID] = HotRegion{
+				RegionID:  s.RegionID,
+				StoreID:   s.StoreID,
+				HotDegree: s.HotDegree,
+				ByteRate:  s.ByteRate,
+				KeyRate:   s.KeyRate,
+				QueryRate: s.QueryRate,
+			}
+		}
+	}
+
+	regions := make([]HotRegion, 0, len(byRegion))
+	for _, r := range byRegion {
+		regions = append(regions, r)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].RegionID < regions[j].RegionID
+	})
+	return regions, nil
+}
+
+// Region 按 ID 查询 region
+func (c *Client) Region(ctx context.Context, id uint64) (*Region, error) {
+	var info regionInfo
+	if err := c.get(ctx, "/pd/api/v1/region/id/"+strconv.FormatUint(id, 10), &info); err != nil {
+		return nil, fmt.Errorf("failed to get region %d: %v", id, err)
+	}
+	if info.ID == 0 {
+		return nil, fmt.Errorf("region %d not found", id)
+	}
+	region, err := info.toRegion()
+	if err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
@@ -0,0 +1,194 @@
+package pd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// MaxRegionScanLimit PD 单次扫描 region 的数量上限
+const MaxRegionScanLimit = 10240
+
+// RegionEpoch region 的版本信息
+type RegionEpoch struct {
+	ConfVer uint64 `json:"confVer"`
+	Version uint64 `json:"version"`
+}
+
+// Peer region 的副本
+type Peer struct {
+	ID           uint64 `json:"id"`
+	StoreID      uint64 `json:"storeId"`
+	StoreAddress string `json:"storeAddress,omitempty"`
+	Role         string `json:"role"`
+	// Down 副本所在节点失联，DownSeconds 为失联时长
+	Down        bool   `json:"down,omitempty"`
+	DownSeconds uint64 `json:"downSeconds,omitempty"`
+	// Pending 副本的日志落后于 leader
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Region PD 中缓存的 region 信息，StartKey / EndKey 为编码后的 region 边界
+type Region struct {
+	ID       uint64      `json:"id"`
+	StartKey []byte      `json:"-"`
+	EndKey   []byte      `json:"-"`
+	Epoch    RegionEpoch `json:"epoch"`
+	Leader   *Peer       `json:"leader,omitempty"`
+	Peers    []Peer      `json:"peers"`
+	// ApproximateSize 近似大小（字节），由 PD 按 MiB 统计
+	ApproximateSize uint64 `json:"approximateSize"`
+	ApproximateKeys uint64 `json:"approximateKeys"`
+	WrittenBytes    uint64 `json:"writtenBytes"`
+	ReadBytes       uint64 `json:"readBytes"`
+	WrittenKeys     uint64 `json:"writtenKeys"`
+	ReadKeys        uint64 `json:"readKeys"`
+}
+
+type peerInfo struct {
+	ID       uint64 `json:"id"`
+	StoreID  uint64 `json:"store_id"`
+	RoleName string `json:"role_name"`
+}
+
+type downPeerInfo struct {
+	Peer        peerInfo `json:"peer"`
+	DownSeconds uint64   `json:"down_seconds"`
+}
+
+type regionInfo struct {
+	ID       uint64 `json:"id"`
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	Epoch    struct {
+		ConfVer uint64 `json:"conf_ver"`
+		Version uint64 `json:"version"`
+	} `json:"epoch"`
+	Peers           []peerInfo     `json:"peers"`
+	Leader          *peerInfo      `json:"leader"`
+	DownPeers       []downPeerInfo `json:"down_peers"`
+	PendingPeers    []peerInfo     `json:"pending_peers"`
+	WrittenBytes    uint64         `json:"written_bytes"`
+	ReadBytes       uint64         `json:"read_bytes"`
+	WrittenKeys     uint64         `json:"written_keys"`
+	ReadKeys        uint64         `json:"read_keys"`
+	ApproximateSize uint64         `json:"approximate_size"`
+	ApproximateKeys uint64         `json:"approximate_keys"`
+}
+
+type regionsResponse struct {
+	Count   int          `json:"count"`
+	Regions []regionInfo `json:"regions"`
+}
+
+// ScanRegions 返回与编码后的范围 [start, end) 相交的 region，从包含 start 的 region 开始，end 为空表示没有上界
+func (c *Client) ScanRegions(ctx context.Context, start, end []byte, limit int) ([]Region, error) {
+	if limit <= 0 || limit > MaxRegionScanLimit {
+		limit = MaxRegionScanLimit
+	}
+
+	query := url.Values{}
+	query.Set("key", string(start))
+	if len(end) > 0 {
+		query.Set("end_key", string(end))
+	}
+	query.Set("limit", strconv.Itoa(limit))
+
+	var resp regionsResponse
+	if err := c.get(ctx, "/pd/api/v1/regions/key?"+query.Encode(), &resp); err != nil {
+		return nil, fmt.Errorf("failed to scan regions: %v", err)
+	}
+
+	regions := make([]Region, 0, len(resp.Regions))
+	for _, info := range resp.Regions {
+		region, err := info.toRegion()
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// StoreAddresses 返回 store ID 到地址的映射
+func (c *Client) StoreAddresses(ctx context.Context) (map[uint64]string, error) {
+	var stores storesResponse
+	if err := c.get(ctx, "/pd/api/v1/stores", &stores); err != nil {
+		return nil, fmt.Errorf("failed to get stores: %v", err)
+	}
+
+	addresses := make(map[uint64]string, len(stores.Stores))
+	for _, s := range stores.Stores {
+		addresses[s.Store.ID] = s.Store.Address
+	}
+	return addresses, nil
+}
+
+// SetStoreAddresses 填充副本所在节点的地址
+func (r *Region) SetStoreAddresses(addresses map[uint64]string) {
+	if r.Leader != nil {
+		r.Leader.StoreAddress = addresses[r.Leader.StoreID]
+	}
+	for i := range r.Peers {
+		r.Peers[i].StoreAddress = addresses[r.Peers[i].StoreID]
+	}
+}
+
+func (info regionInfo) toRegion() (Region, error) {
+	startKey, err := decodeRegionKey(info.StartKey)
+	if err != nil {
+		return Region{}, fmt.Errorf("invalid start key of region %d: %v", info.ID, err)
+	}
+	endKey, err := decodeRegionKey(info.EndKey)
+	if err != nil {
+		return Region{}, fmt.Errorf("invalid end key of region %d: %v", info.ID, err)
+	}
+
+	down := make(map[uint64]uint64, len(info.DownPeers))
+	for _, p := range info.DownPeers {
+		down[p.Peer.ID] = p.DownSeconds
+	}
+	pending := make(map[uint64]bool, len(info.PendingPeers))
+	for _, p := range info.PendingPeers {
+		pending[p.ID] = true
+	}
+
+	region := Region{
+		ID:              info.ID,
+		StartKey:        startKey,
+		EndKey:          endKey,
+		Epoch:           RegionEpoch{ConfVer: info.Epoch.ConfVer, Version: info.Epoch.Version},
+		Peers:           make([]Peer, 0, len(info.Peers)),
+		ApproximateSize: info.ApproximateSize << 20,
+		ApproximateKeys: info.ApproximateKeys,
+		WrittenBytes:    info.WrittenBytes,
+		ReadBytes:       info.ReadBytes,
+		WrittenKeys:     info.WrittenKeys,
+		ReadKeys:        info.ReadKeys,
+	}
+	for _, p := range info.Peers {
+		downSeconds, isDown := down[p.ID]
+		region.Peers = append(region.Peers, Peer{
+			ID:          p.ID,
+			StoreID:     p.StoreID,
+			Role:        p.RoleName,
+			Down:        isDown,
+			DownSeconds: downSeconds,
+			Pending:     pending[p.ID],
+		})
+	}
+	if info.Leader != nil && info.Leader.ID != 0 {
+		region.Leader = &Peer{ID: info.Leader.ID, StoreID: info.Leader.StoreID, Role: info.Leader.RoleName}
+	}
+	return region, nil
+}
+
+// decodeRegionKey PD 返回的 region 边界是十六进制字符串
+func decodeRegionKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
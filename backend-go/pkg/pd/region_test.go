@@ -0,0 +1,55 @@
+package pd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestScanRegions 测试 region 查询参数和结果解析
+func TestScanRegions(t *testing.T) {
+	var query map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pd/api/v1/regions/key" {
+			http.NotFound(w, r)
+			return
+		}
+		query = map[string]string{
+			"key":     r.URL.Query().Get("key"),
+			"end_key": r.URL.Query().Get("end_key"),
+			"limit":   r.URL.Query().Get("limit"),
+		}
+		w.Write([]byte(`{"count": 1, "regions": [{
+			"id": 8, "start_key": "72000000", "end_key": "",
+			"epoch": {"conf_ver": 5, "version": 12},
+			"peers": [{"id": 9, "store_id": 1, "role_name": "Voter"}, {"id": 10, "store_id": 2, "role_name": "Voter"}],
+			"leader": {"id": 9, "store_id": 1, "role_name": "Voter"},
+			"down_peers": [{"peer": {"id": 10, "store_id": 2}, "down_seconds": 120}],
+			"approximate_size": 96, "approximate_keys": 1000
+		}]}`))
+	}))
+	defer srv.Close()
+
+	regions, err := NewClient([]string{srv.URL}).ScanRegions(context.Background(), []byte("a\x00b"), nil, 0)
+	if err != nil {
+		t.Fatalf("ScanRegions 失败: %v", err)
+	}
+	if query["key"] != "a\x00b" || query["end_key"] != "" || query["limit"] != "10240" {
+		t.Errorf("查询参数错误: %q", query)
+	}
+
+	if len(regions) != 1 {
+		t.Fatalf("region 数量错误: %d", len(regions))
+	}
+	region := regions[0]
+	if string(region.StartKey) != "r\x00\x00\x00" || region.EndKey != nil {
+		t.Errorf("region 边界错误: %X - %X", region.StartKey, region.EndKey)
+	}
+	if region.ApproximateSize != 96<<20 || region.ApproximateKeys != 1000 || region.Epoch.Version != 12 {
+		t.Errorf("region 信息错误: %+v", region)
+	}
+	if region.Leader == nil || region.Leader.StoreID != 1 || !region.Peers[1].Down || region.Peers[1].DownSeconds != 120 {
+		t.Errorf("副本信息错误: leader=%+v peers=%+v", region.Leader, region.Peers)
+	}
+}
@@ -0,0 +1,221 @@
+package pd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 集群健康状态
+const (
+	HealthHealthy     = "healthy"
+	HealthDegraded    = "degraded"
+	HealthUnhealthy   = "unhealthy"
+	HealthUnreachable = "unreachable"
+)
+
+// TiKV 节点状态，由 PD 的 state_name 转为小写得到
+const (
+	StoreStateUp           = "up"
+	StoreStateDisconnected = "disconnected"
+	StoreStateDown         = "down"
+	StoreStateOffline      = "offline"
+	StoreStateTombstone    = "tombstone"
+)
+
+// lowSpaceRatio 可用空间低于容量的这个比例时认为节点空间不足
+const lowSpaceRatio = 0.1
+
+// Member PD 节点
+type Member struct {
+	Name       string   `json:"name"`
+	MemberID   string   `json:"memberId"`
+	ClientURLs []string `json:"clientUrls"`
+	PeerURLs   []string `json:"peerUrls"`
+	Version    string   `json:"version,omitempty"`
+	IsLeader   bool     `json:"isLeader"`
+	Healthy    bool     `json:"healthy"`
+}
+
+// Store TiKV 节点
+type Store struct {
+	ID            uint64            `json:"id"`
+	Address       string            `json:"address"`
+	StatusAddress string            `json:"statusAddress,omitempty"`
+	State         string            `json:"state"`
+	Version       string            `json:"version"`
+	Capacity      ByteSize          `json:"capacity"`
+	Available     ByteSize          `json:"available"`
+	UsedSize      ByteSize          `json:"usedSize"`
+	Labels        map[string]string `json:"labels"`
+	LeaderCount   int               `json:"leaderCount"`
+	RegionCount   int               `json:"regionCount"`
+	LastHeartbeat string            `json:"lastHeartbeat,omitempty"`
+	Uptime        string            `json:"uptime,omitempty"`
+}
+
+// Topology 集群拓扑和根据拓扑推导出的健康状态
+type Topology struct {
+	// ClusterID 用字符串表示，避免前端解析大整数时丢失精度
+	ClusterID    string         `json:"clusterId"`
+	MaxPeerCount int            `json:"maxPeerCount"`
+	Health       string         `json:"health"`
+	Problems     []string       `json:"problems,omitempty"`
+	Leader       string         `json:"leader"`
+	Members      []Member       `json:"members"`
+	Stores       []Store        `json:"stores"`
+	StoreStates  map[string]int `json:"storeStates"`
+}
+
+// Topology 查询 PD 成员、TiKV 节点和集群 ID，并推导集群健康状态
+func (c *Client) Topology(ctx context.Context) (*Topology, error) {
+	var members membersResponse
+	if err := c.get(ctx, "/pd/api/v1/members", &members); err != nil {
+		return nil, fmt.Errorf("failed to get PD members: %v", err)
+	}
+	var health []memberHealth
+	if err := c.get(ctx, "/pd/api/v1/health", &health); err != nil {
+		return nil, fmt.Errorf("failed to get PD health: %v", err)
+	}
+	var cluster clusterResponse
+	if err := c.get(ctx, "/pd/api/v1/cluster", &cluster); err != nil {
+		return nil, fmt.Errorf("failed to get cluster info: %v", err)
+	}
+	// 默认不返回 tombstone 节点，需要显式指定所有状态
+	var stores storesResponse
+	if err := c.get(ctx, "/pd/api/v1/stores?state=0&state=1&state=2", &stores); err != nil {
+		return nil, fmt.Errorf("failed to get stores: %v", err)
+	}
+
+	topology := buildTopology(members, health, cluster, stores)
+	topology.Health, topology.Problems = deriveHealth(topology)
+	return topology, nil
+}
+
+// CheckHealth 检查 PD 是否可用：能访问 PD HTTP API，并且健康的成员超过半数
+func (c *Client) CheckHealth(ctx context.Context) error {
+	var health []memberHealth
+	if err := c.get(ctx, "/pd/api/v1/health", &health); err != nil {
+		return fmt.Errorf("failed to get PD health: %v", err)
+	}
+	healthy := 0
+	for _, h := range health {
+		if h.Health {
+			healthy++
+		}
+	}
+	if healthy*2 <= len(health) {
+		return fmt.Errorf("only %d of %d PD members are healthy", healthy, len(health))
+	}
+	return nil
+}
+
+func buildTopology(members membersResponse, health []memberHealth, cluster clusterResponse, stores storesResponse) *Topology {
+	healthy := make(map[uint64]bool, len(health))
+	for _, h := range health {
+		healthy[h.MemberID] = h.Health
+	}
+
+	topology := &Topology{
+		ClusterID:    strconv.FormatUint(cluster.ID, 10),
+		MaxPeerCount: cluster.MaxPeerCount,
+		Members:      make([]Member, 0, len(members.Members)),
+		Stores:       make([]Store, 0, len(stores.Stores)),
+		StoreStates:  make(map[string]int),
+	}
+
+	for _, m := range members.Members {
+		isLeader := members.Leader != nil && members.Leader.MemberID == m.MemberID
+		if isLeader {
+			topology.Leader = m.Name
+		}
+		topology.Members = append(topology.Members, Member{
+			Name:       m.Name,
+			MemberID:   strconv.FormatUint(m.MemberID, 10),
+			ClientURLs: m.ClientURLs,
+			PeerURLs:   m.PeerURLs,
+			Version:    m.BinaryVersion,
+			IsLeader:   isLeader,
+			Healthy:    healthy[m.MemberID],
+		})
+	}
+
+	for _, s := range stores.Stores {
+		labels := make(map[string]string, len(s.Store.Labels))
+		for _, l := range s.Store.Labels {
+			labels[l.Key] = l.Value
+		}
+		state := strings.ToLower(s.Store.StateName)
+		topology.StoreStates[state]++
+		topology.Stores = append(topology.Stores, Store{
+			ID:            s.Store.ID,
+			Address:       s.Store.Address,
+			StatusAddress: s.Store.StatusAddress,
+			State:         state,
+			Version:       s.Store.Version,
+			Capacity:      s.Status.Capacity,
+			Available:     s.Status.Available,
+			UsedSize:      s.Status.UsedSize,
+			Labels:        labels,
+			LeaderCount:   s.Status.LeaderCount,
+			RegionCount:   s.Status.RegionCount,
+			LastHeartbeat: s.Status.LastHeartbeatTS,
+			Uptime:        s.Status.Uptime,
+		})
+	}
+	sort.Slice(topology.Stores, func(i, j int) bool {
+		return topology.Stores[i].ID < topology.Stores[j].ID
+	})
+
+	return topology
+}
+
+// deriveHealth 根据拓扑推导集群健康状态：
+// 没有 PD leader、PD 失去多数派或没有可用的 TiKV 节点时为 unhealthy；
+// 有 PD 节点不健康、TiKV 节点不在线或空间不足时为 degraded。
+func deriveHealth(t *Topology) (string, []string) {
+	var critical, warnings []string
+
+	if t.Leader == "" {
+		critical = append(critical, "PD has no leader")
+	}
+	healthyMembers := 0
+	for _, m := range t.Members {
+		if m.Healthy {
+			healthyMembers++
+		} else {
+			warnings = append(warnings, fmt.Sprintf("PD member %s is unhealthy", m.Name))
+		}
+	}
+	if len(t.Members) > 0 && healthyMembers*2 <= len(t.Members) {
+		critical = append(critical, fmt.Sprintf("only %d of %d PD members are healthy", healthyMembers, len(t.Members)))
+	}
+
+	if t.StoreStates[StoreStateUp] == 0 {
+		critical = append(critical, "no TiKV store is up")
+	}
+	for _, s := range t.Stores {
+		switch s.State {
+		case StoreStateUp:
+			if s.Capacity > 0 && float64(s.Available) < float64(s.Capacity)*lowSpaceRatio {
+				warnings = append(warnings, fmt.Sprintf("store %d (%s) is low on space", s.ID, s.Address))
+			}
+		case StoreStateTombstone:
+			// 已经下线完成的节点不影响健康状态
+		default:
+			warnings = append(warnings, fmt.Sprintf("store %d (%s) is %s", s.ID, s.Address, s.State))
+		}
+	}
+
+	problems := append(critical, warnings...)
+	switch {
+	case len(critical) > 0:
+		return HealthUnhealthy, problems
+	case len(warnings) > 0:
+		return HealthDegraded, problems
+	default:
+		return HealthHealthy, nil
+	}
+}
@@ -0,0 +1,148 @@
+package pd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakePD 启动一个返回固定内容的 PD HTTP API
+func newFakePD(t *testing.T, stores string) *httptest.Server {
+	responses := map[string]string{
+		"/pd/api/v1/members": `{
+			"members": [
+				{"name": "pd-1", "member_id": 11, "client_urls": ["http://pd-1:2379"], "binary_version": "v6.5.0"},
+				{"name": "pd-2", "member_id": 12, "client_urls": ["http://pd-2:2379"], "binary_version": "v6.5.0"},
+				{"name": "pd-3", "member_id": 13, "client_urls": ["http://pd-3:2379"], "binary_version": "v6.5.0"}
+			],
+			"leader": {"name": "pd-2", "member_id": 12}
+		}`,
+		"/pd/api/v1/health": `[
+			{"name": "pd-1", "member_id": 11, "health": true},
+			{"name": "pd-2", "member_id": 12, "health": true},
+			{"name": "pd-3", "member_id": 13, "health": true}
+		]`,
+		"/pd/api/v1/cluster": `{"id": 7200000000000000001, "max_peer_count": 3}`,
+		"/pd/api/v1/stores":  stores,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestTopology 测试拓扑解析和健康状态推导
+func TestTopology(t *testing.T) {
+	cases := []struct {
+		name   string
+		stores string
+		health string
+	}{
+		{
+			name: "所有节点在线",
+			stores: `{"count": 2, "stores": [
+				{"store": {"id": 2, "address": "tikv-2:20160", "state_name": "Up", "labels": [{"key": "zone", "value": "z2"}]},
+				 "status": {"capacity": "100GiB", "available": "60GiB", "leader_count": 5, "region_count": 12}},
+				{"store": {"id": 1, "address": "tikv-1:20160", "state_name": "Up"},
+				 "status": {"capacity": "100GiB", "available": "55.5GiB", "leader_count": 7, "region_count": 12}}
+			]}`,
+			health: HealthHealthy,
+		},
+		{
+			name: "有节点宕机",
+			stores: `{"count": 3, "stores": [
+				{"store": {"id": 1, "address": "tikv-1:20160", "state_name": "Up"}, "status": {"capacity": "100GiB", "available": "60GiB"}},
+				{"store": {"id": 2, "address": "tikv-2:20160", "state_name": "Down"}, "status": {"capacity": "100GiB", "available": "60GiB"}},
+				{"store": {"id": 3, "address": "tikv-3:20160", "state_name": "Tombstone"}, "status": {}}
+			]}`,
+			health: HealthDegraded,
+		},
+		{
+			name: "空间不足",
+			stores: `{"count": 1, "stores": [
+				{"store": {"id": 1, "address": "tikv-1:20160", "state_name": "Up"}, "status": {"capacity": "100GiB", "available": "5GiB"}}
+			]}`,
+			health: HealthDegraded,
+		},
+		{
+			name:   "没有在线节点",
+			stores: `{"count": 0, "stores": []}`,
+			health: HealthUnhealthy,
+		},
+	}
+
+	for _, tc := range cases {
+		srv := newFakePD(t, tc.stores)
+		client := NewClient([]string{strings.TrimPrefix(srv.URL, "http://")})
+
+		topology, err := client.Topology(context.Background())
+		if err != nil {
+			t.Fatalf("%s: Topology 失败: %v", tc.name, err)
+		}
+		if topology.Health != tc.health {
+			t.Errorf("%s: health = %s, 期望 %s, problems=%v", tc.name, topology.Health, tc.health, topology.Problems)
+		}
+		if topology.ClusterID != "7200000000000000001" || topology.Leader != "pd-2" || len(topology.Members) != 3 {
+			t.Errorf("%s: 集群信息错误: %+v", tc.name, topology)
+		}
+	}
+}
+
+// TestTopologyStores 测试节点信息的解析
+func TestTopologyStores(t *testing.T) {
+	srv := newFakePD(t, `{"count": 2, "stores": [
+		{"store": {"id": 2, "address": "tikv-2:20160", "state_name": "Offline", "version": "6.5.0", "labels": [{"key": "zone", "value": "z2"}]},
+		 "status": {"capacity": "1TiB", "available": "512GiB", "leader_count": 5, "region_count": 12}},
+		{"store": {"id": 1, "address": "tikv-1:20160", "state_name": "Up"}, "status": {"capacity": 1024, "available": "1.5KiB"}}
+	]}`)
+
+	topology, err := NewClient([]string{srv.URL}).Topology(context.Background())
+	if err != nil {
+		t.Fatalf("Topology 失败: %v", err)
+	}
+
+	if len(topology.Stores) != 2 || topology.Stores[0].ID != 1 {
+		t.Fatalf("节点应按 ID 排序: %+v", topology.Stores)
+	}
+	store := topology.Stores[1]
+	if store.State != StoreStateOffline || store.Capacity != 1<<40 || store.Available != 512<<30 ||
+		store.Labels["zone"] != "z2" || store.LeaderCount != 5 || store.RegionCount != 12 {
+		t.Errorf("节点信息错误: %+v", store)
+	}
+	if topology.Stores[0].Capacity != 1024 || topology.Stores[0].Available != 1536 {
+		t.Errorf("字节数解析错误: %+v", topology.Stores[0])
+	}
+	if topology.StoreStates[StoreStateUp] != 1 || topology.StoreStates[StoreStateOffline] != 1 {
+		t.Errorf("节点状态统计错误: %v", topology.StoreStates)
+	}
+}
+
+// TestCheckHealth 测试 PD 健康检查：多数成员健康时通过，PD 不可访问时失败
+func TestCheckHealth(t *testing.T) {
+	srv := newFakePD(t, `{"count": 0, "stores": []}`)
+	if err := NewClient([]string{srv.URL}).CheckHealth(context.Background()); err != nil {
+		t.Errorf("PD 健康时不应返回错误: %v", err)
+	}
+
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name": "pd-1", "health": true}, {"name": "pd-2", "health": false}]`))
+	}))
+	defer unhealthy.Close()
+	if err := NewClient([]string{unhealthy.URL}).CheckHealth(context.Background()); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("半数成员不健康时应返回错误, 实际为 %v", err)
+	}
+
+	unhealthy.Close()
+	if err := NewClient([]string{unhealthy.URL}).CheckHealth(context.Background()); err == nil {
+		t.Errorf("PD 不可访问时应返回错误")
+	}
+}
@@ -0,0 +1,123 @@
+package pd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize 字节数。PD 返回的是 "1.5GiB" 这样的字符串，输出时统一为字节数
+type ByteSize uint64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   float64
+}{
+	{"EiB", 1 << 60},
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize 解析 PD 的字节数字符串，例如 "99.8GiB"、"512B"，不带单位时按字节处理
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	unit := 1.0
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize(v * unit), nil
+}
+
+// UnmarshalJSON 同时支持字符串和数字
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n uint64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*b = ByteSize(n)
+		return nil
+	}
+
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// 以下是 PD HTTP API 的原始响应结构，只保留用到的字段
+
+type memberInfo struct {
+	Name          string   `json:"name"`
+	MemberID      uint64   `json:"member_id"`
+	PeerURLs      []string `json:"peer_urls"`
+	ClientURLs    []string `json:"client_urls"`
+	BinaryVersion string   `json:"binary_version"`
+}
+
+type membersResponse struct {
+	Members []memberInfo `json:"members"`
+	Leader  *memberInfo  `json:"leader"`
+}
+
+type memberHealth struct {
+	Name     string `json:"name"`
+	MemberID uint64 `json:"member_id"`
+	Health   bool   `json:"health"`
+}
+
+type clusterResponse struct {
+	ID           uint64 `json:"id"`
+	MaxPeerCount int    `json:"max_peer_count"`
+}
+
+type storeLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type storeMeta struct {
+	ID            uint64       `json:"id"`
+	Address       string       `json:"address"`
+	StatusAddress string       `json:"status_address"`
+	Labels        []storeLabel `json:"labels"`
+	Version       string       `json:"version"`
+	StateName     string       `json:"state_name"`
+}
+
+type storeStatus struct {
+	Capacity        ByteSize `json:"capacity"`
+	Available       ByteSize `json:"available"`
+	UsedSize        ByteSize `json:"used_size"`
+	LeaderCount     int      `json:"leader_count"`
+	RegionCount     int      `json:"region_count"`
+	LastHeartbeatTS string   `json:"last_heartbeat_ts"`
+	Uptime          string   `json:"uptime"`
+}
+
+type storeInfo struct {
+	Store  storeMeta   `json:"store"`
+	Status storeStatus `json:"status"`
+}
+
+type storesResponse struct {
+	Count  int         `json:"count"`
+	Stores []storeInfo `json:"stores"`
+}
@@ -0,0 +1,106 @@
+package tikv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/rawkv"
+	tikvstore "github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// API 版本名称：v1 对应 TiKV 的 storage.api-version = 1，v1ttl 为 api-version = 1 且 enable-ttl = true，v2 为 api-version = 2
+const (
+	APIVersionV1    = "v1"
+	APIVersionV1TTL = "v1ttl"
+	APIVersionV2    = "v2"
+)
+
+// ClusterOptions 连接集群使用的 API 版本和 keyspace，必须与集群的配置一致
+type ClusterOptions struct {
+	APIVersion kvrpcpb.APIVersion
+	// Keyspace 为空时使用默认 keyspace，只有 API V2 支持
+	Keyspace string
+}
+
+// DefaultClusterOptions 返回默认选项：API V2、默认 keyspace
+func DefaultClusterOptions() ClusterOptions {
+	return ClusterOptions{APIVersion: kvrpcpb.APIVersion_V2}
+}
+
+// ParseClusterOptions 解析 API 版本名称和 keyspace，apiVersion 为空时使用 v2
+func ParseClusterOptions(apiVersion, keyspace string) (ClusterOptions, error) {
+	opts := ClusterOptions{Keyspace: keyspace}
+	switch strings.ToLower(apiVersion) {
+	case APIVersionV1:
+		opts.APIVersion = kvrpcpb.APIVersion_V1
+	case APIVersionV1TTL:
+		opts.APIVersion = kvrpcpb.APIVersion_V1TTL
+	case "", APIVersionV2:
+		opts.APIVersion = kvrpcpb.APIVersion_V2
+	default:
+		return ClusterOptions{}, fmt.Errorf("invalid api version %q: must be %s, %s or %s", apiVersion, APIVersionV1, APIVersionV1TTL, APIVersionV2)
+	}
+	if err := opts.Validate(); err != nil {
+		return ClusterOptions{}, err
+	}
+	return opts, nil
+}
+
+// Validate 检查 API 版本和 keyspace 的组合
+func (o ClusterOptions) Validate() error {
+	switch o.APIVersion {
+	case kvrpcpb.APIVersion_V1, kvrpcpb.APIVersion_V1TTL:
+		if o.Keyspace != "" {
+			return fmt.Errorf("keyspace %q requires api version %s", o.Keyspace, APIVersionV2)
+		}
+	case kvrpcpb.APIVersion_V2:
+	default:
+		return fmt.Errorf("unsupported api version %d", o.APIVersion)
+	}
+	return nil
+}
+
+// APIVersionName 返回 API 版本的名称
+func (o ClusterOptions) APIVersionName() string {
+	switch o.APIVersion {
+	case kvrpcpb.APIVersion_V1:
+		return APIVersionV1
+	case kvrpcpb.APIVersion_V1TTL:
+		return APIVersionV1TTL
+	default:
+		return APIVersionV2
+	}
+}
+
+// rawOptions RawKV 客户端的 API 版本和 keyspace 选项
+func (o ClusterOptions) rawOptions() []rawkv.ClientOpt {
+	opts := []rawkv.ClientOpt{rawkv.WithAPIVersion(o.APIVersion)}
+	if o.APIVersion == kvrpcpb.APIVersion_V2 && o.Keyspace != "" {
+		opts = append(opts, rawkv.WithKeyspace(o.Keyspace))
+	}
+	return opts
+}
+
+// txnOptions Txn 客户端的 API 版本和 keyspace 选项。
+// V1TTL 只影响 RawKV 的 value 格式，client-go 的 Txn 客户端只支持 V1 和 V2，因此 V1TTL 集群使用 V1。
+func (o ClusterOptions) txnOptions() []txnkv.ClientOpt {
+	version := o.APIVersion
+	if version == kvrpcpb.APIVersion_V1TTL {
+		version = kvrpcpb.APIVersion_V1
+	}
+	opts := []txnkv.ClientOpt{txnkv.WithAPIVersion(version)}
+	if version == kvrpcpb.APIVersion_V2 && o.Keyspace != "" {
+		opts = append(opts, txnkv.WithKeyspace(o.Keyspace))
+	}
+	return opts
+}
+
+// rawKeyspaceID 返回 API V2 下 RawKV 客户端连接的 keyspace ID
+func rawKeyspaceID(cli *rawkv.Client) uint32 {
+	if codecCli, ok := cli.GetPDClient().(*tikvstore.CodecPDClient); ok && codecCli.GetCodec().GetAPIVersion() == kvrpcpb.APIVersion_V2 {
+		return uint32(codecCli.GetCodec().GetKeyspaceID())
+	}
+	return DefaultKeyspaceID
+}
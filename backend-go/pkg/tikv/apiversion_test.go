@@ -0,0 +1,79 @@
+package tikv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/util/codec"
+)
+
+// TestParseClusterOptions 测试 API 版本名称的解析以及 keyspace 只能用于 API V2
+func TestParseClusterOptions(t *testing.T) {
+	cases := []struct {
+		apiVersion string
+		keyspace   string
+		want       kvrpcpb.APIVersion
+		wantErr    string
+	}{
+		{apiVersion: "", want: kvrpcpb.APIVersion_V2},
+		{apiVersion: "v1", want: kvrpcpb.APIVersion_V1},
+		{apiVersion: "V1TTL", want: kvrpcpb.APIVersion_V1TTL},
+		{apiVersion: "v2", keyspace: "tenant_a", want: kvrpcpb.APIVersion_V2},
+		{apiVersion: "v1", keyspace: "tenant_a", wantErr: "requires api version v2"},
+		{apiVersion: "v3", wantErr: "invalid api version"},
+	}
+	for _, tc := range cases {
+		opts, err := ParseClusterOptions(tc.apiVersion, tc.keyspace)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s/%s: 错误 = %v, 期望包含 %q", tc.apiVersion, tc.keyspace, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || opts.APIVersion != tc.want || opts.Keyspace != tc.keyspace {
+			t.Errorf("%s/%s: 解析结果 %+v, %v", tc.apiVersion, tc.keyspace, opts, err)
+		}
+	}
+
+	if name := (ClusterOptions{APIVersion: kvrpcpb.APIVersion_V1TTL}).APIVersionName(); name != APIVersionV1TTL {
+		t.Errorf("APIVersionName = %s, 期望 %s", name, APIVersionV1TTL)
+	}
+	if len(ClusterOptions{APIVersion: kvrpcpb.APIVersion_V1TTL}.txnOptions()) != 1 {
+		t.Errorf("V1TTL 的 Txn 客户端不应设置 keyspace")
+	}
+}
+
+// TestClientsKeyCodec 测试 region 边界按客户端的 API 版本和 keyspace 编码，以及判断 region 所属的模式
+func TestClientsKeyCodec(t *testing.T) {
+	named := newClients([]string{"pd:2379"}, nil, nil)
+	named.options = ClusterOptions{APIVersion: kvrpcpb.APIVersion_V2, Keyspace: "tenant_a"}
+	named.keyspaceID = 0x010203
+
+	encoded := named.KeyCodec("txn").EncodeRegionKey([]byte("k"))
+	if want := codec.EncodeBytes(nil, []byte("x\x01\x02\x03k")); !bytes.Equal(encoded, want) {
+		t.Errorf("keyspace 编码错误: %X, 期望 %X", encoded, want)
+	}
+	if mode := named.ModeOfRegionKey(encoded); mode != "txn" {
+		t.Errorf("ModeOfRegionKey = %q, 期望 txn", mode)
+	}
+	// 默认 keyspace 的 region 不属于当前 keyspace
+	if mode := named.ModeOfRegionKey(NewKeyCodec("txn").EncodeRegionKey([]byte("k"))); mode != "" {
+		t.Errorf("其他 keyspace 的 region 不应识别出模式: %q", mode)
+	}
+
+	v1 := newClients([]string{"pd:2379"}, nil, nil)
+	v1.options = ClusterOptions{APIVersion: kvrpcpb.APIVersion_V1TTL}
+	if encoded := v1.KeyCodec("rawkv").EncodeRegionKey([]byte("k")); string(encoded) != "k" {
+		t.Errorf("API V1TTL rawkv 不应编码: %X", encoded)
+	}
+	if mode := v1.ModeOfRegionKey(codec.EncodeBytes(nil, []byte("r\x00\x00\x00k"))); mode != "" {
+		t.Errorf("API V1 无法区分模式, 实际为 %q", mode)
+	}
+
+	var disconnected *Clients
+	if disconnected.Options() != DefaultClusterOptions() || disconnected.KeyCodec("rawkv") != NewKeyCodec("rawkv") {
+		t.Errorf("没有连接时应使用默认的 API V2 和默认 keyspace")
+	}
+}
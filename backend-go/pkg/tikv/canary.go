@@ -0,0 +1,63 @@
+package tikv
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// DefaultCanaryKey 就绪检查使用的保留 key 前缀，RawKV 检查在其后追加随机后缀，检查结束后删除
+const DefaultCanaryKey = "__tikvadmin_health__"
+
+// canaryCleanupTimeout 删除 RawKV 检查 key 的超时，不受检查本身的 context 影响
+const canaryCleanupTimeout = 5 * time.Second
+
+// RawCanary 在 canaryKey 下写入一个随机 key，读回校验内容后删除。
+// 每次检查使用不同的 key，多个实例或并发的探测之间不会互相干扰。
+// 写入或读取失败、ctx 超时时也会用新的 context 删除 key，避免检查 key 残留。
+func RawCanary(ctx context.Context, cli *rawkv.Client, canaryKey string) (err error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	key := []byte(canaryKey + "/" + hex.EncodeToString(suffix))
+	value := []byte(hex.EncodeToString(suffix))
+
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), canaryCleanupTimeout)
+		defer cancel()
+		if deleteErr := cli.Delete(cleanupCtx, key); deleteErr != nil && err == nil {
+			err = fmt.Errorf("delete: %v", deleteErr)
+		}
+	}()
+
+	if err := cli.Put(ctx, key, value); err != nil {
+		return fmt.Errorf("put: %v", err)
+	}
+	got, err := cli.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("get: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		return fmt.Errorf("read back %q, expected %q", got, value)
+	}
+	return nil
+}
+
+// TxnCanary 从最新的快照读取 canaryKey，key 不存在也算成功，检查过程不写入数据
+func (tc *TxnClient) TxnCanary(ctx context.Context, canaryKey string) error {
+	snapshot, _, err := tc.SnapshotAt(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("get snapshot: %v", err)
+	}
+	if _, err := snapshot.Get(ctx, []byte(canaryKey)); err != nil && !tikverr.IsErrNotFound(err) {
+		return fmt.Errorf("get: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package tikv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tikv-backend/pkg/logging"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+	"github.com/tikv/client-go/v2/util/codec"
+	"go.uber.org/zap"
+)
+
+// DefaultDrainTimeout 切换集群后等待旧客户端上的请求结束的最长时间，超时后强制关闭
+const DefaultDrainTimeout = 30 * time.Second
+
+// Clients 同一个集群的 RawKV 和 Txn 客户端。
+// 通过 AcquireClients 获取并增加引用计数，使用完后调用 Release；
+// 切换集群后，旧的 Clients 等到所有引用都释放后才关闭，进行中的请求不受影响。
+type Clients struct {
+	endpoints  []string
+	options    ClusterOptions
+	keyspaceID uint32
+	tuning     ClientTuning
+	raw        *rawkv.Client
+	txn        *TxnClient
+
+	refs      atomic.Int64
+	retired   atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var (
+	clientsMu      sync.RWMutex
+	currentClients *Clients
+
+	// swapMu 保证同一时间只有一次切换，切换期间 client-go 的全局配置对应正在创建的客户端
+	swapMu sync.Mutex
+)
+
+// NewClients 按 opts 指定的 API 版本和 keyspace 创建一个集群的 RawKV 和 Txn 客户端，任何一个创建失败时关闭已创建的客户端。
+// Txn 客户端的连接参数来自 client-go 的全局配置，需要先调用 tuning.applyGlobal。
+func NewClients(ctx context.Context, endpoints []string, opts ClusterOptions, tuning ClientTuning) (*Clients, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := NewRawKVClient(ctx, endpoints, opts, tuning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RawKV client: %v", err)
+	}
+	txn, err := NewTxnClient(ctx, endpoints, opts)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("failed to create TxnKV client: %v", err)
+	}
+	c := newClients(endpoints, raw, txn)
+	c.options = opts
+	c.keyspaceID = rawKeyspaceID(raw)
+	c.tuning = tuning
+	return c, nil
+}
+
+func newClients(endpoints []string, raw *rawkv.Client, txn *TxnClient) *Clients {
+	return &Clients{
+		endpoints: append([]string{}, endpoints...),
+		options:   DefaultClusterOptions(),
+		raw:       raw,
+		txn:       txn,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Endpoints 返回客户端连接的 PD 地址
+func (c *Clients) Endpoints() []string {
+	if c == nil {
+		return nil
+	}
+	return append([]string{}, c.endpoints...)
+}
+
+// Options 返回客户端使用的 API 版本和 keyspace，c 为 nil 时返回默认选项
+func (c *Clients) Options() ClusterOptions {
+	if c == nil {
+		return DefaultClusterOptions()
+	}
+	return c.options
+}
+
+// KeyspaceID 返回 API V2 下连接的 keyspace ID，API V1 或 c 为 nil 时返回默认 keyspace
+func (c *Clients) KeyspaceID() uint32 {
+	if c == nil {
+		return DefaultKeyspaceID
+	}
+	return c.keyspaceID
+}
+
+// KeyCodec 返回与客户端的 API 版本和 keyspace 一致的 region 边界编码器，mode 为 rawkv 或 txn
+func (c *Clients) KeyCodec(mode string) KeyCodec {
+	k := NewKeyCodec(mode)
+	k.APIVersion = c.Options().APIVersion
+	k.KeyspaceID = c.KeyspaceID()
+	return k
+}
+
+// ModeOfRegionKey 根据 region 边界的前缀判断属于 rawkv 还是 txn。
+// 只有 API V2 能区分，API V1 或不在当前 keyspace 内时返回空字符串。
+func (c *Clients) ModeOfRegionKey(regionKey []byte) string {
+	if c.Options().APIVersion != kvrpcpb.APIVersion_V2 {
+		return ""
+	}
+	_, decoded, err := codec.DecodeBytes(regionKey, nil)
+	if err != nil {
+		return ""
+	}
+	for _, mode := range []string{"rawkv", "txn"} {
+		if bytes.HasPrefix(decoded, c.KeyCodec(mode).keyspacePrefix()) {
+			return mode
+		}
+	}
+	return ""
+}
+
+// Tuning 返回创建客户端时使用的参数，c 为 nil 时返回零值
+func (c *Clients) Tuning() ClientTuning {
+	if c == nil {
+		return ClientTuning{}
+	}
+	return c.tuning
+}
+
+// RawKV 返回 RawKV 客户端，c 为 nil 时返回 nil
+func (c *Clients) RawKV() *rawkv.Client {
+	if c == nil {
+		return nil
+	}
+	return c.raw
+}
+
+// Txn 返回 Txn 客户端，c 为 nil 时返回 nil
+func (c *Clients) Txn() *TxnClient {
+	if c == nil {
+		return nil
+	}
+	return c.txn
+}
+
+// Verify 检查两个客户端都能正常读取：RawKV 读取保留的 key，Txn 获取 TSO 并从最新快照读取
+func (c *Clients) Verify(ctx context.Context) error {
+	if _, err := c.raw.Get(ctx, []byte(DefaultCanaryKey)); err != nil {
+		return fmt.Errorf("RawKV read failed: %v", err)
+	}
+	if err := c.txn.TxnCanary(ctx, DefaultCanaryKey); err != nil {
+		return fmt.Errorf("TxnKV read failed: %v", err)
+	}
+	return nil
+}
+
+// Release 释放 AcquireClients 获取的引用，c 为 nil 时不做任何事
+func (c *Clients) Release() {
+	if c == nil {
+		return
+	}
+	if c.refs.Add(-1) == 0 && c.retired.Load() {
+		c.close(false)
+	}
+}
+
+// retire 标记客户端不再使用，没有引用时立即关闭，否则最多等待 drainTimeout 后强制关闭。
+// 返回的 channel 在客户端关闭后关闭。
+func (c *Clients) retire(drainTimeout time.Duration) <-chan struct{} {
+	c.retired.Store(true)
+	if c.refs.Load() == 0 {
+		c.close(false)
+		return c.closed
+	}
+
+	go func() {
+		timer := time.NewTimer(drainTimeout)
+		defer timer.Stop()
+		select {
+		case <-c.closed:
+		case <-timer.C:
+			c.close(true)
+		}
+	}()
+	return c.closed
+}
+
+func (c *Clients) close(forced bool) {
+	c.closeOnce.Do(func() {
+		fields := []zap.Field{zap.String("endpoints", strings.Join(c.endpoints, ","))}
+		if forced {
+			logging.L().Warn("closing TiKV clients with requests still in flight",
+				append(fields, zap.Int64("refs", c.refs.Load()))...)
+		}
+		if c.raw != nil {
+			if err := c.raw.Close(); err != nil {
+				logging.L().Warn("failed to close RawKV client", append(fields, zap.Error(err))...)
+			}
+		}
+		if c.txn != nil {
+			if err := c.txn.cli.Close(); err != nil {
+				logging.L().Warn("failed to close TxnKV client", append(fields, zap.Error(err))...)
+			}
+		}
+		logging.L().Info("TiKV clients closed", fields...)
+		close(c.closed)
+	})
+}
+
+// AcquireClients 返回当前集群的客户端并增加引用计数，使用完后必须调用 Release。
+// 没有连接集群时返回 nil。
+func AcquireClients() *Clients {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	c := currentClients
+	if c != nil {
+		c.refs.Add(1)
+	}
+	return c
+}
+
+// SwapClients 连接新的集群：先按 opts 创建并验证新的 RawKV 和 Txn 客户端，两者都可用后才替换当前的客户端。
+// 新客户端使用 SetClientTuning 设置的参数。验证失败时关闭新客户端并返回错误，当前的客户端和参数保持不变。
+// 旧客户端在所有进行中的请求结束后关闭，最多等待 drainTimeout。
+func SwapClients(ctx context.Context, endpoints []string, opts ClusterOptions, drainTimeout time.Duration) error {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	if err := verifyPeerCN(ctx, CurrentSecurity(), endpoints); err != nil {
+		return err
+	}
+	tuning := CurrentClientTuning()
+	tuning.applyGlobal()
+	next, err := NewClients(ctx, endpoints, opts, tuning)
+	if err == nil {
+		if err = next.Verify(ctx); err != nil {
+			next.close(false)
+		}
+	}
+	if err != nil {
+		if current := peekClients(); current != nil {
+			current.tuning.applyGlobal()
+		}
+		return err
+	}
+
+	publishClients(next, drainTimeout)
+	return nil
+}
+
+// publishClients 替换当前的客户端并让旧客户端在引用释放后关闭
+func publishClients(next *Clients, drainTimeout time.Duration) <-chan struct{} {
+	clientsMu.Lock()
+	previous := currentClients
+	currentClients = next
+	clientsMu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+	return previous.retire(drainTimeout)
+}
+
+// CloseClients 关闭当前的客户端，等待进行中的请求结束，最多等待 drainTimeout
+func CloseClients(drainTimeout time.Duration) {
+	if closed := publishClients(nil, drainTimeout); closed != nil {
+		<-closed
+	}
+}
+
+// peekClients 返回当前的客户端，不增加引用计数
+func peekClients() *Clients {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	return currentClients
+}
+
+// peekTxnKV 返回当前的底层事务客户端，没有连接集群时返回 nil
+func peekTxnKV() *txnkv.Client {
+	if txn := peekClients().Txn(); txn != nil {
+		return txn.cli
+	}
+	return nil
+}
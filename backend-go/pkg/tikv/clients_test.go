@@ -0,0 +1,91 @@
+package tikv
+
+import (
+	"testing"
+	"time"
+)
+
+func isClosed(c *Clients) bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// TestClientsSwap 测试切换客户端时旧客户端等到引用全部释放后才关闭
+func TestClientsSwap(t *testing.T) {
+	defer publishClients(nil, 0)
+
+	first := newClients([]string{"pd-1:2379"}, nil, nil)
+	publishClients(first, time.Minute)
+
+	inFlight := AcquireClients()
+	if inFlight != first {
+		t.Fatalf("AcquireClients 应返回当前的客户端")
+	}
+	another := AcquireClients()
+
+	second := newClients([]string{"pd-2:2379"}, nil, nil)
+	closed := publishClients(second, time.Minute)
+
+	if got := AcquireClients(); got != second {
+		t.Fatalf("切换后应返回新的客户端")
+	} else {
+		got.Release()
+	}
+	if isClosed(first) {
+		t.Fatalf("还有请求在使用时不应关闭旧客户端")
+	}
+
+	inFlight.Release()
+	if isClosed(first) {
+		t.Fatalf("还有一个引用时不应关闭旧客户端")
+	}
+	another.Release()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("引用全部释放后应关闭旧客户端")
+	}
+	if isClosed(second) {
+		t.Errorf("新客户端不应被关闭")
+	}
+}
+
+// TestClientsDrainTimeout 测试请求一直不结束时，超过等待时间后强制关闭旧客户端
+func TestClientsDrainTimeout(t *testing.T) {
+	defer publishClients(nil, 0)
+
+	first := newClients([]string{"pd-1:2379"}, nil, nil)
+	publishClients(first, time.Minute)
+	stuck := AcquireClients()
+
+	closed := publishClients(newClients([]string{"pd-2:2379"}, nil, nil), 20*time.Millisecond)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("超过等待时间后应强制关闭旧客户端")
+	}
+	// 强制关闭后再释放引用不应重复关闭
+	stuck.Release()
+}
+
+// TestCloseClients 测试关闭当前客户端后不再返回客户端，nil 上的方法都是安全的
+func TestCloseClients(t *testing.T) {
+	publishClients(newClients([]string{"pd-1:2379"}, nil, nil), time.Minute)
+	CloseClients(time.Second)
+
+	clients := AcquireClients()
+	if clients != nil {
+		t.Fatalf("关闭后不应返回客户端")
+	}
+	clients.Release()
+	if clients.RawKV() != nil || clients.Txn() != nil || clients.Endpoints() != nil {
+		t.Errorf("nil Clients 应返回零值")
+	}
+	if peekTxnKV() != nil {
+		t.Errorf("关闭后 peekTxnKV 应返回 nil")
+	}
+}
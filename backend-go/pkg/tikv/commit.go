@@ -0,0 +1,137 @@
+package tikv
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/tracing"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/txnkv/transaction"
+	"github.com/tikv/client-go/v2/util"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 事务实际使用的提交协议
+const (
+	CommitProtocolNone        = "none"
+	CommitProtocol2PC         = "2pc"
+	CommitProtocolAsyncCommit = "async_commit"
+	CommitProtocol1PC         = "1pc"
+)
+
+// CommitOptions 事务提交选项
+type CommitOptions struct {
+	AsyncCommit bool `json:"asyncCommit"`
+	OnePC       bool `json:"onePC"`
+}
+
+// CommitResult 事务提交结果
+type CommitResult struct {
+	Protocol            string `json:"protocol"`
+	StartTS             uint64 `json:"startTs"`
+	CommitTS            uint64 `json:"commitTs"`
+	AsyncCommitFallback bool   `json:"asyncCommitFallback,omitempty"`
+	OnePCFallback       bool   `json:"onePCFallback,omitempty"`
+}
+
+var (
+	commitOptsMu      sync.RWMutex
+	defaultCommitOpts CommitOptions
+)
+
+// SetDefaultCommitOptions 设置全局默认提交选项
+func SetDefaultCommitOptions(opts CommitOptions) {
+	commitOptsMu.Lock()
+	defaultCommitOpts = opts
+	commitOptsMu.Unlock()
+}
+
+// DefaultCommitOptions 获取全局默认提交选项
+func DefaultCommitOptions() CommitOptions {
+	commitOptsMu.RLock()
+	defer commitOptsMu.RUnlock()
+	return defaultCommitOpts
+}
+
+// ResolveCommitOptions 用请求级别的设置覆盖全局默认值，nil 表示沿用默认值
+func ResolveCommitOptions(asyncCommit, onePC *bool) CommitOptions {
+	opts := DefaultCommitOptions()
+	if asyncCommit != nil {
+		opts.AsyncCommit = *asyncCommit
+	}
+	if onePC != nil {
+		opts.OnePC = *onePC
+	}
+	return opts
+}
+
+// CommitWithOptions 按提交选项提交事务，并返回实际使用的提交协议和提交时间戳
+func CommitWithOptions(ctx context.Context, txn *transaction.KVTxn, opts CommitOptions) (*CommitResult, error) {
+	txn.SetEnableAsyncCommit(opts.AsyncCommit)
+	txn.SetEnable1PC(opts.OnePC)
+
+	// client-go 只通过回调暴露提交模式，回调在 Commit 返回前同步执行
+	var info transaction.TxnInfo
+	txn.SetCommitCallback(func(infoStr string, _ error) {
+		_ = json.Unmarshal([]byte(infoStr), &info)
+	})
+
+	// client-go 把提交过程中的重试和各阶段耗时记录在 context 中的 CommitDetails 里
+	var detail *util.CommitDetails
+	commitCtx, op := tracing.StartOp(ctx, "txn", metrics.OpCommit, attribute.Int64("tikv.start_ts", int64(txn.StartTS())))
+	err := txn.Commit(context.WithValue(commitCtx, util.CommitDetailCtxKey, &detail))
+	if detail != nil {
+		op.SetAttributes(commitDetailAttributes(detail)...)
+	}
+	if err != nil {
+		if tikverr.IsErrWriteConflict(err) {
+			metrics.ObserveTxnConflict()
+		}
+		op.End(err)
+		return nil, err
+	}
+
+	result := &CommitResult{
+		Protocol: CommitProtocolNone,
+		StartTS:  txn.StartTS(),
+	}
+	// 没有写入的事务不会走提交流程，也就没有提交时间戳
+	if info.TxnCommitMode != "" {
+		result.Protocol = info.TxnCommitMode
+		result.CommitTS = info.CommitTS
+		result.AsyncCommitFallback = info.AsyncCommitFallback
+		result.OnePCFallback = info.OnePCFallback
+	}
+	op.SetAttributes(attribute.String("tikv.commit.protocol", result.Protocol), attribute.Int64("tikv.commit_ts", int64(result.CommitTS)))
+	op.End(nil)
+	return result, nil
+}
+
+// commitDetailAttributes 记录提交重试，并把重试次数和各阶段耗时转换为 span 属性
+func commitDetailAttributes(detail *util.CommitDetails) []attribute.KeyValue {
+	detail.Mu.Lock()
+	defer detail.Mu.Unlock()
+
+	metrics.ObserveTxnRetries(detail.Mu.PrewriteBackoffTypes)
+	metrics.ObserveTxnRetries(detail.Mu.CommitBackoffTypes)
+	return []attribute.KeyValue{
+		tracing.KeyCount(detail.WriteKeys),
+		attribute.Int("tikv.write_bytes", detail.WriteSize),
+		attribute.Int("tikv.retries", len(detail.Mu.PrewriteBackoffTypes)+len(detail.Mu.CommitBackoffTypes)),
+		attribute.StringSlice("tikv.backoff_types", append(append([]string{}, detail.Mu.PrewriteBackoffTypes...), detail.Mu.CommitBackoffTypes...)),
+		attribute.Float64("tikv.commit.backoff_ms", millis(time.Duration(detail.Mu.CommitBackoffTime))),
+		attribute.Float64("tikv.commit.get_commit_ts_ms", millis(detail.GetCommitTsTime)),
+		attribute.Float64("tikv.commit.prewrite_ms", millis(detail.PrewriteTime)),
+		attribute.Float64("tikv.commit.commit_ms", millis(detail.CommitTime)),
+		attribute.Float64("tikv.commit.local_latch_ms", millis(detail.LocalLatchTime)),
+		attribute.Float64("tikv.commit.resolve_lock_ms", millis(time.Duration(detail.ResolveLock.ResolveLockTime))),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
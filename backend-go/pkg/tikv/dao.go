@@ -14,12 +14,19 @@ var (
 )
 
 type RawKv struct {
-	cli *rawkv.Client
+	cli       *rawkv.Client
+	namespace []byte
 }
 
 func NewRawKv() *RawKv {
+	return NewRawKvWithNamespace(TiKVWebKeyPrefix)
+}
+
+// NewRawKvWithNamespace 使用指定的命名空间前缀，空前缀表示直接访问整个 keyspace
+func NewRawKvWithNamespace(namespace []byte) *RawKv {
 	return &RawKv{
-		cli: RawKVClient,
+		cli:       peekClients().RawKV(),
+		namespace: namespace,
 	}
 }
 
@@ -92,17 +99,43 @@ func (c *RawKv) ReverseScan(ctx context.Context, startKey, endKey []byte, limit
 	return c.cli.ReverseScan(ctx, endKey, startKey, limit)
 }
 
+// ScanRange 在命名空间内按范围扫描，返回的 key 带有命名空间前缀
+func (c *RawKv) ScanRange(ctx context.Context, r KeyRange, reverse bool, limit int) (keys [][]byte, vals [][]byte, err error) {
+	if limit <= 0 {
+		return nil, nil, nil
+	}
+
+	err = ScanRawRange(ctx, c.cli, namespaceRange(c.namespace, r), reverse, limit, func(key, val []byte) bool {
+		keys = append(keys, key)
+		vals = append(vals, val)
+		return len(keys) < limit
+	})
+	return keys, vals, err
+}
+
+// Walk 在命名空间内按顺序遍历范围，回调收到的 key 不带命名空间前缀
+func (c *RawKv) Walk(ctx context.Context, r KeyRange, fn ScanFunc) error {
+	return ScanRawRange(ctx, c.cli, namespaceRange(c.namespace, r), false, DefaultScanBatchSize, stripNamespace(c.namespace, fn))
+}
+
 func (c *RawKv) makeKey(key []byte) []byte {
-	return append(TiKVWebKeyPrefix, key...)
+	return namespaceKey(c.namespace, key)
 }
 
 type TxnKv struct {
-	cli *txnkv.Client
+	cli       *txnkv.Client
+	namespace []byte
 }
 
 func NewTxnKv() *TxnKv {
+	return NewTxnKvWithNamespace(TiKVWebKeyPrefix)
+}
+
+// NewTxnKvWithNamespace 使用指定的命名空间前缀，空前缀表示直接访问整个 keyspace
+func NewTxnKvWithNamespace(namespace []byte) *TxnKv {
 	return &TxnKv{
-		cli: TxnKVClient,
+		cli:       peekTxnKV(),
+		namespace: namespace,
 	}
 }
 
@@ -114,6 +147,11 @@ func (c *TxnKv) Commit(ctx context.Context, txn *transaction.KVTxn) error {
 	return txn.Commit(ctx)
 }
 
+// CommitWithOptions 按提交选项提交事务，返回实际使用的提交协议
+func (c *TxnKv) CommitWithOptions(ctx context.Context, txn *transaction.KVTxn, opts CommitOptions) (*CommitResult, error) {
+	return CommitWithOptions(ctx, txn, opts)
+}
+
 func (c *TxnKv) Rollback(txn *transaction.KVTxn) error {
 	return txn.Rollback()
 }
@@ -142,6 +180,65 @@ func (c *TxnKv) Delete(txn *transaction.KVTxn, key []byte) error {
 	return txn.Delete(realKey)
 }
 
+// Walk 在最新的快照上按顺序遍历命名空间内的范围，回调收到的 key 不带命名空间前缀
+func (c *TxnKv) Walk(ctx context.Context, r KeyRange, fn ScanFunc) error {
+	ts, err := c.cli.GetTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	return ScanSnapshotRange(c.cli.GetSnapshot(ts), namespaceRange(c.namespace, r), false, stripNamespace(c.namespace, fn))
+}
+
+// WalkTxn 在事务中按顺序遍历命名空间内的范围，能读到事务内尚未提交的写入，回调收到的 key 不带命名空间前缀
+func (c *TxnKv) WalkTxn(txn *transaction.KVTxn, r KeyRange, fn ScanFunc) error {
+	realRange := namespaceRange(c.namespace, r)
+	if realRange.IsEmpty() {
+		return nil
+	}
+	iter, err := txn.Iter(realRange.Start, realRange.End)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	fn = stripNamespace(c.namespace, fn)
+	for iter.Valid() {
+		if !fn(iter.Key(), iter.Value()) {
+			return nil
+		}
+		if err := iter.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *TxnKv) makeKey(key []byte) []byte {
-	return append(TiKVWebKeyPrefix, key...)
+	return namespaceKey(c.namespace, key)
+}
+
+// namespaceKey 给 key 加上命名空间前缀，每次都复制一份，避免多个 key 共用前缀的底层数组
+func namespaceKey(namespace, key []byte) []byte {
+	realKey := make([]byte, 0, len(namespace)+len(key))
+	realKey = append(realKey, namespace...)
+	return append(realKey, key...)
+}
+
+// stripNamespace 去掉扫描结果中 key 的命名空间前缀
+func stripNamespace(namespace []byte, fn ScanFunc) ScanFunc {
+	return func(key, val []byte) bool {
+		return fn(key[len(namespace):], val)
+	}
+}
+
+// namespaceRange 把命名空间内的相对范围转换为实际范围，没有上界时扫描到命名空间末尾；空命名空间没有上界
+func namespaceRange(namespace []byte, r KeyRange) KeyRange {
+	realRange := KeyRange{
+		Start: namespaceKey(namespace, r.Start),
+		End:   PrefixNext(namespace),
+	}
+	if len(r.End) > 0 {
+		realRange.End = namespaceKey(namespace, r.End)
+	}
+	return realRange
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package tikv
+
+import (
+	"context"
+
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// GetGCSafePoint 获取集群当前的 GC safe point
+func GetGCSafePoint(ctx context.Context, cli *txnkv.Client) (uint64, error) {
+	// PD 只会推进 safe point，传 0 不会修改它，只返回当前值
+	return cli.GetPDClient().UpdateGCSafePoint(ctx, 0)
+}
@@ -2,51 +2,40 @@ package tikv
 
 import (
 	"context"
-	"log"
+
+	"tikv-backend/pkg/logging"
+
+	"go.uber.org/zap"
 )
 
 // 全局变量
 var (
 	rawKvClient *RawKv
 	txnKvClient  *TxnKv
+	pdEndpoints []string
 )
 
 // InitializeTiKVClient 初始化 TiKV 客户端
 func InitializeTiKVClient(endpoints []string) error {
 	ctx := context.Background()
 
-	// 初始化 RawKV 客户端
-	log.Printf("Initializing TiKV RawKV client with endpoints: %v", endpoints)
-	_, err := NewRawKvClient(ctx, endpoints)
-	if err != nil {
+	// 创建并验证新的 RawKV 和 TxnKV 客户端后再替换
+	logging.L().Info("initializing TiKV clients", zap.Strings("endpoints", endpoints))
+	if err := SwapClients(ctx, endpoints, DefaultClusterOptions(), DefaultDrainTimeout); err != nil {
 		return err
 	}
 	rawKvClient = NewRawKv()
-	log.Println("RawKV client initialized successfully")
-
-	// 初始化 TxnKV 客户端
-	log.Printf("Initializing TiKV TxnKV client with endpoints: %v", endpoints)
-	_, err = NewTxnClient(ctx, endpoints)
-	if err != nil {
-		return err
-	}
 	txnKvClient = NewTxnKv()
-	log.Println("TxnKV client initialized successfully")
+	logging.L().Info("TiKV clients initialized")
+
+	pdEndpoints = append([]string{}, endpoints...)
 
 	return nil
 }
 
 // CloseTiKVClient 关闭 TiKV 客户端
 func CloseTiKVClient() {
-	if RawKVClient != nil {
-		RawKVClient.Close()
-		log.Println("RawKV client closed")
-	}
-
-	if TxnKVClient != nil {
-		TxnKVClient.Close()
-		log.Println("TxnKV client closed")
-	}
+	CloseClients(DefaultDrainTimeout)
 }
 
 // GetRawKvClient 获取 RawKV 客户端
@@ -59,7 +48,17 @@ func GetTxnKvClient() *TxnKv {
 	return txnKvClient
 }
 
+// GetPDEndpoints 获取当前连接的 PD 地址
+func GetPDEndpoints() []string {
+	return append([]string{}, pdEndpoints...)
+}
+
+// GetClusterOptions 获取当前连接集群的 API 版本和 keyspace，没有连接时返回默认选项
+func GetClusterOptions() ClusterOptions {
+	return peekClients().Options()
+}
+
 // IsConnected 检查是否已连接
 func IsConnected() bool {
-	return rawKvClient != nil && txnKvClient != nil && RawKVClient != nil && TxnKVClient != nil
+	return rawKvClient != nil && txnKvClient != nil && peekClients() != nil
 }
\ No newline at end of file
@@ -0,0 +1,106 @@
+package tikv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/util/codec"
+)
+
+// DefaultKeyspaceID 默认 keyspace
+const DefaultKeyspaceID uint32 = 0
+
+// API V2 下 key 的模式前缀，后面紧跟 3 字节的 keyspace ID
+const (
+	apiV2RawModePrefix byte = 'r'
+	apiV2TxnModePrefix byte = 'x'
+)
+
+// KeyCodec 把用户 key 转换为 PD region 边界使用的格式。
+// API V2 下所有 key 都带有模式和 keyspace 前缀并做 memcomparable 编码；
+// API V1 下只有事务模式的 key 做 memcomparable 编码。
+type KeyCodec struct {
+	APIVersion kvrpcpb.APIVersion
+	Mode       string
+	KeyspaceID uint32
+}
+
+// NewKeyCodec 创建 API V2 默认 keyspace 的 key 编码器，mode 为 rawkv 或 txn。
+// 与已连接集群一致的编码器通过 Clients.KeyCodec 获取。
+func NewKeyCodec(mode string) KeyCodec {
+	return KeyCodec{
+		APIVersion: kvrpcpb.APIVersion_V2,
+		Mode:       mode,
+		KeyspaceID: DefaultKeyspaceID,
+	}
+}
+
+// keyspacePrefix 返回 API V2 下的 key 前缀，API V1 没有前缀
+func (k KeyCodec) keyspacePrefix() []byte {
+	if k.APIVersion != kvrpcpb.APIVersion_V2 {
+		return nil
+	}
+	mode := apiV2RawModePrefix
+	if k.Mode == "txn" {
+		mode = apiV2TxnModePrefix
+	}
+	return []byte{mode, byte(k.KeyspaceID >> 16), byte(k.KeyspaceID >> 8), byte(k.KeyspaceID)}
+}
+
+func (k KeyCodec) memComparable() bool {
+	return k.APIVersion == kvrpcpb.APIVersion_V2 || k.Mode == "txn"
+}
+
+func (k KeyCodec) encode(key []byte) []byte {
+	if k.memComparable() {
+		return codec.EncodeBytes(nil, key)
+	}
+	return key
+}
+
+// EncodeRegionKey 编码单个用户 key
+func (k KeyCodec) EncodeRegionKey(key []byte) []byte {
+	return k.encode(append(k.keyspacePrefix(), key...))
+}
+
+// EncodeRegionRange 编码用户 key 范围，End 为空时 API V2 下编码为 keyspace 的末尾
+func (k KeyCodec) EncodeRegionRange(r KeyRange) KeyRange {
+	prefix := k.keyspacePrefix()
+	encoded := KeyRange{Start: k.encode(append(append([]byte{}, prefix...), r.Start...))}
+	switch {
+	case len(r.End) > 0:
+		encoded.End = k.encode(append(append([]byte{}, prefix...), r.End...))
+	case len(prefix) > 0:
+		encoded.End = k.encode(PrefixNext(prefix))
+	}
+	return encoded
+}
+
+// DecodeRegionKey 把 region 边界解码为用户 key。
+// region 可能跨越 keyspace 的边界，落在 keyspace 之前的边界返回空 key（表示 keyspace 的开头），
+// 落在 keyspace 之后的边界返回 nil 和 beyond=true（表示没有上界）。
+func (k KeyCodec) DecodeRegionKey(regionKey []byte) (key []byte, beyond bool, err error) {
+	if len(regionKey) == 0 {
+		return nil, false, nil
+	}
+
+	decoded := regionKey
+	if k.memComparable() {
+		if _, decoded, err = codec.DecodeBytes(regionKey, nil); err != nil {
+			return nil, false, fmt.Errorf("failed to decode region key %X: %v", regionKey, err)
+		}
+	}
+
+	prefix := k.keyspacePrefix()
+	if len(prefix) == 0 {
+		return decoded, false, nil
+	}
+	if bytes.Compare(decoded, prefix) < 0 {
+		return []byte{}, false, nil
+	}
+	if !bytes.HasPrefix(decoded, prefix) {
+		return nil, true, nil
+	}
+	return decoded[len(prefix):], false, nil
+}
@@ -0,0 +1,46 @@
+package tikv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/util/codec"
+)
+
+// TestKeyCodec 测试 API V2 下 region 边界的编码和解码
+func TestKeyCodec(t *testing.T) {
+	raw := NewKeyCodec("rawkv")
+	txn := NewKeyCodec("txn")
+
+	encoded := raw.EncodeRegionKey([]byte("user_1"))
+	if want := codec.EncodeBytes(nil, []byte("r\x00\x00\x00user_1")); !bytes.Equal(encoded, want) {
+		t.Errorf("rawkv 编码错误: %X, 期望 %X", encoded, want)
+	}
+	if key, beyond, err := raw.DecodeRegionKey(encoded); err != nil || beyond || string(key) != "user_1" {
+		t.Errorf("rawkv 解码错误: %q, %v, %v", key, beyond, err)
+	}
+
+	// 没有上界时编码为 keyspace 的末尾，txn 和 rawkv 的前缀不同
+	r := txn.EncodeRegionRange(KeyRange{Start: []byte("a")})
+	if want := codec.EncodeBytes(nil, []byte("x\x00\x00\x01")); !bytes.Equal(r.End, want) {
+		t.Errorf("txn 范围上界错误: %X, 期望 %X", r.End, want)
+	}
+
+	// region 跨越 keyspace 边界时，起点落在 keyspace 之前、终点落在 keyspace 之后
+	if key, beyond, err := raw.DecodeRegionKey(codec.EncodeBytes(nil, []byte("m"))); err != nil || beyond || key == nil || len(key) != 0 {
+		t.Errorf("keyspace 之前的边界应解码为空 key: %q, %v, %v", key, beyond, err)
+	}
+	if key, beyond, err := raw.DecodeRegionKey(codec.EncodeBytes(nil, []byte("x\x00\x00\x00"))); err != nil || !beyond || key != nil {
+		t.Errorf("keyspace 之后的边界应标记为 beyond: %q, %v, %v", key, beyond, err)
+	}
+
+	// API V1 下 rawkv 的 region 边界不做编码
+	v1 := KeyCodec{APIVersion: kvrpcpb.APIVersion_V1, Mode: "rawkv"}
+	if encoded := v1.EncodeRegionKey([]byte("k")); string(encoded) != "k" {
+		t.Errorf("API V1 rawkv 不应编码: %X", encoded)
+	}
+	if r := v1.EncodeRegionRange(KeyRange{Start: []byte("k")}); r.End != nil {
+		t.Errorf("API V1 没有上界时应保持为空: %X", r.End)
+	}
+}
@@ -0,0 +1,296 @@
+package tikv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/tracing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/oracle"
+	tikvstore "github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+	"github.com/tikv/client-go/v2/txnkv"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultLockScanLimit 扫描锁时默认最多返回的数量
+	DefaultLockScanLimit = 256
+	// lockRPCMaxBackoff 扫描锁和查询事务状态的最大重试时间（毫秒）
+	lockRPCMaxBackoff = 20000
+)
+
+// 事务状态
+const (
+	TxnStateLocked     = "locked"
+	TxnStateCommitted  = "committed"
+	TxnStateRolledBack = "rolled_back"
+	TxnStateNotFound   = "not_found"
+)
+
+// 锁处理结果
+const (
+	LockResolved     = "resolved"
+	LockStillLocked  = "still_locked"
+	LockNotFound     = "not_found"
+	LockResolveError = "error"
+)
+
+// TxnStatusInfo 锁对应事务的状态，由 primary key 上的锁决定
+type TxnStatusInfo struct {
+	State    string `json:"state"`
+	CommitTS uint64 `json:"commitTs,omitempty"`
+	TTL      uint64 `json:"ttl,omitempty"`
+}
+
+// LockInfo Percolator 锁
+type LockInfo struct {
+	Key            string `json:"key"`
+	Primary        string `json:"primary"`
+	StartTS        uint64 `json:"startTs"`
+	TTL            uint64 `json:"ttl"`
+	LockType       string `json:"lockType"`
+	TxnSize        uint64 `json:"txnSize"`
+	UseAsyncCommit bool   `json:"useAsyncCommit"`
+	MinCommitTS    uint64 `json:"minCommitTs,omitempty"`
+	// Expired 按 PD 当前时间计算锁的 TTL 是否已经过期
+	Expired bool           `json:"expired"`
+	Status  *TxnStatusInfo `json:"status,omitempty"`
+
+	lock *txnkv.Lock
+}
+
+// LockResolveResult 单个锁的处理结果
+type LockResolveResult struct {
+	Key     string `json:"key"`
+	StartTS uint64 `json:"startTs"`
+	Result  string `json:"result"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newLockInfo(l *kvrpcpb.LockInfo, currentTS uint64) LockInfo {
+	// TTL 以毫秒为单位，从事务的 start ts 开始计算
+	expireAt := oracle.ExtractPhysical(l.GetLockVersion()) + int64(l.GetLockTtl())
+	return LockInfo{
+		Key:            string(l.GetKey()),
+		Primary:        string(l.GetPrimaryLock()),
+		StartTS:        l.GetLockVersion(),
+		TTL:            l.GetLockTtl(),
+		LockType:       l.GetLockType().String(),
+		TxnSize:        l.GetTxnSize(),
+		UseAsyncCommit: l.GetUseAsyncCommit(),
+		MinCommitTS:    l.GetMinCommitTs(),
+		Expired:        oracle.ExtractPhysical(currentTS) >= expireAt,
+		lock:           txnkv.NewLock(l),
+	}
+}
+
+// ScanLocks 扫描范围内 start ts 不大于当前时间戳的锁，最多返回 limit 个。
+// 结果被截断时返回下一次扫描的起始 key，否则返回 nil。
+func (tc *TxnClient) ScanLocks(ctx context.Context, r KeyRange, limit int) ([]LockInfo, []byte, error) {
+	ctx, op := tracing.StartOp(ctx, "txn", metrics.OpScanLock, tracing.Range(r.Start, r.End)...)
+	locks, next, err := tc.scanLocks(ctx, r, limit)
+	op.SetAttributes(tracing.KeyCount(len(locks)))
+	op.End(err)
+	return locks, next, err
+}
+
+func (tc *TxnClient) scanLocks(ctx context.Context, r KeyRange, limit int) ([]LockInfo, []byte, error) {
+	if limit <= 0 {
+		limit = DefaultLockScanLimit
+	}
+	currentTS, err := tc.cli.GetTimestamp(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var locks []LockInfo
+	key := r.Start
+	bo := tikvstore.NewBackoffer(ctx, lockRPCMaxBackoff)
+	for !r.IsEmpty() && len(locks) < limit {
+		loc, err := tc.cli.GetRegionCache().LocateKey(bo, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		end := loc.EndKey
+		if len(r.End) > 0 && (len(end) == 0 || bytes.Compare(r.End, end) < 0) {
+			end = r.End
+		}
+
+		batch := limit - len(locks)
+		req := tikvrpc.NewRequest(tikvrpc.CmdScanLock, &kvrpcpb.ScanLockRequest{
+			MaxVersion: currentTS,
+			StartKey:   key,
+			EndKey:     end,
+			Limit:      uint32(batch),
+		})
+		resp, err := tc.cli.SendReq(bo, req, loc.Region, tikvstore.ReadTimeoutMedium)
+		if err != nil {
+			return nil, nil, err
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return nil, nil, err
+		}
+		if regionErr != nil {
+			if err := bo.Backoff(tikvstore.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if resp.Resp == nil {
+			return nil, nil, tikverr.ErrBodyMissing
+		}
+		scanResp := resp.Resp.(*kvrpcpb.ScanLockResponse)
+		if scanResp.GetError() != nil {
+			return nil, nil, fmt.Errorf("scan lock error: %s", scanResp.GetError())
+		}
+
+		for _, l := range scanResp.GetLocks() {
+			locks = append(locks, newLockInfo(l, currentTS))
+		}
+		if len(scanResp.GetLocks()) >= batch {
+			key = KeyAfter(scanResp.GetLocks()[len(scanResp.GetLocks())-1].GetKey())
+		} else {
+			key = end
+		}
+		if len(key) == 0 || (len(r.End) > 0 && bytes.Compare(key, r.End) >= 0) {
+			return locks, nil, nil
+		}
+	}
+	if r.IsEmpty() {
+		return locks, nil, nil
+	}
+	return locks, key, nil
+}
+
+// CheckTxnStatus 查询 primary key 上事务的状态。
+// current ts 传 0，锁不会被判定为过期，因此只查询状态，不会回滚事务或推高 min commit ts。
+func (tc *TxnClient) CheckTxnStatus(ctx context.Context, primary []byte, startTS uint64) (*TxnStatusInfo, error) {
+	bo := tikvstore.NewBackoffer(ctx, lockRPCMaxBackoff)
+	for {
+		loc, err := tc.cli.GetRegionCache().LocateKey(bo, primary)
+		if err != nil {
+			return nil, err
+		}
+		req := tikvrpc.NewRequest(tikvrpc.CmdCheckTxnStatus, &kvrpcpb.CheckTxnStatusRequest{
+			PrimaryKey:         primary,
+			LockTs:             startTS,
+			CallerStartTs:      0,
+			CurrentTs:          0,
+			RollbackIfNotExist: false,
+		})
+		resp, err := tc.cli.SendReq(bo, req, loc.Region, tikvstore.ReadTimeoutShort)
+		if err != nil {
+			return nil, err
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return nil, err
+		}
+		if regionErr != nil {
+			if err := bo.Backoff(tikvstore.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.Resp == nil {
+			return nil, tikverr.ErrBodyMissing
+		}
+
+		statusResp := resp.Resp.(*kvrpcpb.CheckTxnStatusResponse)
+		if keyErr := statusResp.GetError(); keyErr != nil {
+			if keyErr.GetTxnNotFound() != nil {
+				return &TxnStatusInfo{State: TxnStateNotFound}, nil
+			}
+			return nil, fmt.Errorf("check txn status error: %s", keyErr)
+		}
+		switch {
+		case statusResp.GetLockTtl() > 0:
+			return &TxnStatusInfo{State: TxnStateLocked, TTL: statusResp.GetLockTtl()}, nil
+		case statusResp.GetCommitVersion() > 0:
+			return &TxnStatusInfo{State: TxnStateCommitted, CommitTS: statusResp.GetCommitVersion()}, nil
+		default:
+			return &TxnStatusInfo{State: TxnStateRolledBack}, nil
+		}
+	}
+}
+
+// FillTxnStatus 为每个锁查询事务状态，同一个事务只查询一次
+func (tc *TxnClient) FillTxnStatus(ctx context.Context, locks []LockInfo) error {
+	type txnKey struct {
+		primary string
+		startTS uint64
+	}
+	statuses := make(map[txnKey]*TxnStatusInfo)
+	for i := range locks {
+		k := txnKey{primary: locks[i].Primary, startTS: locks[i].StartTS}
+		status, ok := statuses[k]
+		if !ok {
+			var err error
+			status, err = tc.CheckTxnStatus(ctx, []byte(k.primary), k.startTS)
+			if err != nil {
+				return err
+			}
+			statuses[k] = status
+		}
+		locks[i].Status = status
+	}
+	return nil
+}
+
+// ResolveLocks 处理已经过期的锁：根据 primary 的状态提交或回滚对应的事务，TTL 未过期的锁保持不变。
+// 传入的 key 会重新读取当前的锁，只处理 start ts 一致的锁，避免误处理新的事务。
+func (tc *TxnClient) ResolveLocks(ctx context.Context, keys [][]byte, startTSs []uint64) ([]LockResolveResult, error) {
+	currentTS, err := tc.cli.GetTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]LockResolveResult, len(keys))
+	for i, key := range keys {
+		results[i] = LockResolveResult{Key: string(key), StartTS: startTSs[i]}
+		lock, err := tc.GetLock(ctx, key, startTSs[i])
+		if err != nil {
+			results[i].Result, results[i].Error = LockResolveError, err.Error()
+			continue
+		}
+		if lock == nil {
+			results[i].Result = LockNotFound
+			continue
+		}
+
+		opCtx, op := tracing.StartOp(ctx, "txn", metrics.OpResolveLock, tracing.Key("tikv.key", key), attribute.Int64("tikv.start_ts", int64(startTSs[i])))
+		bo := tikvstore.NewBackoffer(opCtx, lockRPCMaxBackoff)
+		msBeforeExpired, err := tc.cli.GetLockResolver().ResolveLocks(bo, currentTS, []*txnkv.Lock{lock.lock})
+		op.End(err)
+		switch {
+		case err != nil:
+			results[i].Result, results[i].Error = LockResolveError, err.Error()
+		case msBeforeExpired > 0:
+			results[i].Result = LockStillLocked
+		default:
+			results[i].Result = LockResolved
+		}
+	}
+	return results, nil
+}
+
+// GetLock 读取 key 上 start ts 为 startTS 的锁，不存在时返回 nil
+func (tc *TxnClient) GetLock(ctx context.Context, key []byte, startTS uint64) (*LockInfo, error) {
+	locks, _, err := tc.ScanLocks(ctx, KeyRange{Start: key, End: KeyAfter(key)}, 1)
+	if err != nil {
+		return nil, err
+	}
+	for i := range locks {
+		if locks[i].Key == string(key) && locks[i].StartTS == startTS {
+			return &locks[i], nil
+		}
+	}
+	return nil, nil
+}
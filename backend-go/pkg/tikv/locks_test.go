@@ -0,0 +1,33 @@
+package tikv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// TestNewLockInfo 测试锁信息的转换和 TTL 过期判断
+func TestNewLockInfo(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := &kvrpcpb.LockInfo{
+		Key:         []byte("order_2"),
+		PrimaryLock: []byte("order_1"),
+		LockVersion: oracle.GoTimeToTS(start),
+		LockTtl:     3000,
+		LockType:    kvrpcpb.Op_Put,
+	}
+
+	lock := newLockInfo(l, oracle.GoTimeToTS(start.Add(2*time.Second)))
+	if lock.Key != "order_2" || lock.Primary != "order_1" || lock.LockType != "Put" || lock.TTL != 3000 {
+		t.Errorf("锁信息转换错误: %+v", lock)
+	}
+	if lock.Expired {
+		t.Error("TTL 内的锁不应过期")
+	}
+
+	if lock := newLockInfo(l, oracle.GoTimeToTS(start.Add(4*time.Second))); !lock.Expired {
+		t.Error("超过 TTL 的锁应过期")
+	}
+}
@@ -0,0 +1,175 @@
+package tikv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/tracing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	tikverr "github.com/tikv/client-go/v2/error"
+	tikvstore "github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+)
+
+// MvccWriteInfo key 的一条提交记录（write CF），Type 为 Put、Del、Lock 或 Rollback
+type MvccWriteInfo struct {
+	Type     string `json:"type"`
+	StartTS  uint64 `json:"startTs"`
+	CommitTS uint64 `json:"commitTs"`
+	// Value 为 Put 写入的值，HasValue 为 false 表示值已不可读（例如已被 GC）
+	Value    string `json:"value,omitempty"`
+	HasValue bool   `json:"hasValue"`
+	// Visible 在 ReadTS 读取时返回的版本，类型为 Del 时表示 key 不存在
+	Visible bool `json:"visible"`
+	// BelowSafePoint commit ts 不大于 GC safe point，不能再用 snapshot 读取这一时刻之前的版本
+	BelowSafePoint bool `json:"belowSafePoint"`
+}
+
+// MvccLockInfo key 上尚未提交的锁（lock CF）
+type MvccLockInfo struct {
+	Type           string   `json:"type"`
+	StartTS        uint64   `json:"startTs"`
+	Primary        string   `json:"primary"`
+	TTL            uint64   `json:"ttl"`
+	ForUpdateTS    uint64   `json:"forUpdateTs,omitempty"`
+	TxnSize        uint64   `json:"txnSize"`
+	UseAsyncCommit bool     `json:"useAsyncCommit"`
+	Value          string   `json:"value,omitempty"`
+	RollbackTS     []uint64 `json:"rollbackTs,omitempty"`
+}
+
+// MvccHistory 单个 key 在 TiKV 中保存的所有 MVCC 版本，Writes 按 commit ts 从新到旧排列
+type MvccHistory struct {
+	Key       string          `json:"key"`
+	Lock      *MvccLockInfo   `json:"lock,omitempty"`
+	Writes    []MvccWriteInfo `json:"writes"`
+	ReadTS    TSOInfo         `json:"readTs"`
+	SafePoint TSOInfo         `json:"safePoint"`
+}
+
+// newMvccHistory 转换 TiKV 返回的 MVCC 信息，并按 readTS 和 safePoint 标记可见性
+func newMvccHistory(key []byte, info *kvrpcpb.MvccInfo, readTS, safePoint uint64) *MvccHistory {
+	history := &MvccHistory{
+		Key:       string(key),
+		Writes:    []MvccWriteInfo{},
+		ReadTS:    NewTSOInfo(readTS),
+		SafePoint: NewTSOInfo(safePoint),
+	}
+	if info == nil {
+		return history
+	}
+
+	// 长值单独保存在 default CF 中，以 start ts 关联
+	values := make(map[uint64][]byte, len(info.GetValues()))
+	for _, v := range info.GetValues() {
+		values[v.GetStartTs()] = v.GetValue()
+	}
+	valueOf := func(shortValue []byte, startTS uint64) ([]byte, bool) {
+		if len(shortValue) > 0 {
+			return shortValue, true
+		}
+		v, ok := values[startTS]
+		return v, ok
+	}
+
+	if l := info.GetLock(); l != nil {
+		lock := &MvccLockInfo{
+			Type:           l.GetType().String(),
+			StartTS:        l.GetStartTs(),
+			Primary:        string(l.GetPrimary()),
+			TTL:            l.GetTtl(),
+			ForUpdateTS:    l.GetForUpdateTs(),
+			TxnSize:        l.GetTxnSize(),
+			UseAsyncCommit: l.GetUseAsyncCommit(),
+			RollbackTS:     l.GetRollbackTs(),
+		}
+		if l.GetType() == kvrpcpb.Op_Put {
+			v, _ := valueOf(l.GetShortValue(), l.GetStartTs())
+			lock.Value = string(v)
+		}
+		history.Lock = lock
+	}
+
+	for _, w := range info.GetWrites() {
+		write := MvccWriteInfo{
+			Type:           w.GetType().String(),
+			StartTS:        w.GetStartTs(),
+			CommitTS:       w.GetCommitTs(),
+			BelowSafePoint: w.GetCommitTs() <= safePoint,
+		}
+		if w.GetType() == kvrpcpb.Op_Put {
+			v, ok := valueOf(w.GetShortValue(), w.GetStartTs())
+			write.Value, write.HasValue = string(v), ok
+		}
+		history.Writes = append(history.Writes, write)
+	}
+	sort.SliceStable(history.Writes, func(i, j int) bool {
+		return history.Writes[i].CommitTS > history.Writes[j].CommitTS
+	})
+
+	// 读取时跳过 Lock 和 Rollback 记录，第一条 commit ts 不大于 readTS 的 Put 或 Del 即为可见版本
+	for i := range history.Writes {
+		w := &history.Writes[i]
+		if w.CommitTS > readTS || (w.Type != kvrpcpb.Op_Put.String() && w.Type != kvrpcpb.Op_Del.String()) {
+			continue
+		}
+		w.Visible = true
+		break
+	}
+	return history
+}
+
+// MvccHistory 读取 key 的所有 MVCC 版本（包括未提交的锁），并按当前时间戳和 GC safe point 标记可见性
+func (tc *TxnClient) MvccHistory(ctx context.Context, key []byte) (*MvccHistory, error) {
+	ctx, op := tracing.StartOp(ctx, "txn", metrics.OpMvcc, tracing.Key("tikv.key", key))
+	history, err := tc.mvccHistory(ctx, key)
+	op.End(err)
+	return history, err
+}
+
+func (tc *TxnClient) mvccHistory(ctx context.Context, key []byte) (*MvccHistory, error) {
+	readTS, err := tc.cli.GetTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	safePoint, err := GetGCSafePoint(ctx, tc.cli)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GC safe point: %v", err)
+	}
+
+	bo := tikvstore.NewBackoffer(ctx, lockRPCMaxBackoff)
+	for {
+		loc, err := tc.cli.GetRegionCache().LocateKey(bo, key)
+		if err != nil {
+			return nil, err
+		}
+		req := tikvrpc.NewRequest(tikvrpc.CmdMvccGetByKey, &kvrpcpb.MvccGetByKeyRequest{Key: key})
+		resp, err := tc.cli.SendReq(bo, req, loc.Region, tikvstore.ReadTimeoutShort)
+		if err != nil {
+			return nil, err
+		}
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return nil, err
+		}
+		if regionErr != nil {
+			if err := bo.Backoff(tikvstore.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.Resp == nil {
+			return nil, tikverr.ErrBodyMissing
+		}
+
+		mvccResp := resp.Resp.(*kvrpcpb.MvccGetByKeyResponse)
+		if mvccResp.GetError() != "" {
+			return nil, fmt.Errorf("mvcc get by key error: %s", mvccResp.GetError())
+		}
+		return newMvccHistory(key, mvccResp.GetInfo(), readTS, safePoint), nil
+	}
+}
@@ -0,0 +1,60 @@
+package tikv
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+)
+
+// TestNewMvccHistory 测试 MVCC 版本的转换、长值关联和可见性标记
+func TestNewMvccHistory(t *testing.T) {
+	info := &kvrpcpb.MvccInfo{
+		Lock: &kvrpcpb.MvccLock{Type: kvrpcpb.Op_Put, StartTs: 60, Primary: []byte("k"), ShortValue: []byte("v4")},
+		Writes: []*kvrpcpb.MvccWrite{
+			{Type: kvrpcpb.Op_Put, StartTs: 10, CommitTs: 11, ShortValue: []byte("v1")},
+			{Type: kvrpcpb.Op_Lock, StartTs: 40, CommitTs: 41},
+			{Type: kvrpcpb.Op_Put, StartTs: 20, CommitTs: 21},
+			{Type: kvrpcpb.Op_Del, StartTs: 50, CommitTs: 55},
+		},
+		Values: []*kvrpcpb.MvccValue{{StartTs: 20, Value: []byte("long value")}},
+	}
+
+	h := newMvccHistory([]byte("k"), info, 45, 15)
+	if h.Lock == nil || h.Lock.StartTS != 60 || h.Lock.Value != "v4" {
+		t.Errorf("锁信息错误: %+v", h.Lock)
+	}
+	if h.ReadTS.TS != 45 || h.SafePoint.TS != 15 {
+		t.Errorf("时间戳错误: %+v %+v", h.ReadTS, h.SafePoint)
+	}
+
+	want := []struct {
+		commitTS       uint64
+		value          string
+		visible        bool
+		belowSafePoint bool
+	}{
+		{commitTS: 55},
+		{commitTS: 41},
+		{commitTS: 21, value: "long value", visible: true},
+		{commitTS: 11, value: "v1", belowSafePoint: true},
+	}
+	if len(h.Writes) != len(want) {
+		t.Fatalf("版本数量错误: %d", len(h.Writes))
+	}
+	for i, w := range want {
+		got := h.Writes[i]
+		if got.CommitTS != w.commitTS || got.Value != w.value || got.Visible != w.visible || got.BelowSafePoint != w.belowSafePoint {
+			t.Errorf("第 %d 个版本错误: %+v", i, got)
+		}
+	}
+
+	// 最新的可见版本是删除记录
+	h = newMvccHistory([]byte("k"), info, 100, 0)
+	if !h.Writes[0].Visible || h.Writes[0].Type != "Del" {
+		t.Errorf("删除记录应可见: %+v", h.Writes[0])
+	}
+
+	if h := newMvccHistory([]byte("missing"), nil, 1, 0); h.Lock != nil || len(h.Writes) != 0 {
+		t.Errorf("不存在的 key 应没有版本: %+v", h)
+	}
+}
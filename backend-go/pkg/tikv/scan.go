@@ -0,0 +1,194 @@
+package tikv
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// DefaultScanBatchSize 分批扫描时每批的大小
+const DefaultScanBatchSize = 256
+
+// reverseScanProbe 范围没有上界时反向扫描第一次尝试的上界
+var reverseScanProbe = bytes.Repeat([]byte{0xFF}, 16)
+
+// KeyRange 键范围 [Start, End)，Start 为空表示从头开始，End 为空表示没有上界
+type KeyRange struct {
+	Start []byte
+	End   []byte
+}
+
+// PrefixNext 返回大于所有以 key 为前缀的键的最小键。
+// 末尾的 0xFF 会进位，例如 "a\xFF" 的结果是 "b"；key 全部为 0xFF（或为空）时返回 nil，表示没有上界。
+func PrefixNext(key []byte) []byte {
+	next := append([]byte{}, key...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next[:i+1]
+		}
+	}
+	return nil
+}
+
+// KeyAfter 返回紧跟在 key 之后的键，用于把包含边界转换为不包含边界
+func KeyAfter(key []byte) []byte {
+	return append(append([]byte{}, key...), 0x00)
+}
+
+// PrefixRange 返回前缀对应的键范围
+func PrefixRange(prefix []byte) KeyRange {
+	return KeyRange{
+		Start: prefix,
+		End:   PrefixNext(prefix),
+	}
+}
+
+// Intersect 返回两个范围的交集
+func (r KeyRange) Intersect(other KeyRange) KeyRange {
+	result := r
+	if bytes.Compare(other.Start, result.Start) > 0 {
+		result.Start = other.Start
+	}
+	if len(other.End) > 0 && (len(result.End) == 0 || bytes.Compare(other.End, result.End) < 0) {
+		result.End = other.End
+	}
+	return result
+}
+
+// IsEmpty 判断范围内是否不可能有键
+func (r KeyRange) IsEmpty() bool {
+	return len(r.End) > 0 && bytes.Compare(r.Start, r.End) >= 0
+}
+
+// ScanFunc 扫描回调，返回 false 时停止扫描
+type ScanFunc func(key, value []byte) bool
+
+// ScanRawRange 分批扫描 RawKV 范围，reverse 为 true 时从上界向下界扫描
+func ScanRawRange(ctx context.Context, cli *rawkv.Client, r KeyRange, reverse bool, batchSize int, fn ScanFunc, options ...rawkv.RawOption) error {
+	if r.IsEmpty() {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultScanBatchSize
+	}
+
+	if !reverse {
+		start := r.Start
+		for {
+			keys, values, err := cli.Scan(ctx, start, r.End, batchSize, options...)
+			if err != nil {
+				return err
+			}
+			for i := range keys {
+				if !fn(keys[i], values[i]) {
+					return nil
+				}
+			}
+			if len(keys) < batchSize {
+				return nil
+			}
+			start = KeyAfter(keys[len(keys)-1])
+		}
+	}
+
+	upper, err := reverseUpperBound(r, func(start []byte) (bool, error) {
+		keys, _, err := cli.Scan(ctx, start, nil, 1, append(options[:len(options):len(options)], rawkv.ScanKeyOnly())...)
+		return len(keys) > 0, err
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		// ReverseScan 的范围是 [lower, upper)，上一批最后一个 key 作为新的上界正好被排除
+		keys, values, err := cli.ReverseScan(ctx, upper, r.Start, batchSize, options...)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			if !fn(keys[i], values[i]) {
+				return nil
+			}
+		}
+		if len(keys) < batchSize {
+			return nil
+		}
+		upper = keys[len(keys)-1]
+	}
+}
+
+// ScanSnapshotRange 在事务快照上扫描范围，reverse 为 true 时从上界向下界扫描
+func ScanSnapshotRange(snapshot *txnkv.KVSnapshot, r KeyRange, reverse bool, fn ScanFunc) error {
+	if r.IsEmpty() {
+		return nil
+	}
+
+	if !reverse {
+		iter, err := snapshot.Iter(r.Start, r.End)
+		if err != nil {
+			return err
+		}
+		defer iter.Close()
+
+		for iter.Valid() {
+			if !fn(iter.Key(), iter.Value()) {
+				return nil
+			}
+			if err := iter.Next(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	upper, err := reverseUpperBound(r, func(start []byte) (bool, error) {
+		iter, err := snapshot.Iter(start, nil)
+		if err != nil {
+			return false, err
+		}
+		defer iter.Close()
+		return iter.Valid(), nil
+	})
+	if err != nil {
+		return err
+	}
+	// IterReverse 返回小于 upper 的键，下界需要自己判断
+	iter, err := snapshot.IterReverse(upper)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.Valid() {
+		if bytes.Compare(iter.Key(), r.Start) < 0 {
+			return nil
+		}
+		if !fn(iter.Key(), iter.Value()) {
+			return nil
+		}
+		if err := iter.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseUpperBound 返回反向扫描使用的上界。
+// RawKV 的 ReverseScan 和快照的 IterReverse 都不能从 keyspace 的末尾开始（client-go 会把空 key 定位到 keyspace 的开头），
+// 因此范围没有上界时先取 16 个 0xFF，用一次正向扫描确认没有不小于它的 key；有的话把长度加倍再确认，
+// 以任意多个 0xFF 开头的 key 也不会被跳过。hasKeyFrom 判断 [start, 末尾) 中是否有 key。
+func reverseUpperBound(r KeyRange, hasKeyFrom func(start []byte) (bool, error)) ([]byte, error) {
+	if len(r.End) > 0 {
+		return r.End, nil
+	}
+	upper := reverseScanProbe
+	for {
+		found, err := hasKeyFrom(upper)
+		if err != nil || !found {
+			return upper, err
+		}
+		upper = bytes.Repeat([]byte{0xFF}, 2*len(upper))
+	}
+}
@@ -0,0 +1,86 @@
+package tikv
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestPrefixNext 测试前缀上界的计算，尤其是末尾为 0xFF 的情况
+func TestPrefixNext(t *testing.T) {
+	cases := []struct {
+		prefix []byte
+		want   []byte
+	}{
+		{prefix: []byte("order_"), want: []byte("order`")},
+		{prefix: []byte("a\xFF"), want: []byte("b")},
+		{prefix: []byte("a\xFF\xFF"), want: []byte("b")},
+		{prefix: []byte{0x01, 0xFE}, want: []byte{0x01, 0xFF}},
+		{prefix: []byte{0xFF, 0xFF}, want: nil},
+		{prefix: []byte{}, want: nil},
+	}
+
+	for _, tc := range cases {
+		got := PrefixNext(tc.prefix)
+		if !bytes.Equal(got, tc.want) || (tc.want == nil) != (got == nil) {
+			t.Errorf("PrefixNext(%q) = %q, 期望 %q", tc.prefix, got, tc.want)
+		}
+	}
+
+	// 以 0xFF 结尾的前缀下的键必须落在范围内，直接追加 0xFF 会漏掉这些键
+	r := PrefixRange([]byte("a"))
+	key := []byte("a\xFF\x01")
+	if bytes.Compare(key, r.Start) < 0 || bytes.Compare(key, r.End) >= 0 {
+		t.Errorf("key %q 应该在前缀范围 [%q, %q) 内", key, r.Start, r.End)
+	}
+}
+
+// TestKeyRangeIntersect 测试前缀范围和起止键范围的交集
+func TestKeyRangeIntersect(t *testing.T) {
+	prefix := PrefixRange([]byte("user_"))
+
+	got := prefix.Intersect(KeyRange{Start: []byte("user_100"), End: []byte("zzz")})
+	if !bytes.Equal(got.Start, []byte("user_100")) || !bytes.Equal(got.End, []byte("user`")) {
+		t.Errorf("交集错误: [%q, %q)", got.Start, got.End)
+	}
+
+	got = PrefixRange(nil).Intersect(KeyRange{End: []byte("m")})
+	if len(got.Start) != 0 || !bytes.Equal(got.End, []byte("m")) {
+		t.Errorf("交集错误: [%q, %q)", got.Start, got.End)
+	}
+
+	if !prefix.Intersect(KeyRange{Start: []byte("v")}).IsEmpty() {
+		t.Errorf("不相交的范围应该为空")
+	}
+	if PrefixRange(nil).IsEmpty() {
+		t.Errorf("没有上界的范围不应该为空")
+	}
+}
+
+// TestReverseUpperBound 测试没有上界时反向扫描的起点不小于所有的 key，包括以很多个 0xFF 开头的 key
+func TestReverseUpperBound(t *testing.T) {
+	longKey := append(bytes.Repeat([]byte{0xFF}, 40), 'a')
+	keys := [][]byte{[]byte("a"), bytes.Repeat([]byte{0xFF}, 16), longKey}
+	probes := 0
+	hasKeyFrom := func(start []byte) (bool, error) {
+		probes++
+		for _, key := range keys {
+			if bytes.Compare(key, start) >= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	upper, err := reverseUpperBound(KeyRange{Start: []byte("a")}, hasKeyFrom)
+	if err != nil || bytes.Compare(upper, longKey) <= 0 {
+		t.Fatalf("上界 %X 没有覆盖 %X (%v)", upper, longKey, err)
+	}
+	if probes != 3 {
+		t.Errorf("探测次数 = %d, 期望 3", probes)
+	}
+
+	probes = 0
+	if upper, _ := reverseUpperBound(KeyRange{End: []byte("b")}, hasKeyFrom); !bytes.Equal(upper, []byte("b")) || probes != 0 {
+		t.Errorf("有上界的范围应直接使用 End 且不探测: 上界 = %q, 探测 %d 次", upper, probes)
+	}
+}
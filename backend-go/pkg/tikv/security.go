@@ -0,0 +1,291 @@
+package tikv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tikv/client-go/v2/config"
+)
+
+// SecurityWatchInterval 检查证书文件是否变化的间隔
+const SecurityWatchInterval = 10 * time.Second
+
+// Security 连接 PD 和 TiKV 使用的 TLS 配置，CAPath 为空时使用明文连接。
+// 同时设置 CertPath 和 KeyPath 时使用双向 TLS。
+type Security struct {
+	CAPath   string
+	CertPath string
+	KeyPath  string
+	// AllowedCN 非空时要求 PD 证书的 Common Name 在列表中。
+	// PD HTTP API 的每次连接都会检查；client-go 的 gRPC 连接不支持检查 CN，
+	// 因此 PD 只在 SwapClients 创建客户端前握手检查一次，TiKV 节点的证书只校验 CA
+	AllowedCN []string
+}
+
+// Security 中的字段名，SecurityError.Field 取其中之一
+const (
+	SecurityFieldCAPath    = "CAPath"
+	SecurityFieldCertPath  = "CertPath"
+	SecurityFieldKeyPath   = "KeyPath"
+	SecurityFieldAllowedCN = "AllowedCN"
+)
+
+// SecurityError Validate 返回的错误，Field 为出错的字段
+type SecurityError struct {
+	Field string
+	Err   error
+}
+
+func (e *SecurityError) Error() string { return e.Err.Error() }
+
+func (e *SecurityError) Unwrap() error { return e.Err }
+
+var (
+	securityMu      sync.RWMutex
+	currentSecurity Security
+)
+
+// Enabled 是否使用 TLS
+func (s Security) Enabled() bool {
+	return s.CAPath != ""
+}
+
+// Validate 检查配置是否完整，并尝试读取证书文件。返回的错误为 *SecurityError，指出出错的字段
+func (s Security) Validate() error {
+	fail := func(field string, err error) error {
+		return &SecurityError{Field: field, Err: err}
+	}
+	if !s.Enabled() {
+		if s.CertPath != "" || s.KeyPath != "" || len(s.AllowedCN) > 0 {
+			return fail(SecurityFieldCAPath, fmt.Errorf("ca path is required when cert, key or allowed CN is set"))
+		}
+		return nil
+	}
+	if (s.CertPath == "") != (s.KeyPath == "") {
+		field := SecurityFieldKeyPath
+		if s.CertPath == "" {
+			field = SecurityFieldCertPath
+		}
+		return fail(field, fmt.Errorf("cert path and key path must be set together"))
+	}
+	if _, err := s.rootCAs(); err != nil {
+		return fail(SecurityFieldCAPath, err)
+	}
+	if s.CertPath != "" {
+		if _, err := s.loadKeyPair(); err != nil {
+			return fail(s.keyPairErrorField(), err)
+		}
+	}
+	for _, cn := range s.AllowedCN {
+		if strings.TrimSpace(cn) == "" {
+			return fail(SecurityFieldAllowedCN, fmt.Errorf("allowed CN must not be empty"))
+		}
+	}
+	return nil
+}
+
+// keyPairErrorField 判断加载客户端证书失败时出错的文件：证书文件无法读取或没有证书时为 CertPath，否则为 KeyPath
+func (s Security) keyPairErrorField() string {
+	data, err := os.ReadFile(s.CertPath)
+	if err != nil {
+		return SecurityFieldCertPath
+	}
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return SecurityFieldCertPath
+		}
+		if block.Type == "CERTIFICATE" {
+			return SecurityFieldKeyPath
+		}
+	}
+}
+
+// rootCAs 读取 CA 证书
+func (s Security) rootCAs() (*x509.CertPool, error) {
+	ca, err := os.ReadFile(s.CAPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", s.CAPath)
+	}
+	return pool, nil
+}
+
+// TLSConfig 返回访问 PD HTTP API 使用的 TLS 配置，未启用 TLS 时返回 nil。
+// 客户端证书在每次握手时从磁盘读取，更换证书文件后新建的连接会使用新证书。
+func (s Security) TLSConfig() (*tls.Config, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	pool, err := s.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	if s.CertPath != "" {
+		if _, err := s.loadKeyPair(); err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.loadKeyPair()
+		}
+	}
+
+	if len(s.AllowedCN) > 0 {
+		allowed := make(map[string]bool, len(s.AllowedCN))
+		for _, cn := range s.AllowedCN {
+			allowed[cn] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("peer did not present a certificate")
+			}
+			if cn := cs.PeerCertificates[0].Subject.CommonName; !allowed[cn] {
+				return fmt.Errorf("certificate CN %q is not allowed", cn)
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
+
+func (s Security) loadKeyPair() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load client key pair: %v", err)
+	}
+	return &cert, nil
+}
+
+// clientGoSecurity 转换为 client-go 的配置
+func (s Security) clientGoSecurity() config.Security {
+	return config.NewSecurity(s.CAPath, s.CertPath, s.KeyPath, s.AllowedCN)
+}
+
+// SetSecurity 设置之后创建的客户端使用的 TLS 配置，已经创建的客户端需要通过 SwapClients 重新连接。
+// Txn 客户端只能从 client-go 的全局配置读取 TLS 配置，因此同时更新全局配置。
+func SetSecurity(s Security) {
+	securityMu.Lock()
+	defer securityMu.Unlock()
+
+	currentSecurity = s
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.Security = s.clientGoSecurity()
+	})
+}
+
+// CurrentSecurity 返回当前的 TLS 配置
+func CurrentSecurity() Security {
+	securityMu.RLock()
+	defer securityMu.RUnlock()
+	return currentSecurity
+}
+
+// verifyPeerCN 配置了 AllowedCN 时与每个 PD 节点握手并检查证书的 CN。
+// 无法连接的节点跳过，但至少要有一个节点通过检查；CN 不符合时返回错误。
+func verifyPeerCN(ctx context.Context, s Security, endpoints []string) error {
+	if !s.Enabled() || len(s.AllowedCN) == 0 {
+		return nil
+	}
+	tlsConfig, err := s.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var dialErr error
+	for _, endpoint := range endpoints {
+		addr := hostPort(endpoint)
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			dialErr = err
+			continue
+		}
+
+		cfg := tlsConfig.Clone()
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		tlsConn := tls.Client(conn, cfg)
+		err = tlsConn.HandshakeContext(ctx)
+		tlsConn.Close()
+		if err != nil {
+			return fmt.Errorf("TLS handshake with PD %s failed: %v", addr, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to connect to PD to verify certificate: %v", dialErr)
+}
+
+// hostPort 去掉 PD 地址中的协议和路径
+func hostPort(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		if u, err := url.Parse(endpoint); err == nil {
+			return u.Host
+		}
+	}
+	return strings.TrimRight(endpoint, "/")
+}
+
+// fileStamp 文件的修改时间和大小，用于判断证书是否被替换
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (s Security) stamps() []fileStamp {
+	var stamps []fileStamp
+	for _, path := range []string{s.CAPath, s.CertPath, s.KeyPath} {
+		if path == "" {
+			continue
+		}
+		var stamp fileStamp
+		if info, err := os.Stat(path); err == nil {
+			stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps
+}
+
+// WatchSecurityFiles 每隔 interval 检查一次证书文件，发生变化时调用 onChange，直到 ctx 结束。
+// 未启用 TLS 时直接返回。
+func WatchSecurityFiles(ctx context.Context, s Security, interval time.Duration, onChange func()) {
+	if !s.Enabled() {
+		return
+	}
+	last := s.stamps()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stamps := s.stamps()
+		changed := false
+		for i := range stamps {
+			if !stamps[i].modTime.Equal(last[i].modTime) || stamps[i].size != last[i].size {
+				changed = true
+			}
+		}
+		if changed {
+			last = stamps
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package tikv
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA 测试用的 CA，签发服务端和客户端证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) write(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// issue 签发证书，返回证书和私钥的文件路径
+func (ca *testCA) issue(t *testing.T, name, cn string, usage x509.ExtKeyUsage) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca.write(t, name+".pem", "CERTIFICATE", der), ca.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+// newMTLSServer 启动一个要求客户端证书的 HTTPS 服务，模拟 PD
+func newMTLSServer(t *testing.T, ca *testCA, cn string) *httptest.Server {
+	t.Helper()
+	certPath, keyPath := ca.issue(t, cn, cn, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestSecurityValidate 测试 TLS 配置的检查
+func TestSecurityValidate(t *testing.T) {
+	ca := newTestCA(t)
+	certPath, keyPath := ca.issue(t, "client", "tikvadmin", x509.ExtKeyUsageClientAuth)
+	caPath := filepath.Join(ca.dir, "ca.pem")
+
+	cases := []struct {
+		name      string
+		security  Security
+		wantErr   string
+		wantField string
+	}{
+		{name: "明文", security: Security{}},
+		{name: "单向 TLS", security: Security{CAPath: caPath}},
+		{name: "双向 TLS", security: Security{CAPath: caPath, CertPath: certPath, KeyPath: keyPath}},
+		{name: "缺少 CA", security: Security{CertPath: certPath, KeyPath: keyPath}, wantErr: "ca path is required", wantField: SecurityFieldCAPath},
+		{name: "只有证书", security: Security{CAPath: caPath, CertPath: certPath}, wantErr: "must be set together", wantField: SecurityFieldKeyPath},
+		{name: "只有私钥", security: Security{CAPath: caPath, KeyPath: keyPath}, wantErr: "must be set together", wantField: SecurityFieldCertPath},
+		{name: "CA 不存在", security: Security{CAPath: filepath.Join(ca.dir, "missing.pem")}, wantErr: "could not read ca", wantField: SecurityFieldCAPath},
+		{name: "CA 内容错误", security: Security{CAPath: keyPath}, wantErr: "no certificates", wantField: SecurityFieldCAPath},
+		{name: "证书不存在", security: Security{CAPath: caPath, CertPath: filepath.Join(ca.dir, "missing.pem"), KeyPath: keyPath}, wantErr: "could not load client key pair", wantField: SecurityFieldCertPath},
+		{name: "证书内容错误", security: Security{CAPath: caPath, CertPath: keyPath, KeyPath: keyPath}, wantErr: "could not load client key pair", wantField: SecurityFieldCertPath},
+		{name: "证书和私钥不匹配", security: Security{CAPath: caPath, CertPath: certPath, KeyPath: caPath}, wantErr: "could not load client key pair", wantField: SecurityFieldKeyPath},
+		{name: "空的 CN", security: Security{CAPath: caPath, AllowedCN: []string{"pd-server", " "}}, wantErr: "allowed CN must not be empty", wantField: SecurityFieldAllowedCN},
+	}
+	for _, tc := range cases {
+		err := tc.security.Validate()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%s: 不应返回错误: %v", tc.name, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s: 错误 = %v, 期望包含 %q", tc.name, err, tc.wantErr)
+		}
+		var securityErr *SecurityError
+		if tc.wantField != "" && (!errors.As(err, &securityErr) || securityErr.Field != tc.wantField) {
+			t.Errorf("%s: 出错的字段 = %v, 期望 %s", tc.name, err, tc.wantField)
+		}
+	}
+}
+
+// TestSecurityTLSConfig 测试双向 TLS 握手以及 PD 证书 CN 的检查
+func TestSecurityTLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+	srv := newMTLSServer(t, ca, "pd-server")
+	certPath, keyPath := ca.issue(t, "client", "tikvadmin", x509.ExtKeyUsageClientAuth)
+	base := Security{CAPath: filepath.Join(ca.dir, "ca.pem"), CertPath: certPath, KeyPath: keyPath}
+
+	get := func(s Security) error {
+		cfg, err := s.TLSConfig()
+		if err != nil {
+			return err
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(base); err != nil {
+		t.Errorf("双向 TLS 请求失败: %v", err)
+	}
+	if err := get(Security{CAPath: base.CAPath}); err == nil {
+		t.Errorf("服务端要求客户端证书时，没有证书的请求应失败")
+	}
+
+	allowed := base
+	allowed.AllowedCN = []string{"pd-server"}
+	if err := get(allowed); err != nil {
+		t.Errorf("CN 在允许列表中时请求应成功: %v", err)
+	}
+	if err := verifyPeerCN(context.Background(), allowed, []string{"https://" + srv.Listener.Addr().String()}); err != nil {
+		t.Errorf("verifyPeerCN 应通过: %v", err)
+	}
+
+	denied := base
+	denied.AllowedCN = []string{"other"}
+	if err := get(denied); err == nil || !strings.Contains(err.Error(), `"pd-server" is not allowed`) {
+		t.Errorf("CN 不在允许列表中时应失败, 实际为 %v", err)
+	}
+	if err := verifyPeerCN(context.Background(), denied, []string{srv.Listener.Addr().String()}); err == nil {
+		t.Errorf("verifyPeerCN 应拒绝不允许的 CN")
+	}
+}
+
+// TestWatchSecurityFiles 测试证书文件变化后触发重新加载
+func TestWatchSecurityFiles(t *testing.T) {
+	ca := newTestCA(t)
+	certPath, keyPath := ca.issue(t, "client", "tikvadmin", x509.ExtKeyUsageClientAuth)
+	s := Security{CAPath: filepath.Join(ca.dir, "ca.pem"), CertPath: certPath, KeyPath: keyPath}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	go WatchSecurityFiles(ctx, s, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	select {
+	case <-changed:
+		t.Fatalf("文件没有变化时不应触发")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 重新签发证书，大小相同时依靠修改时间判断
+	ca.issue(t, "client", "tikvadmin", x509.ExtKeyUsageClientAuth)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, future, future)
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("证书文件变化后应触发重新加载")
+	}
+}
@@ -0,0 +1,50 @@
+package tikv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tikv/client-go/v2/oracle"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+var (
+	// ErrReadTSBeforeSafePoint 读取时间戳早于 GC safe point，历史版本可能已被回收
+	ErrReadTSBeforeSafePoint = errors.New("read ts is older than the GC safe point")
+	// ErrReadTSInFuture 读取时间戳晚于当前 TSO
+	ErrReadTSInFuture = errors.New("read ts is in the future")
+)
+
+// SnapshotAt 返回指定时间戳上的一致性快照，ts 为 0 表示读取最新数据。
+// 返回值中的时间戳是快照实际使用的读取时间戳。
+func (tc *TxnClient) SnapshotAt(ctx context.Context, ts uint64) (*txnkv.KVSnapshot, uint64, error) {
+	latest, err := tc.cli.GetTimestamp(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ts == 0 {
+		return tc.cli.GetSnapshot(latest), latest, nil
+	}
+
+	if ts > latest {
+		return nil, 0, fmt.Errorf("%w: ts %d, current tso %d", ErrReadTSInFuture, ts, latest)
+	}
+
+	safePoint, err := GetGCSafePoint(ctx, tc.cli)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get GC safe point: %v", err)
+	}
+	if ts < safePoint {
+		return nil, 0, fmt.Errorf("%w: ts %d (%s), safe point %d (%s)", ErrReadTSBeforeSafePoint,
+			ts, oracle.GetTimeFromTS(ts).Format("2006-01-02 15:04:05"),
+			safePoint, oracle.GetTimeFromTS(safePoint).Format("2006-01-02 15:04:05"))
+	}
+
+	return tc.cli.GetSnapshot(ts), ts, nil
+}
+
+// IsInvalidReadTS 判断错误是否由不合法的读取时间戳引起
+func IsInvalidReadTS(err error) bool {
+	return errors.Is(err, ErrReadTSBeforeSafePoint) || errors.Is(err, ErrReadTSInFuture)
+}
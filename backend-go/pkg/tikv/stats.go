@@ -0,0 +1,415 @@
+package tikv
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStatsCacheTTL 统计结果的缓存时间，过期后在后台重新统计
+	DefaultStatsCacheTTL = 5 * time.Minute
+	// DefaultStatsScanLimit 后台统计最多扫描的键数，超过后统计结果只是下界
+	DefaultStatsScanLimit = 1000000
+	// DefaultLargestKeys 统计结果中保留的最大键数量
+	DefaultLargestKeys = 10
+
+	maxStatsJobs = 64
+	// MaxRunningStatsJobs 同时运行的精确统计任务上限，每个任务都会扫描整个前缀范围
+	MaxRunningStatsJobs = 4
+)
+
+// 统计任务状态
+const (
+	StatsJobRunning  = "running"
+	StatsJobDone     = "done"
+	StatsJobFailed   = "failed"
+	StatsJobCanceled = "canceled"
+)
+
+var (
+	// ErrTooManyStatsJobs 正在运行的精确统计任务已达到 MaxRunningStatsJobs
+	ErrTooManyStatsJobs = errors.New("too many stats recount jobs running")
+	// ErrStatsJobFinished 取消的统计任务已经结束
+	ErrStatsJobFinished = errors.New("stats job has already finished")
+	// ErrClusterSwitched 切换集群时取消了正在运行的统计任务
+	ErrClusterSwitched = errors.New("cluster switched")
+)
+
+// valueSizeBuckets value 大小直方图的桶上界（字节），最后再加一个没有上界的桶
+var valueSizeBuckets = []int{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// KeySize 单个键的大小
+type KeySize struct {
+	Key       string `json:"key"`
+	KeySize   int    `json:"keySize"`
+	ValueSize int    `json:"valueSize"`
+}
+
+// SizeBucket value 大小直方图的一个桶，统计 value 大小不超过 UpperBound 的键数
+type SizeBucket struct {
+	// UpperBound 桶上界（字节），-1 表示没有上界
+	UpperBound int   `json:"upperBound"`
+	Count      int64 `json:"count"`
+}
+
+// KVStats 一个模式下某个前缀范围内的统计信息
+type KVStats struct {
+	Mode               string       `json:"mode"`
+	Prefix             string       `json:"prefix"`
+	KeyCount           int64        `json:"keyCount"`
+	KeyBytes           int64        `json:"keyBytes"`
+	ValueBytes         int64        `json:"valueBytes"`
+	LargestKeys        []KeySize    `json:"largestKeys"`
+	ValueSizeHistogram []SizeBucket `json:"valueSizeHistogram"`
+	// Exact 是否扫描完整个范围，超过扫描上限时各项统计只是下界
+	Exact      bool      `json:"exact"`
+	ComputedAt time.Time `json:"computedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// StatsScanFunc 按顺序扫描需要统计的范围，fn 返回 false 时停止扫描
+type StatsScanFunc func(ctx context.Context, fn ScanFunc) error
+
+// keySizeHeap 按 value 大小排序的小顶堆，用于保留最大的 N 个键
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) Len() int           { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool { return h[i].ValueSize < h[j].ValueSize }
+func (h keySizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keySizeHeap) Push(x any)        { *h = append(*h, x.(KeySize)) }
+func (h *keySizeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// statsAccumulator 在扫描过程中累计统计信息
+type statsAccumulator struct {
+	stats     *KVStats
+	limit     int
+	truncated bool
+	largest   keySizeHeap
+}
+
+func newStatsAccumulator(mode, prefix string, limit int) *statsAccumulator {
+	histogram := make([]SizeBucket, 0, len(valueSizeBuckets)+1)
+	for _, bound := range valueSizeBuckets {
+		histogram = append(histogram, SizeBucket{UpperBound: bound})
+	}
+	histogram = append(histogram, SizeBucket{UpperBound: -1})
+
+	return &statsAccumulator{
+		stats: &KVStats{
+			Mode:               mode,
+			Prefix:             prefix,
+			ValueSizeHistogram: histogram,
+		},
+		limit: limit,
+	}
+}
+
+// add 累计一个键值对，limit 大于 0 时扫描到第 limit+1 个键就停止，并标记结果不精确
+func (a *statsAccumulator) add(key, value []byte) bool {
+	if a.limit > 0 && a.stats.KeyCount >= int64(a.limit) {
+		a.truncated = true
+		return false
+	}
+
+	a.stats.KeyCount++
+	a.stats.KeyBytes += int64(len(key))
+	a.stats.ValueBytes += int64(len(value))
+
+	bucket := sort.SearchInts(valueSizeBuckets, len(value))
+	a.stats.ValueSizeHistogram[bucket].Count++
+
+	item := KeySize{Key: string(key), KeySize: len(key), ValueSize: len(value)}
+	if a.largest.Len() < DefaultLargestKeys {
+		heap.Push(&a.largest, item)
+	} else if a.largest[0].ValueSize < item.ValueSize {
+		a.largest[0] = item
+		heap.Fix(&a.largest, 0)
+	}
+	return true
+}
+
+func (a *statsAccumulator) finish(startedAt time.Time) *KVStats {
+	largest := make([]KeySize, len(a.largest))
+	copy(largest, a.largest)
+	sort.Slice(largest, func(i, j int) bool {
+		return largest[i].ValueSize > largest[j].ValueSize
+	})
+
+	a.stats.LargestKeys = largest
+	a.stats.Exact = !a.truncated
+	a.stats.ComputedAt = time.Now()
+	a.stats.DurationMs = time.Since(startedAt).Milliseconds()
+	return a.stats
+}
+
+// ComputeStats 扫描并统计键数量、大小、最大键和 value 大小分布，limit 为 0 表示扫描整个范围
+func ComputeStats(ctx context.Context, mode, prefix string, limit int, scan StatsScanFunc) (*KVStats, error) {
+	startedAt := time.Now()
+	acc := newStatsAccumulator(mode, prefix, limit)
+	if err := scan(ctx, acc.add); err != nil {
+		return nil, err
+	}
+	return acc.finish(startedAt), nil
+}
+
+// StatsJob 精确重新统计任务
+type StatsJob struct {
+	ID         string     `json:"id"`
+	Mode       string     `json:"mode"`
+	Prefix     string     `json:"prefix"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Result     *KVStats   `json:"result,omitempty"`
+
+	// cancel 取消任务的扫描，任务结束后为 nil
+	cancel context.CancelFunc
+}
+
+// StatsCache 缓存各模式、各前缀的统计结果。
+// 缓存过期后由后台按扫描上限重新统计，精确统计通过 Recount 单独启动任务。
+type StatsCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	scanLimit  int
+	generation uint64
+	entries    map[string]*KVStats
+	refreshing map[string]bool
+	jobs       map[string]*StatsJob
+	jobOrder   []string
+	nextJobID  uint64
+	// runningJobs 按模式和前缀记录正在运行的精确统计任务，相同的请求返回同一个任务
+	runningJobs map[string]*StatsJob
+
+	// ctx 在 Shutdown 超时后取消，用于中止后台的统计扫描
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStatsCache 创建统计缓存，ttl 和 scanLimit 小于等于 0 时使用默认值
+func NewStatsCache(ttl time.Duration, scanLimit int) *StatsCache {
+	if ttl <= 0 {
+		ttl = DefaultStatsCacheTTL
+	}
+	if scanLimit <= 0 {
+		scanLimit = DefaultStatsScanLimit
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StatsCache{
+		ctx:        ctx,
+		cancel:     cancel,
+		ttl:        ttl,
+		scanLimit:  scanLimit,
+		entries:    make(map[string]*KVStats),
+		refreshing: make(map[string]bool),
+		jobs:       make(map[string]*StatsJob),
+
+		runningJobs: make(map[string]*StatsJob),
+	}
+}
+
+func statsCacheKey(mode, prefix string) string {
+	return mode + "\x00" + prefix
+}
+
+// Get 返回缓存的统计结果，没有缓存或已过期时在后台重新统计。
+// 第二个返回值表示后台是否正在统计，第一次请求时统计结果为 nil。
+func (c *StatsCache) Get(mode, prefix string, scan StatsScanFunc) (*KVStats, bool) {
+	key := statsCacheKey(mode, prefix)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.entries[key]
+	if stats != nil && time.Since(stats.ComputedAt) < c.ttl {
+		return stats, c.refreshing[key]
+	}
+	if !c.refreshing[key] {
+		c.refreshing[key] = true
+		c.wg.Add(1)
+		go c.refresh(c.generation, key, mode, prefix, scan)
+	}
+	return stats, true
+}
+
+func (c *StatsCache) refresh(generation uint64, key, mode, prefix string, scan StatsScanFunc) {
+	defer c.wg.Done()
+	defer metrics.JobStarted(metrics.JobStatsRefresh)()
+	stats, err := ComputeStats(c.ctx, mode, prefix, c.scanLimit, scan)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	delete(c.refreshing, key)
+	if err != nil {
+		logging.L().Warn("failed to compute stats", zap.String("mode", mode), logging.Key("prefix", []byte(prefix)), zap.Error(err))
+		return
+	}
+	c.entries[key] = stats
+}
+
+// Recount 启动一次不限制扫描数量的精确统计任务，完成后同时更新缓存。
+// 相同模式和前缀的任务正在运行时直接返回该任务，started 为 false；
+// 正在运行的任务达到 MaxRunningStatsJobs 时返回 ErrTooManyStatsJobs。
+func (c *StatsCache) Recount(mode, prefix string, scan StatsScanFunc) (job StatsJob, started bool, err error) {
+	key := statsCacheKey(mode, prefix)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if running := c.runningJobs[key]; running != nil {
+		return *running, false, nil
+	}
+	if len(c.runningJobs) >= MaxRunningStatsJobs {
+		return StatsJob{}, false, ErrTooManyStatsJobs
+	}
+
+	c.nextJobID++
+	ctx, cancel := context.WithCancel(c.ctx)
+	newJob := &StatsJob{
+		ID:        fmt.Sprintf("stats-%d", c.nextJobID),
+		Mode:      mode,
+		Prefix:    prefix,
+		Status:    StatsJobRunning,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+	c.jobs[newJob.ID] = newJob
+	c.jobOrder = append(c.jobOrder, newJob.ID)
+	c.runningJobs[key] = newJob
+	c.pruneJobs()
+
+	c.wg.Add(1)
+	go c.runJob(ctx, c.generation, newJob, scan)
+	return *newJob, true, nil
+}
+
+func (c *StatsCache) runJob(ctx context.Context, generation uint64, job *StatsJob, scan StatsScanFunc) {
+	defer c.wg.Done()
+	defer metrics.JobStarted(metrics.JobStatsRecount)()
+	stats, err := ComputeStats(ctx, job.Mode, job.Prefix, 0, scan)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	job.cancel()
+	job.cancel = nil
+	// 任务被取消后同一范围可能已经启动了新任务
+	key := statsCacheKey(job.Mode, job.Prefix)
+	if c.runningJobs[key] == job {
+		delete(c.runningJobs, key)
+	}
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	switch {
+	case job.Status == StatsJobCanceled:
+	case err != nil:
+		job.Status = StatsJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = StatsJobDone
+		job.Result = stats
+		if generation == c.generation {
+			c.entries[key] = stats
+		}
+	}
+}
+
+// CancelJob 取消正在运行的统计任务，任务状态立即变为 canceled，扫描在后台停止。
+// 任务不存在时 ok 为 false，任务已经结束时返回 ErrStatsJobFinished。
+func (c *StatsCache) CancelJob(id string) (job StatsJob, ok bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target, ok := c.jobs[id]
+	if !ok {
+		return StatsJob{}, false, nil
+	}
+	if target.Status != StatsJobRunning {
+		return *target, true, ErrStatsJobFinished
+	}
+	target.Status = StatsJobCanceled
+	target.cancel()
+	// 同一范围可以立即重新统计，不必等待扫描停止
+	delete(c.runningJobs, statsCacheKey(target.Mode, target.Prefix))
+	return *target, true, nil
+}
+
+// pruneJobs 任务数超过上限时丢弃最早完成的任务，正在运行的任务保留
+func (c *StatsCache) pruneJobs() {
+	for i := 0; len(c.jobs) > maxStatsJobs && i < len(c.jobOrder); {
+		id := c.jobOrder[i]
+		if c.jobs[id].Status == StatsJobRunning {
+			i++
+			continue
+		}
+		delete(c.jobs, id)
+		c.jobOrder = append(c.jobOrder[:i], c.jobOrder[i+1:]...)
+	}
+}
+
+// Job 查询统计任务
+func (c *StatsCache) Job(id string) (StatsJob, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	job, ok := c.jobs[id]
+	if !ok {
+		return StatsJob{}, false
+	}
+	return *job, true
+}
+
+// Reset 清空缓存的统计结果，切换集群后调用。
+// 正在运行的精确统计任务被取消并记录 ErrClusterSwitched，进行中的后台统计完成后不再写入缓存。
+func (c *StatsCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*KVStats)
+	c.refreshing = make(map[string]bool)
+	for key, job := range c.runningJobs {
+		job.Status = StatsJobCanceled
+		job.Error = ErrClusterSwitched.Error()
+		job.cancel()
+		delete(c.runningJobs, key)
+	}
+}
+
+// Shutdown 等待进行中的后台统计结束，ctx 结束时取消剩余的统计并返回 ctx 的错误
+func (c *StatsCache) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,208 @@
+package tikv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestComputeStats 测试键数量、大小、最大键和直方图的统计，以及扫描上限
+func TestComputeStats(t *testing.T) {
+	var keys, values [][]byte
+	for i := 0; i < 20; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("k%02d", i)))
+		values = append(values, []byte(strings.Repeat("v", i*10)))
+	}
+	scan := func(ctx context.Context, fn ScanFunc) error {
+		for i := range keys {
+			if !fn(keys[i], values[i]) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	stats, err := ComputeStats(context.Background(), "rawkv", "k", 0, scan)
+	if err != nil {
+		t.Fatalf("ComputeStats 失败: %v", err)
+	}
+	if stats.KeyCount != 20 || stats.KeyBytes != 60 || stats.ValueBytes != 1900 || !stats.Exact {
+		t.Errorf("统计结果错误: count=%d keyBytes=%d valueBytes=%d exact=%v",
+			stats.KeyCount, stats.KeyBytes, stats.ValueBytes, stats.Exact)
+	}
+	if len(stats.LargestKeys) != DefaultLargestKeys || stats.LargestKeys[0].Key != "k19" || stats.LargestKeys[9].Key != "k10" {
+		t.Errorf("最大键错误: %+v", stats.LargestKeys)
+	}
+	// 0..60 字节的 value 落在 64 字节桶，70..190 字节落在 256 字节桶
+	if stats.ValueSizeHistogram[0].Count != 7 || stats.ValueSizeHistogram[1].Count != 13 {
+		t.Errorf("直方图错误: %+v", stats.ValueSizeHistogram)
+	}
+
+	limited, err := ComputeStats(context.Background(), "rawkv", "k", 5, scan)
+	if err != nil {
+		t.Fatalf("ComputeStats 失败: %v", err)
+	}
+	if limited.KeyCount != 5 || limited.Exact {
+		t.Errorf("超过扫描上限时应只统计 5 个键并标记为不精确: count=%d exact=%v", limited.KeyCount, limited.Exact)
+	}
+
+	exactLimit, err := ComputeStats(context.Background(), "rawkv", "k", 20, scan)
+	if err != nil {
+		t.Fatalf("ComputeStats 失败: %v", err)
+	}
+	if !exactLimit.Exact {
+		t.Error("键数正好等于扫描上限时结果应是精确的")
+	}
+}
+
+// TestStatsCacheShutdown 测试关闭时等待后台统计结束，超时后取消仍在运行的统计
+func TestStatsCacheShutdown(t *testing.T) {
+	cache := NewStatsCache(time.Minute, 0)
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	cache.Recount("rawkv", "a", func(ctx context.Context, fn ScanFunc) error {
+		<-release
+		return nil
+	})
+	cache.Recount("rawkv", "b", func(ctx context.Context, fn ScanFunc) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := cache.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("统计没有结束时 Shutdown 应返回超时, 实际为 %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("超时后应取消仍在运行的统计")
+	}
+	if err := cache.Shutdown(context.Background()); err != nil {
+		t.Errorf("统计全部结束后 Shutdown 应成功: %v", err)
+	}
+	if job, _ := cache.Job("stats-1"); job.Status != StatsJobDone {
+		t.Errorf("已完成的统计状态应为 done, 实际为 %s", job.Status)
+	}
+}
+
+// TestStatsCacheRecountJobs 测试相同范围的重新统计复用同一个任务、运行任务数上限和取消任务
+func TestStatsCacheRecountJobs(t *testing.T) {
+	cache := NewStatsCache(time.Minute, 0)
+	defer cache.Shutdown(context.Background())
+	scanned := make(chan string, MaxRunningStatsJobs+1)
+	blocking := func(prefix string) StatsScanFunc {
+		return func(ctx context.Context, fn ScanFunc) error {
+			scanned <- prefix
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+
+	first, started, err := cache.Recount("rawkv", "p0", blocking("p0"))
+	if err != nil || !started {
+		t.Fatalf("第一次重新统计应启动任务: %v", err)
+	}
+	again, started, err := cache.Recount("rawkv", "p0", blocking("p0"))
+	if err != nil || started || again.ID != first.ID {
+		t.Errorf("相同范围应返回正在运行的任务 %s, 实际为 %s (started=%v, err=%v)", first.ID, again.ID, started, err)
+	}
+	for i := 1; i < MaxRunningStatsJobs; i++ {
+		prefix := fmt.Sprintf("p%d", i)
+		if _, _, err := cache.Recount("rawkv", prefix, blocking(prefix)); err != nil {
+			t.Fatalf("未达到上限时应能启动任务: %v", err)
+		}
+	}
+	if _, _, err := cache.Recount("txn", "p0", blocking("txn")); err != ErrTooManyStatsJobs {
+		t.Errorf("达到上限时应返回 ErrTooManyStatsJobs, 实际为 %v", err)
+	}
+	for i := 0; i < MaxRunningStatsJobs; i++ {
+		<-scanned
+	}
+
+	canceled, ok, err := cache.CancelJob(first.ID)
+	if !ok || err != nil || canceled.Status != StatsJobCanceled {
+		t.Fatalf("取消任务失败: %+v, %v, %v", canceled, ok, err)
+	}
+	if _, _, err := cache.CancelJob(first.ID); err != ErrStatsJobFinished {
+		t.Errorf("重复取消应返回 ErrStatsJobFinished, 实际为 %v", err)
+	}
+	if _, ok, _ := cache.CancelJob("stats-404"); ok {
+		t.Error("不存在的任务不应取消成功")
+	}
+
+	// 取消后同一范围可以立即重新统计，旧任务结束时不影响新任务
+	next, started, err := cache.Recount("rawkv", "p0", blocking("p0"))
+	if err != nil || !started || next.ID == first.ID {
+		t.Fatalf("取消后应能重新统计: %+v, %v, %v", next, started, err)
+	}
+	<-scanned
+	deadline := time.Now().Add(time.Second)
+	for {
+		job, _ := cache.Job(first.ID)
+		if job.FinishedAt != nil {
+			if job.Status != StatsJobCanceled {
+				t.Errorf("被取消的任务结束后状态应为 canceled, 实际为 %s", job.Status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("被取消的任务没有停止扫描")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if again, started, _ := cache.Recount("rawkv", "p0", blocking("p0")); started || again.ID != next.ID {
+		t.Errorf("旧任务结束后新任务应仍在运行: %s (started=%v)", again.ID, started)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cache.Shutdown(ctx)
+}
+
+// TestStatsCacheResetCancelsJobs 测试切换集群时取消正在运行的精确统计任务
+func TestStatsCacheResetCancelsJobs(t *testing.T) {
+	cache := NewStatsCache(time.Minute, 0)
+	defer cache.Shutdown(context.Background())
+	scanning := make(chan struct{})
+	job, _, err := cache.Recount("rawkv", "p0", func(ctx context.Context, fn ScanFunc) error {
+		close(scanning)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("启动重新统计失败: %v", err)
+	}
+	<-scanning
+
+	cache.Reset()
+	canceled, _ := cache.Job(job.ID)
+	if canceled.Status != StatsJobCanceled || canceled.Error != ErrClusterSwitched.Error() {
+		t.Errorf("切换集群后任务应被取消并记录原因: %+v", canceled)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		finished, _ := cache.Job(job.ID)
+		if finished.FinishedAt != nil {
+			if finished.Status != StatsJobCanceled || finished.Error != ErrClusterSwitched.Error() {
+				t.Errorf("任务结束后应保留取消状态和原因: %+v", finished)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("切换集群后任务没有停止扫描")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	next, started, err := cache.Recount("rawkv", "p0", func(ctx context.Context, fn ScanFunc) error { return nil })
+	if err != nil || !started || next.ID == job.ID {
+		t.Errorf("切换集群后同一范围应能重新统计: %+v, %v, %v", next, started, err)
+	}
+}
@@ -0,0 +1,114 @@
+package tikv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tikv/client-go/v2/oracle"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// TSOInfo 时间戳的组成：高位为物理时间（毫秒），低 18 位为逻辑计数器
+type TSOInfo struct {
+	TS       uint64 `json:"ts"`
+	Physical int64  `json:"physical"`
+	Logical  int64  `json:"logical"`
+	Time     string `json:"time"`
+}
+
+// NewTSOInfo 拆分时间戳，ts 为 0 时返回空的时间
+func NewTSOInfo(ts uint64) TSOInfo {
+	info := TSOInfo{
+		TS:       ts,
+		Physical: oracle.ExtractPhysical(ts),
+		Logical:  oracle.ExtractLogical(ts),
+	}
+	if ts != 0 {
+		info.Time = oracle.GetTimeFromTS(ts).UTC().Format(time.RFC3339Nano)
+	}
+	return info
+}
+
+// maxLogical 逻辑计数器占 18 位
+const maxLogical = 1 << 18
+
+// MaxPhysical 物理时间占高 46 位，超过时组成的时间戳会溢出
+const MaxPhysical = 1<<46 - 1
+
+// GetTSO 通过客户端的 oracle 从 PD 获取一个新的全局时间戳
+func GetTSO(ctx context.Context, cli *txnkv.Client) (TSOInfo, error) {
+	ts, err := cli.GetOracle().GetTimestamp(ctx, &oracle.Option{TxnScope: oracle.GlobalTxnScope})
+	if err != nil {
+		return TSOInfo{}, err
+	}
+	return NewTSOInfo(ts), nil
+}
+
+// ComposeTimestamp 由物理时间（毫秒）和逻辑计数器组成时间戳
+func ComposeTimestamp(physical int64, logical int64) (uint64, error) {
+	if physical < 0 || physical > MaxPhysical {
+		return 0, fmt.Errorf("physical must be in [0, %d]", MaxPhysical)
+	}
+	if logical < 0 || logical >= maxLogical {
+		return 0, fmt.Errorf("logical must be in [0, %d)", maxLogical)
+	}
+	return oracle.ComposeTS(physical, logical), nil
+}
+
+// Timestamp 请求体中的时间戳，JSON 中可以是数字 TSO、字符串 TSO 或 RFC3339 时间
+type Timestamp uint64
+
+// UnmarshalJSON 按 ParseTimestamp 的规则解析时间戳
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var s string
+	switch v := raw.(type) {
+	case nil:
+		*t = 0
+		return nil
+	case string:
+		s = v
+	case float64:
+		// 数字 TSO 超过 float64 的精度，直接使用原始文本
+		s = string(data)
+	default:
+		return fmt.Errorf("invalid timestamp %s", data)
+	}
+
+	ts, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(ts)
+	return nil
+}
+
+// ParseTimestamp 解析时间戳参数，支持原始 TSO 或 RFC3339 格式的时间
+func ParseTimestamp(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("timestamp is empty")
+	}
+
+	if ts, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return ts, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: must be a TSO or RFC3339 time", s)
+	}
+	ts, err := ComposeTimestamp(t.UnixMilli(), 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %v", s, err)
+	}
+	return ts, nil
+}
@@ -0,0 +1,92 @@
+package tikv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// TestParseTimestamp 测试 TSO 和 RFC3339 时间戳的解析
+func TestParseTimestamp(t *testing.T) {
+	wallClock := time.Date(2025, 12, 3, 14, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{input: "446054400000000000", want: 446054400000000000},
+		{input: " 42 ", want: 42},
+		{input: "2025-12-03T14:00:00Z", want: oracle.GoTimeToTS(wallClock)},
+		{input: "2025-12-03T22:00:00+08:00", want: oracle.GoTimeToTS(wallClock)},
+		{input: "", wantErr: true},
+		{input: "yesterday", wantErr: true},
+		{input: "-1", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseTimestamp(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseTimestamp(%q) 期望返回错误，实际得到 %d", tc.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTimestamp(%q) 返回错误: %v", tc.input, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseTimestamp(%q) = %d, 期望 %d", tc.input, got, tc.want)
+		}
+	}
+}
+
+// TestTimestampConversion 测试 TSO 的拆分、组合和请求体中的解析
+func TestTimestampConversion(t *testing.T) {
+	wallClock := time.Date(2025, 12, 3, 14, 0, 0, 0, time.UTC)
+	ts, err := ComposeTimestamp(wallClock.UnixMilli(), 7)
+	if err != nil {
+		t.Fatalf("ComposeTimestamp 失败: %v", err)
+	}
+
+	info := NewTSOInfo(ts)
+	if info.Physical != wallClock.UnixMilli() || info.Logical != 7 || info.Time != "2025-12-03T14:00:00Z" {
+		t.Errorf("拆分结果错误: %+v", info)
+	}
+	if _, err := ComposeTimestamp(0, 1<<18); err == nil {
+		t.Error("逻辑计数器越界应返回错误")
+	}
+	if ts, err := ComposeTimestamp(MaxPhysical, maxLogical-1); err != nil || ts != math.MaxUint64 {
+		t.Errorf("物理时间上限应能组成最大的时间戳: %d, %v", ts, err)
+	}
+	if _, err := ComposeTimestamp(MaxPhysical+1, 0); err == nil {
+		t.Error("物理时间超过 46 位应返回错误")
+	}
+	if _, err := ComposeTimestamp(-1, 0); err == nil {
+		t.Error("物理时间为负数应返回错误")
+	}
+	if _, err := ParseTimestamp("5000-01-01T00:00:00Z"); err == nil {
+		t.Error("超出 TSO 范围的时间应返回错误")
+	}
+
+	var req struct {
+		A Timestamp `json:"a"`
+		B Timestamp `json:"b"`
+		C Timestamp `json:"c"`
+	}
+	body := fmt.Sprintf(`{"a": %d, "b": "%d", "c": "2025-12-03T14:00:00Z"}`, ts, ts)
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if uint64(req.A) != ts || uint64(req.B) != ts || uint64(req.C) != oracle.GoTimeToTS(wallClock) {
+		t.Errorf("解析结果错误: %+v", req)
+	}
+	if err := json.Unmarshal([]byte(`{"a": "yesterday"}`), &req); err == nil {
+		t.Error("非法时间戳应返回错误")
+	}
+}
@@ -0,0 +1,230 @@
+package tikv
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"tikv-backend/pkg/metrics"
+
+	"github.com/tikv/client-go/v2/config"
+	tikvstore "github.com/tikv/client-go/v2/tikv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ClientTuning 创建客户端使用的 gRPC、批量发送、Region 缓存参数以及单次操作的超时，零值字段使用默认值。
+// gRPC 窗口和消息大小只能通过 RawKV 的拨号选项设置，Txn 客户端使用 client-go 的默认值（1 GiB 窗口，不限制接收大小）；
+// 其他参数通过 client-go 的全局配置同时作用于两个客户端。
+type ClientTuning struct {
+	GRPCConnectionCount       uint
+	GRPCInitialWindowSize     int32
+	GRPCInitialConnWindowSize int32
+	GRPCMaxRecvMsgSize        int
+	GRPCMaxSendMsgSize        int
+	GRPCKeepAliveTime         time.Duration
+	GRPCKeepAliveTimeout      time.Duration
+
+	// MaxBatchSize 为 0 时关闭批量发送
+	MaxBatchSize     uint
+	MaxBatchWaitTime time.Duration
+	BatchWaitSize    uint
+
+	RegionCacheTTL time.Duration
+
+	// ReadTimeout、ScanTimeout、WriteTimeout 分别限制单次 get、扫描和 put/delete，0 表示不限制。
+	// 提交不受限制，超时取消提交可能导致无法确定事务是否成功。
+	ReadTimeout  time.Duration
+	ScanTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+var (
+	tuningMu      sync.RWMutex
+	currentTuning = DefaultClientTuning()
+)
+
+// DefaultClientTuning 返回默认的客户端参数
+func DefaultClientTuning() ClientTuning {
+	return ClientTuning{
+		GRPCConnectionCount:       4,
+		GRPCInitialWindowSize:     64 << 20,
+		GRPCInitialConnWindowSize: 64 << 20,
+		GRPCMaxRecvMsgSize:        512 << 20,
+		GRPCMaxSendMsgSize:        256 << 20,
+		GRPCKeepAliveTime:         30 * time.Second,
+		GRPCKeepAliveTimeout:      10 * time.Second,
+		MaxBatchSize:              128,
+		BatchWaitSize:             8,
+		RegionCacheTTL:            10 * time.Minute,
+	}
+}
+
+// WithDefaults 用默认值填充零值字段。MaxBatchSize、批量等待和操作超时的零值有意义，保持不变。
+func (t ClientTuning) WithDefaults() ClientTuning {
+	d := DefaultClientTuning()
+	if t.GRPCConnectionCount == 0 {
+		t.GRPCConnectionCount = d.GRPCConnectionCount
+	}
+	if t.GRPCInitialWindowSize == 0 {
+		t.GRPCInitialWindowSize = d.GRPCInitialWindowSize
+	}
+	if t.GRPCInitialConnWindowSize == 0 {
+		t.GRPCInitialConnWindowSize = d.GRPCInitialConnWindowSize
+	}
+	if t.GRPCMaxRecvMsgSize == 0 {
+		t.GRPCMaxRecvMsgSize = d.GRPCMaxRecvMsgSize
+	}
+	if t.GRPCMaxSendMsgSize == 0 {
+		t.GRPCMaxSendMsgSize = d.GRPCMaxSendMsgSize
+	}
+	if t.GRPCKeepAliveTime == 0 {
+		t.GRPCKeepAliveTime = d.GRPCKeepAliveTime
+	}
+	if t.GRPCKeepAliveTimeout == 0 {
+		t.GRPCKeepAliveTimeout = d.GRPCKeepAliveTimeout
+	}
+	if t.RegionCacheTTL == 0 {
+		t.RegionCacheTTL = d.RegionCacheTTL
+	}
+	return t
+}
+
+// Validate 检查参数范围，拒绝会导致连接被 TiKV 断开、请求大量失败或延迟明显升高的组合
+func (t ClientTuning) Validate() error {
+	switch {
+	case t.GRPCConnectionCount < 1 || t.GRPCConnectionCount > 16:
+		return fmt.Errorf("grpc connection count must be between 1 and 16, got %d", t.GRPCConnectionCount)
+	case t.GRPCInitialWindowSize < 64<<10 || t.GRPCInitialConnWindowSize < 64<<10:
+		// gRPC 会忽略小于 64 KiB 的窗口
+		return fmt.Errorf("grpc window sizes must be at least 64 KiB")
+	case t.GRPCInitialConnWindowSize < t.GRPCInitialWindowSize:
+		return fmt.Errorf("grpc connection window (%d) must not be smaller than the stream window (%d)",
+			t.GRPCInitialConnWindowSize, t.GRPCInitialWindowSize)
+	case t.GRPCMaxRecvMsgSize < 4<<20 || t.GRPCMaxSendMsgSize < 4<<20:
+		return fmt.Errorf("grpc message size limits must be at least 4 MiB")
+	case t.GRPCKeepAliveTime < 10*time.Second:
+		// TiKV 会断开 ping 过于频繁的连接
+		return fmt.Errorf("grpc keepalive time must be at least 10s, got %s", t.GRPCKeepAliveTime)
+	case t.GRPCKeepAliveTimeout < time.Second || t.GRPCKeepAliveTimeout >= t.GRPCKeepAliveTime:
+		return fmt.Errorf("grpc keepalive timeout must be at least 1s and shorter than the keepalive time, got %s", t.GRPCKeepAliveTimeout)
+	case t.MaxBatchWaitTime < 0 || t.MaxBatchWaitTime > 50*time.Millisecond:
+		return fmt.Errorf("max batch wait time must be between 0 and 50ms, got %s", t.MaxBatchWaitTime)
+	case t.MaxBatchWaitTime > 0 && (t.MaxBatchSize == 0 || t.BatchWaitSize == 0):
+		return fmt.Errorf("max batch wait time requires max batch size and batch wait size to be set")
+	case t.BatchWaitSize > t.MaxBatchSize && t.MaxBatchSize > 0:
+		return fmt.Errorf("batch wait size (%d) must not exceed max batch size (%d)", t.BatchWaitSize, t.MaxBatchSize)
+	case t.RegionCacheTTL < 10*time.Second:
+		// 过短会让每次请求都重新从 PD 加载 Region
+		return fmt.Errorf("region cache ttl must be at least 10s, got %s", t.RegionCacheTTL)
+	}
+	for _, timeout := range []struct {
+		name string
+		d    time.Duration
+	}{{"read", t.ReadTimeout}, {"scan", t.ScanTimeout}, {"write", t.WriteTimeout}} {
+		if timeout.d != 0 && timeout.d < 100*time.Millisecond {
+			return fmt.Errorf("%s timeout must be 0 or at least 100ms, got %s", timeout.name, timeout.d)
+		}
+	}
+	return nil
+}
+
+// OpTimeout 返回一次操作的超时，op 为 metrics 中的操作名，0 表示不限制
+func (t ClientTuning) OpTimeout(op string) time.Duration {
+	switch op {
+	case metrics.OpGet:
+		return t.ReadTimeout
+	case metrics.OpScan, metrics.OpScanLock, metrics.OpMvcc:
+		return t.ScanTimeout
+	case metrics.OpPut, metrics.OpDelete:
+		return t.WriteTimeout
+	}
+	return 0
+}
+
+// MarshalJSON 时间以 "30s" 这样的字符串输出
+func (t ClientTuning) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		GRPCConnectionCount       uint   `json:"grpcConnectionCount"`
+		GRPCInitialWindowSize     int32  `json:"grpcInitialWindowSize"`
+		GRPCInitialConnWindowSize int32  `json:"grpcInitialConnWindowSize"`
+		GRPCMaxRecvMsgSize        int    `json:"grpcMaxRecvMsgSize"`
+		GRPCMaxSendMsgSize        int    `json:"grpcMaxSendMsgSize"`
+		GRPCKeepAliveTime         string `json:"grpcKeepAliveTime"`
+		GRPCKeepAliveTimeout      string `json:"grpcKeepAliveTimeout"`
+		MaxBatchSize              uint   `json:"maxBatchSize"`
+		MaxBatchWaitTime          string `json:"maxBatchWaitTime"`
+		BatchWaitSize             uint   `json:"batchWaitSize"`
+		RegionCacheTTL            string `json:"regionCacheTTL"`
+		ReadTimeout               string `json:"readTimeout"`
+		ScanTimeout               string `json:"scanTimeout"`
+		WriteTimeout              string `json:"writeTimeout"`
+	}{
+		GRPCConnectionCount:       t.GRPCConnectionCount,
+		GRPCInitialWindowSize:     t.GRPCInitialWindowSize,
+		GRPCInitialConnWindowSize: t.GRPCInitialConnWindowSize,
+		GRPCMaxRecvMsgSize:        t.GRPCMaxRecvMsgSize,
+		GRPCMaxSendMsgSize:        t.GRPCMaxSendMsgSize,
+		GRPCKeepAliveTime:         t.GRPCKeepAliveTime.String(),
+		GRPCKeepAliveTimeout:      t.GRPCKeepAliveTimeout.String(),
+		MaxBatchSize:              t.MaxBatchSize,
+		MaxBatchWaitTime:          t.MaxBatchWaitTime.String(),
+		BatchWaitSize:             t.BatchWaitSize,
+		RegionCacheTTL:            t.RegionCacheTTL.String(),
+		ReadTimeout:               t.ReadTimeout.String(),
+		ScanTimeout:               t.ScanTimeout.String(),
+		WriteTimeout:              t.WriteTimeout.String(),
+	})
+}
+
+// dialOptions RawKV 客户端的 gRPC 拨号选项，排在 client-go 默认选项之后，会覆盖默认值
+func (t ClientTuning) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithInitialWindowSize(t.GRPCInitialWindowSize),
+		grpc.WithInitialConnWindowSize(t.GRPCInitialConnWindowSize),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(t.GRPCMaxRecvMsgSize),
+			grpc.MaxCallSendMsgSize(t.GRPCMaxSendMsgSize),
+		),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    t.GRPCKeepAliveTime,
+			Timeout: t.GRPCKeepAliveTimeout,
+		}),
+	}
+}
+
+// applyGlobal 写入 client-go 的全局配置。client-go 在连接每个 TiKV 节点时才读取这些配置，
+// 因此切换集群后，旧集群的客户端新建的连接也会使用新的参数。
+func (t ClientTuning) applyGlobal() {
+	config.UpdateGlobal(func(conf *config.Config) {
+		conf.TiKVClient.GrpcConnectionCount = t.GRPCConnectionCount
+		conf.TiKVClient.GrpcKeepAliveTime = uint(t.GRPCKeepAliveTime / time.Second)
+		conf.TiKVClient.GrpcKeepAliveTimeout = uint(t.GRPCKeepAliveTimeout / time.Second)
+		conf.TiKVClient.MaxBatchSize = t.MaxBatchSize
+		conf.TiKVClient.MaxBatchWaitTime = t.MaxBatchWaitTime
+		conf.TiKVClient.BatchWaitSize = t.BatchWaitSize
+		conf.TiKVClient.RegionCacheTTL = uint(t.RegionCacheTTL / time.Second)
+	})
+	tikvstore.SetRegionCacheTTLSec(int64(t.RegionCacheTTL / time.Second))
+}
+
+// SetClientTuning 检查并设置之后创建的客户端使用的参数，已经创建的客户端需要通过 SwapClients 重新连接
+func SetClientTuning(t ClientTuning) error {
+	t = t.WithDefaults()
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	tuningMu.Lock()
+	defer tuningMu.Unlock()
+	currentTuning = t
+	return nil
+}
+
+// CurrentClientTuning 返回之后创建的客户端使用的参数
+func CurrentClientTuning() ClientTuning {
+	tuningMu.RLock()
+	defer tuningMu.RUnlock()
+	return currentTuning
+}
@@ -0,0 +1,76 @@
+package tikv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"tikv-backend/pkg/metrics"
+)
+
+// TestClientTuningValidate 测试默认值填充以及拒绝危险的参数组合
+func TestClientTuningValidate(t *testing.T) {
+	if err := DefaultClientTuning().Validate(); err != nil {
+		t.Fatalf("默认参数应通过检查: %v", err)
+	}
+	if got := (ClientTuning{ReadTimeout: time.Second}).WithDefaults(); got.GRPCConnectionCount != 4 || got.ReadTimeout != time.Second || got.MaxBatchSize != 0 {
+		t.Errorf("WithDefaults 只应填充 gRPC 和 Region 缓存的零值: %+v", got)
+	}
+
+	cases := []struct {
+		name    string
+		modify  func(*ClientTuning)
+		wantErr string
+	}{
+		{name: "连接数过多", modify: func(c *ClientTuning) { c.GRPCConnectionCount = 64 }, wantErr: "connection count"},
+		{name: "窗口过小", modify: func(c *ClientTuning) { c.GRPCInitialWindowSize = 1024 }, wantErr: "at least 64 KiB"},
+		{name: "连接窗口小于流窗口", modify: func(c *ClientTuning) { c.GRPCInitialConnWindowSize = 1 << 20 }, wantErr: "connection window"},
+		{name: "消息上限过小", modify: func(c *ClientTuning) { c.GRPCMaxRecvMsgSize = 1 << 20 }, wantErr: "message size"},
+		{name: "keepalive 过于频繁", modify: func(c *ClientTuning) { c.GRPCKeepAliveTime = time.Second }, wantErr: "keepalive time"},
+		{name: "keepalive 超时不短于间隔", modify: func(c *ClientTuning) { c.GRPCKeepAliveTimeout = time.Minute }, wantErr: "keepalive timeout"},
+		{name: "批量等待过长", modify: func(c *ClientTuning) { c.MaxBatchWaitTime = time.Second }, wantErr: "between 0 and 50ms"},
+		{name: "关闭批量发送时设置等待", modify: func(c *ClientTuning) { c.MaxBatchSize = 0; c.MaxBatchWaitTime = time.Millisecond }, wantErr: "requires max batch size"},
+		{name: "等待数量超过批量上限", modify: func(c *ClientTuning) { c.BatchWaitSize = 256 }, wantErr: "must not exceed"},
+		{name: "Region 缓存过短", modify: func(c *ClientTuning) { c.RegionCacheTTL = time.Second }, wantErr: "region cache ttl"},
+		{name: "操作超时过短", modify: func(c *ClientTuning) { c.ScanTimeout = time.Millisecond }, wantErr: "scan timeout"},
+	}
+	for _, tc := range cases {
+		tuning := DefaultClientTuning()
+		tc.modify(&tuning)
+		if err := tuning.Validate(); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: 错误 = %v, 期望包含 %q", tc.name, err, tc.wantErr)
+		}
+		if err := SetClientTuning(tuning); err == nil {
+			t.Errorf("%s: SetClientTuning 应拒绝不合法的参数", tc.name)
+		}
+	}
+	if CurrentClientTuning() != DefaultClientTuning() {
+		t.Errorf("不合法的参数不应替换当前参数")
+	}
+}
+
+// TestClientTuningOpTimeout 测试按操作取超时以及 JSON 输出
+func TestClientTuningOpTimeout(t *testing.T) {
+	tuning := ClientTuning{ReadTimeout: time.Second, ScanTimeout: time.Minute, WriteTimeout: 2 * time.Second}
+	want := map[string]time.Duration{
+		metrics.OpGet:    time.Second,
+		metrics.OpScan:   time.Minute,
+		metrics.OpPut:    2 * time.Second,
+		metrics.OpDelete: 2 * time.Second,
+		metrics.OpCommit: 0,
+	}
+	for op, d := range want {
+		if got := tuning.OpTimeout(op); got != d {
+			t.Errorf("OpTimeout(%s) = %s, 期望 %s", op, got, d)
+		}
+	}
+
+	data, err := json.Marshal(DefaultClientTuning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"grpcKeepAliveTime":"30s"`) || !strings.Contains(string(data), `"grpcConnectionCount":4`) {
+		t.Errorf("JSON 输出错误: %s", data)
+	}
+}
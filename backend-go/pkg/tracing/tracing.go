@@ -0,0 +1,239 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 导出方式
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// OTLP 协议
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// instrumentationName 本服务创建的 span 使用的 tracer 名称
+const instrumentationName = "tikv-backend"
+
+// Options 链路追踪配置
+type Options struct {
+	// Exporter 为 none、otlp 或 file，none 时只传播上游的 trace context，不导出 span
+	Exporter string
+	// Endpoint OTLP 接收端地址（host:port），为空时使用 OTEL_EXPORTER_OTLP_* 环境变量
+	Endpoint string
+	// Protocol OTLP 协议，grpc 或 http
+	Protocol string
+	// Insecure OTLP 不使用 TLS
+	Insecure bool
+	// FilePath file 导出时写入的文件，每行一个 JSON 格式的 span
+	FilePath string
+	// SampleRatio 没有上游采样决定时的采样比例，0 到 1
+	SampleRatio float64
+	// ServiceName 上报的服务名
+	ServiceName string
+}
+
+// Validate 检查导出方式、OTLP 协议、文件路径和采样比例
+func (o Options) Validate() error {
+	switch o.Exporter {
+	case "", ExporterNone:
+	case ExporterOTLP:
+		switch o.Protocol {
+		case "", ProtocolGRPC, ProtocolHTTP:
+		default:
+			return fmt.Errorf("invalid OTLP protocol %q: must be %s or %s", o.Protocol, ProtocolGRPC, ProtocolHTTP)
+		}
+	case ExporterFile:
+		if o.FilePath == "" {
+			return fmt.Errorf("file path is required for the file exporter")
+		}
+	default:
+		return fmt.Errorf("invalid trace exporter %q: must be %s, %s or %s", o.Exporter, ExporterNone, ExporterOTLP, ExporterFile)
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio must be between 0 and 1, got %v", o.SampleRatio)
+	}
+	return nil
+}
+
+// Setup 初始化全局 TracerProvider 和 W3C trace context 传播，返回的函数在退出前调用以导出剩余的 span
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closeFile, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = instrumentationName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := opts.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closeFile != nil {
+			if cerr := closeFile(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+// newExporter 按已经检查过的 opts 创建导出器，none 时返回 nil
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, func() error, error) {
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return nil, nil, nil
+
+	case ExporterOTLP:
+		switch opts.Protocol {
+		case "", ProtocolGRPC:
+			var grpcOpts []otlptracegrpc.Option
+			if opts.Endpoint != "" {
+				grpcOpts = append(grpcOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+			}
+			if opts.Insecure {
+				grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+			}
+			exporter, err := otlptracegrpc.New(ctx, grpcOpts...)
+			return exporter, nil, err
+		case ProtocolHTTP:
+			var httpOpts []otlptracehttp.Option
+			if opts.Endpoint != "" {
+				httpOpts = append(httpOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+			}
+			if opts.Insecure {
+				httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+			}
+			exporter, err := otlptracehttp.New(ctx, httpOpts...)
+			return exporter, nil, err
+		}
+
+	case ExporterFile:
+		f, err := os.OpenFile(opts.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f.Close, nil
+	}
+	return nil, nil, nil
+}
+
+// Tracer 返回本服务使用的 tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Key 返回经过脱敏的 key 属性，规则与日志相同
+func Key(name string, key []byte) attribute.KeyValue {
+	return attribute.String(name, logging.RedactKey(key))
+}
+
+// Range 返回扫描范围的属性
+func Range(start, end []byte) []attribute.KeyValue {
+	return []attribute.KeyValue{Key("tikv.range.start", start), Key("tikv.range.end", end)}
+}
+
+// KeyCount 返回涉及的 key 数量属性
+func KeyCount(n int) attribute.KeyValue {
+	return attribute.Int("tikv.key_count", n)
+}
+
+// Op 一次 TiKV 操作，同时记录 span 和 Prometheus 指标
+type Op struct {
+	mode   string
+	name   string
+	start  time.Time
+	span   trace.Span
+	cancel context.CancelFunc
+}
+
+type opTimeoutsKey struct{}
+
+// WithOpTimeouts 在 ctx 中设置单次操作的超时，timeout 按操作名返回超时时间，0 表示不限制
+func WithOpTimeouts(ctx context.Context, timeout func(op string) time.Duration) context.Context {
+	return context.WithValue(ctx, opTimeoutsKey{}, timeout)
+}
+
+// StartOp 开始一次 TiKV 操作，返回的 context 带有该操作的 span，需要传给 client-go 的调用。
+// ctx 中通过 WithOpTimeouts 设置了超时时，返回的 context 在超时后取消。
+func StartOp(ctx context.Context, mode, op string, attrs ...attribute.KeyValue) (context.Context, *Op) {
+	attrs = append(attrs, attribute.String("tikv.mode", mode), attribute.String("tikv.op", op))
+	ctx, span := Tracer().Start(ctx, "tikv."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	o := &Op{mode: mode, name: op, start: time.Now(), span: span}
+	if timeout, ok := ctx.Value(opTimeoutsKey{}).(func(string) time.Duration); ok {
+		if d := timeout(op); d > 0 {
+			ctx, o.cancel = context.WithTimeout(ctx, d)
+		}
+	}
+	return ctx, o
+}
+
+// SetAttributes 添加操作完成后才知道的属性，例如扫描的 key 数量和提交时间戳
+func (o *Op) SetAttributes(attrs ...attribute.KeyValue) {
+	o.span.SetAttributes(attrs...)
+}
+
+// End 结束操作，err 不为空时标记为失败
+func (o *Op) End(err error) {
+	if o.cancel != nil {
+		o.cancel()
+	}
+	metrics.ObserveTiKV(o.mode, o.name, o.start, err)
+	if err != nil {
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+	o.span.End()
+}
@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func attrValue(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// TestMiddleware 测试请求 span 继承上游的 traceparent，TiKV 操作的 span 挂在请求 span 下，未知的模式记为 other
+func TestMiddleware(t *testing.T) {
+	recorder := newRecorder(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logging.Middleware())
+	router.Use(Middleware())
+	router.GET("/api/kv/:key", func(c *gin.Context) {
+		_, op := StartOp(c.Request.Context(), "rawkv", metrics.OpGet, Key("tikv.key", []byte(c.Param("key"))))
+		op.End(nil)
+		c.Status(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/kv/user_1?type=rawkv", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("span 数量 = %d, 期望 2", len(spans))
+	}
+	opSpan, reqSpan := spans[0], spans[1]
+
+	if reqSpan.Name() != "GET /api/kv/:key" {
+		t.Errorf("请求 span 名称 = %q, 期望使用路由模板", reqSpan.Name())
+	}
+	if reqSpan.SpanKind() != trace.SpanKindServer {
+		t.Errorf("请求 span 类型 = %v", reqSpan.SpanKind())
+	}
+	if got := reqSpan.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace id = %s, 期望继承上游 %s", got, traceID)
+	}
+	if got := reqSpan.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("请求 span 的 parent = %s", got)
+	}
+	if v, ok := attrValue(reqSpan, "tikv.mode"); !ok || v.AsString() != "rawkv" {
+		t.Errorf("请求 span 缺少 tikv.mode 属性")
+	}
+	if v, ok := attrValue(reqSpan, "request.id"); !ok || v.AsString() == "" {
+		t.Errorf("请求 span 缺少 request.id 属性")
+	}
+
+	// type 查询参数不是 rawkv 或 txn 时记为 other，属性取值保持有限
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/kv/user_1?type=random-1", nil))
+	spans = recorder.Ended()
+	if v, ok := attrValue(spans[len(spans)-1], "tikv.mode"); !ok || v.AsString() != "other" {
+		t.Errorf("未知模式的 tikv.mode = %q, 期望 other", v.AsString())
+	}
+
+	if opSpan.Name() != "tikv.get" {
+		t.Errorf("操作 span 名称 = %q", opSpan.Name())
+	}
+	if opSpan.Parent().SpanID() != reqSpan.SpanContext().SpanID() {
+		t.Errorf("操作 span 应挂在请求 span 下")
+	}
+	if v, ok := attrValue(opSpan, "tikv.key"); !ok || v.AsString() != `"user_1"` {
+		t.Errorf("tikv.key = %v", v.AsString())
+	}
+}
+
+// TestStartOp 测试操作失败时记录错误，并且 key 按日志规则脱敏
+func TestStartOp(t *testing.T) {
+	recorder := newRecorder(t)
+	logging.SetRedactedKeyPrefixes([]string{"secret/"})
+	defer logging.SetRedactedKeyPrefixes(nil)
+
+	_, op := StartOp(context.Background(), "txn", metrics.OpScan, Range([]byte("secret/a"), []byte("z"))...)
+	op.SetAttributes(KeyCount(42))
+	op.End(errors.New("region unavailable"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("span 数量 = %d, 期望 1", len(spans))
+	}
+	span := spans[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "region unavailable" {
+		t.Errorf("status = %+v, 期望记录错误", span.Status())
+	}
+	if len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+		t.Errorf("期望记录一个 exception 事件，实际为 %v", span.Events())
+	}
+
+	want := map[string]string{
+		"tikv.mode":        "txn",
+		"tikv.op":          "scan",
+		"tikv.range.start": "<redacted len=8>",
+		"tikv.range.end":   `"z"`,
+	}
+	for k, w := range want {
+		if v, ok := attrValue(span, k); !ok || v.AsString() != w {
+			t.Errorf("%s = %q, 期望 %q", k, v.AsString(), w)
+		}
+	}
+	if v, ok := attrValue(span, "tikv.key_count"); !ok || v.AsInt64() != 42 {
+		t.Errorf("tikv.key_count = %v, 期望 42", v.AsInt64())
+	}
+}
+
+// TestStartOpTimeout 测试 WithOpTimeouts 设置的超时只作用于对应的操作，End 后释放
+func TestStartOpTimeout(t *testing.T) {
+	ctx := WithOpTimeouts(context.Background(), func(op string) time.Duration {
+		if op == metrics.OpGet {
+			return time.Minute
+		}
+		return 0
+	})
+
+	getCtx, op := StartOp(ctx, "rawkv", metrics.OpGet)
+	if _, ok := getCtx.Deadline(); !ok {
+		t.Errorf("get 操作应设置超时")
+	}
+	op.End(nil)
+	if getCtx.Err() != context.Canceled {
+		t.Errorf("End 之后应取消操作的 context, 实际为 %v", getCtx.Err())
+	}
+
+	commitCtx, op := StartOp(ctx, "txn", metrics.OpCommit)
+	defer op.End(nil)
+	if _, ok := commitCtx.Deadline(); ok {
+		t.Errorf("超时为 0 的操作不应设置超时")
+	}
+}
+
+// TestSetupFileExporter 测试 file 导出方式把 span 写入文件，以及不合法的配置
+func TestSetupFileExporter(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterFile, FilePath: path, ServiceName: "test"})
+	if err != nil {
+		t.Fatalf("Setup 失败: %v", err)
+	}
+	_, op := StartOp(context.Background(), "rawkv", metrics.OpPut)
+	op.End(nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown 失败: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"tikv.put"`) {
+		t.Errorf("文件中没有导出的 span: %s", data)
+	}
+
+	for _, opts := range []Options{
+		{Exporter: "zipkin"},
+		{Exporter: ExporterFile},
+		{Exporter: ExporterOTLP, Protocol: "udp"},
+	} {
+		if _, err := Setup(context.Background(), opts); err == nil {
+			t.Errorf("配置 %+v 应返回错误", opts)
+		}
+	}
+}
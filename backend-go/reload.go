@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tikv-backend/config"
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/pd"
+	"tikv-backend/pkg/tikv"
+	"tikv-backend/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var (
+	// 配置文件路径、启动时的配置和最近一次成功加载的配置
+	configPath     string
+	startupConfig  *config.Config
+	loadedConfig   atomic.Pointer[config.Config]
+	configLoadedAt atomic.Pointer[time.Time]
+
+	// reloadMu 保证同一时间只有一次重新加载
+	reloadMu sync.Mutex
+
+	// 证书文件监听，TLS 配置变化后重新启动
+	securityWatchMu     sync.Mutex
+	securityWatchCancel context.CancelFunc
+)
+
+// runtimeConfig 从配置文件解析出的各个包使用的设置，创建时已经全部检查过
+type runtimeConfig struct {
+	cfg      *config.Config
+	log      logging.Options
+	tracing  tracing.Options
+	commit   tikv.CommitOptions
+	cluster  tikv.ClusterOptions
+	tuning   tikv.ClientTuning
+	security tikv.Security
+}
+
+// prepareConfig 检查配置并转换成各个包使用的设置，不修改当前的设置。
+// 有无效的设置时返回 config.ValidationError，列出所有无效的字段。
+func prepareConfig(cfg *config.Config) (*runtimeConfig, error) {
+	rc := &runtimeConfig{
+		cfg: cfg,
+		log: logging.Options{
+			Level:             cfg.Log.Level,
+			Format:            cfg.Log.Format,
+			RedactKeyPrefixes: cfg.Log.RedactKeyPrefixes,
+		},
+		tracing: tracing.Options{
+			Exporter:    cfg.Tracing.Exporter,
+			Endpoint:    cfg.Tracing.Endpoint,
+			Protocol:    cfg.Tracing.Protocol,
+			Insecure:    cfg.Tracing.Insecure,
+			FilePath:    cfg.Tracing.FilePath,
+			SampleRatio: cfg.Tracing.SampleRatio,
+			ServiceName: cfg.Tracing.ServiceName,
+		},
+		commit: tikv.CommitOptions{
+			AsyncCommit: cfg.TiKV.AsyncCommit,
+			OnePC:       cfg.TiKV.OnePC,
+		},
+		tuning:   cfg.TiKV.Client.Tuning(),
+		security: cfg.TiKV.Security(),
+	}
+
+	var errs config.ValidationError
+	errs.Add("", cfg.Validate())
+	errs.Add("log.level", logging.Options{Level: cfg.Log.Level}.Validate())
+	errs.Add("log.format", logging.Options{Format: cfg.Log.Format}.Validate())
+	errs.Add("tracing", rc.tracing.Validate())
+
+	var err error
+	if _, err = tikv.ParseClusterOptions(cfg.TiKV.APIVersion, ""); err != nil {
+		errs.Add("tikv.api_version", err)
+	} else if rc.cluster, err = cfg.TiKV.ClusterOptions(); err != nil {
+		errs.Add("tikv.keyspace", err)
+	}
+
+	errs.Add("tikv.client", rc.tuning.Validate())
+
+	// 证书文件是否存在、能否读取也在这里检查，错误记在出错的字段下
+	errs.Add("", cfg.TiKV.ValidateSecurity())
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// runConfigCommand 执行 --print-config 和 --check-config：检查时把每个无效的字段输出一行，
+// 输出配置时使用配置文件的格式。返回进程的退出码。
+func runConfigCommand(cfg *config.Config, loadErr error, print, check bool) int {
+	err := loadErr
+	if err == nil && check {
+		_, err = prepareConfig(cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config %s:\n", configPath)
+		if fieldErrs, ok := err.(config.ValidationError); ok {
+			for _, fieldErr := range fieldErrs {
+				fmt.Fprintf(os.Stderr, "  %s\n", fieldErr)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "  %v\n", err)
+		}
+		return 1
+	}
+
+	if print {
+		data, err := cfg.Encode(config.FormatOf(configPath))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode config: %v\n", err)
+			return 1
+		}
+		os.Stdout.Write(data)
+	}
+	if check {
+		if _, statErr := os.Stat(configPath); os.IsNotExist(statErr) {
+			fmt.Fprintf(os.Stderr, "config file %s not found, defaults and environment overrides are valid\n", configPath)
+		} else {
+			fmt.Fprintf(os.Stderr, "config %s is valid\n", configPath)
+		}
+	}
+	return 0
+}
+
+// applyConfig 应用已经检查过的设置：日志、提交选项、默认 API 版本和 keyspace、客户端参数、TLS、探针和请求体上限。
+// 已经连接的客户端不受影响，需要重新连接的情况由 reloadConfig 处理。
+func applyConfig(rc *runtimeConfig) error {
+	if err := applyConnectionSettings(rc); err != nil {
+		return err
+	}
+	return applySettings(rc)
+}
+
+// connectionSettings 新建客户端时使用的客户端参数和 TLS 配置
+type connectionSettings struct {
+	tuning   tikv.ClientTuning
+	security tikv.Security
+	pdTLS    *tls.Config
+}
+
+// currentConnectionSettings 返回当前的客户端参数和 TLS 配置，用于切换集群失败后恢复
+func currentConnectionSettings() connectionSettings {
+	return connectionSettings{
+		tuning:   tikv.CurrentClientTuning(),
+		security: tikv.CurrentSecurity(),
+		pdTLS:    pd.CurrentTLSConfig(),
+	}
+}
+
+// restore 恢复之前的客户端参数和 TLS 配置，不重新读取证书文件
+func (s connectionSettings) restore() {
+	if err := tikv.SetClientTuning(s.tuning); err != nil {
+		logging.L().Warn("failed to restore TiKV client tuning", zap.Error(err))
+	}
+	tikv.SetSecurity(s.security)
+	pd.SetTLSConfig(s.pdTLS)
+}
+
+// applyConnectionSettings 应用连接集群使用的客户端参数和 TLS 配置
+func applyConnectionSettings(rc *runtimeConfig) error {
+	if err := tikv.SetClientTuning(rc.tuning); err != nil {
+		return err
+	}
+	return applySecurity(rc.security)
+}
+
+// applySettings 应用连接集群以外的设置，并记录为最近一次加载的配置
+func applySettings(rc *runtimeConfig) error {
+	if err := logging.Setup(rc.log); err != nil {
+		return err
+	}
+	tikv.SetDefaultCommitOptions(rc.commit)
+
+	settingsMu.Lock()
+	defaultClusterOptions = rc.cluster
+	if rc.cfg.Health.CanaryKey != "" {
+		canaryKey = rc.cfg.Health.CanaryKey
+	}
+	probeTimeout = time.Duration(rc.cfg.Health.TimeoutMs) * time.Millisecond
+	maxBodyBytes = rc.cfg.Server.MaxBodyBytes
+	settingsMu.Unlock()
+
+	loadedAt := time.Now()
+	loadedConfig.Store(rc.cfg)
+	configLoadedAt.Store(&loadedAt)
+	return nil
+}
+
+// watchSecurity 监听证书文件，文件变化后重新连接；再次调用时停止之前的监听
+func watchSecurity(ctx context.Context, security tikv.Security) {
+	securityWatchMu.Lock()
+	defer securityWatchMu.Unlock()
+
+	if securityWatchCancel != nil {
+		securityWatchCancel()
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	securityWatchCancel = cancel
+	go tikv.WatchSecurityFiles(watchCtx, security, tikv.SecurityWatchInterval, func() {
+		reloadSecurity(security)
+	})
+}
+
+// reloadConfig 重新读取配置文件并在运行时应用。新配置无效时保持当前配置不变。
+// 配置文件中的集群变化时切换到新集群；集群不变但客户端参数或 TLS 配置变化时重新连接当前集群。
+// HTTP 服务、链路追踪和统计缓存的设置需要重启才能生效。
+func reloadConfig(ctx context.Context, reason string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if _, err := os.Stat(configPath); err != nil {
+		logging.L().Warn("config file not available, keeping current config", zap.String("path", configPath), zap.Error(err))
+		return err
+	}
+	logging.L().Info("reloading config", zap.String("path", configPath), zap.String("reason", reason))
+
+	cfg, err := config.LoadConfig(configPath)
+	if err == nil {
+		var rc *runtimeConfig
+		if rc, err = prepareConfig(cfg); err == nil {
+			err = applyReloaded(ctx, rc)
+		}
+	}
+	if err != nil {
+		logging.L().Error("failed to reload config, keeping current config", zap.Error(err))
+		return err
+	}
+	logging.L().Info("config reloaded")
+	return nil
+}
+
+// applyReloaded 应用重新加载的配置，并按需要切换或重新连接集群。
+// 新的客户端参数和 TLS 配置先用于连接集群，连接并验证成功后才应用其余的设置；
+// 连接失败时恢复之前的客户端参数和 TLS 配置，当前的客户端和设置都保持不变。
+func applyReloaded(ctx context.Context, rc *runtimeConfig) error {
+	previous := loadedConfig.Load()
+	connection := currentConnectionSettings()
+	tuningChanged := rc.tuning != connection.tuning
+	securityChanged := !reflect.DeepEqual(rc.security, connection.security)
+
+	if err := applyConnectionSettings(rc); err != nil {
+		connection.restore()
+		return err
+	}
+	if err := reconnectReloaded(previous, rc, tuningChanged || securityChanged); err != nil {
+		connection.restore()
+		return err
+	}
+
+	if err := applySettings(rc); err != nil {
+		return err
+	}
+	if securityChanged {
+		watchSecurity(ctx, rc.security)
+	}
+	warnRestartRequired(previous, rc.cfg)
+	return nil
+}
+
+// reconnectReloaded 配置文件中的集群变化时切换到新集群，客户端参数或 TLS 配置变化时重新连接当前集群
+func reconnectReloaded(previous *config.Config, rc *runtimeConfig, connectionChanged bool) error {
+	// 配置文件中的集群没有变化时保持当前连接，包括通过 API 切换到的集群
+	endpoints := getCurrentEndpoints()
+	clients := tikv.AcquireClients()
+	opts := clients.Options()
+	clients.Release()
+	connected := len(endpoints) > 0
+
+	fileClusterChanged := previous == nil || !sameCluster(previous.TiKV, rc.cfg.TiKV)
+	if fileClusterChanged && (!connected || strings.Join(endpoints, ",") != strings.Join(rc.cfg.TiKV.PDEndpoints, ",") || opts != rc.cluster) {
+		logging.L().Info("cluster in config file changed, switching cluster")
+		return switchCluster(rc.cfg.TiKV.PDEndpoints, rc.cluster)
+	}
+	if connected && connectionChanged {
+		logging.L().Info("TiKV client or TLS config changed, reconnecting")
+		return switchCluster(endpoints, opts)
+	}
+	return nil
+}
+
+// sameCluster 比较两份配置中的集群地址、API 版本和 keyspace，API 版本按解析后的结果比较
+func sameCluster(a, b config.TiKVConfig) bool {
+	optsA, errA := tikv.ParseClusterOptions(a.APIVersion, a.Keyspace)
+	optsB, errB := tikv.ParseClusterOptions(b.APIVersion, b.Keyspace)
+	return strings.Join(a.PDEndpoints, ",") == strings.Join(b.PDEndpoints, ",") && errA == nil && errB == nil && optsA == optsB
+}
+
+// warnRestartRequired 需要重启才能生效的设置发生变化时记录警告
+func warnRestartRequired(previous, next *config.Config) {
+	if previous == nil {
+		return
+	}
+	prevServer, nextServer := previous.Server, next.Server
+	prevServer.MaxBodyBytes, nextServer.MaxBodyBytes = 0, 0
+
+	var sections []string
+	if prevServer != nextServer {
+		sections = append(sections, "server")
+	}
+	if previous.Tracing != next.Tracing {
+		sections = append(sections, "tracing")
+	}
+	if previous.TiKV.StatsCacheTTL != next.TiKV.StatsCacheTTL || previous.TiKV.StatsScanLimit != next.TiKV.StatsScanLimit {
+		sections = append(sections, "tikv.stats_cache_ttl/stats_scan_limit")
+	}
+	if len(sections) > 0 {
+		logging.L().Warn("config changes require a restart to take effect", zap.Strings("sections", sections))
+	}
+}
+
+// switchCluster 连接新的集群，成功后清空统计缓存和热点统计
+func switchCluster(endpoints []string, opts tikv.ClusterOptions) error {
+	if err := InitializeTiKVClient(endpoints, opts); err != nil {
+		return err
+	}
+
+	// 之前集群的客户端已被替换，进行中的请求结束后关闭
+	if previous := strings.Join(getCurrentEndpoints(), ","); previous != "" && previous != strings.Join(endpoints, ",") {
+		metrics.DeleteClientConnected(previous, "rawkv")
+		metrics.DeleteClientConnected(previous, "txn")
+	}
+	setCurrentEndpoints(endpoints)
+	kvStatsCache.Reset()
+	accessTracker.Reset()
+	return nil
+}
+
+// persistCluster 开启 persist_endpoints 时把通过 API 切换的集群写回配置文件，下次启动或重新加载时使用
+func persistCluster(endpoints []string, opts tikv.ClusterOptions) error {
+	cfg := loadedConfig.Load()
+	if cfg == nil || !cfg.TiKV.PersistEndpoints || configPath == "" {
+		return nil
+	}
+	return config.SaveCluster(configPath, endpoints, opts.APIVersionName(), opts.Keyspace)
+}
+
+// EffectiveConfigResponse 当前生效的配置
+type EffectiveConfigResponse struct {
+	Path     string         `json:"path"`
+	LoadedAt time.Time      `json:"loaded_at"`
+	Config   *config.Config `json:"config"`
+}
+
+// handleGetConfig 返回当前生效的配置：最近一次加载的配置文件和环境变量的设置，需要重启的部分使用启动时的设置，
+// 加上运行时切换的集群和修改的日志级别。私钥路径等敏感字段已隐藏。
+func handleGetConfig(c *gin.Context) {
+	cfg := loadedConfig.Load()
+	if cfg == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Success: false,
+			Message: "Config not loaded",
+		})
+		return
+	}
+
+	effective := *cfg
+	if startupConfig != nil {
+		maxBodyBytes := effective.Server.MaxBodyBytes
+		effective.Server = startupConfig.Server
+		effective.Server.MaxBodyBytes = maxBodyBytes
+		effective.Tracing = startupConfig.Tracing
+		effective.TiKV.StatsCacheTTL = startupConfig.TiKV.StatsCacheTTL
+		effective.TiKV.StatsScanLimit = startupConfig.TiKV.StatsScanLimit
+	}
+	if endpoints := getCurrentEndpoints(); len(endpoints) > 0 {
+		opts := requestClients(c.Request.Context()).Options()
+		effective.TiKV.PDEndpoints = endpoints
+		effective.TiKV.APIVersion = opts.APIVersionName()
+		effective.TiKV.Keyspace = opts.Keyspace
+	}
+	effective.Log.Level = logging.Level()
+
+	response := EffectiveConfigResponse{Path: configPath, Config: effective.Redacted()}
+	if loadedAt := configLoadedAt.Load(); loadedAt != nil {
+		response.LoadedAt = *loadedAt
+	}
+	c.JSON(http.StatusOK, ApiResponse{
+		Success: true,
+		Message: "Get config successful",
+		Data:    response,
+	})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tikv-backend/config"
+	"tikv-backend/pkg/logging"
+	"tikv-backend/pkg/metrics"
+	"tikv-backend/pkg/pd"
+	"tikv-backend/pkg/tikv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestReloadConfig 测试重新加载配置：有效的修改立即生效，无效的配置或无法连接的集群被拒绝且不影响当前配置
+func TestReloadConfig(t *testing.T) {
+	defer func(path string, body int64) {
+		configPath, maxBodyBytes = path, body
+		loadedConfig.Store(nil)
+		logging.Setup(logging.Options{Level: "info"})
+	}(configPath, maxBodyBytes)
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"log": {"level": "info"}}`)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := prepareConfig(cfg)
+	if err != nil {
+		t.Fatalf("prepareConfig failed: %v", err)
+	}
+	if err := applyConfig(rc); err != nil {
+		t.Fatal(err)
+	}
+
+	write(`{"log": {"level": "debug"}, "server": {"max_body_bytes": 1024}}`)
+	if err := reloadConfig(context.Background(), "test"); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+	if logging.Level() != "debug" || maxBodyBytes != 1024 {
+		t.Errorf("重新加载后应生效: level=%s maxBodyBytes=%d", logging.Level(), maxBodyBytes)
+	}
+
+	write(`{"log": {"level": "verbose"}, "server": {"max_body_bytes": 2048}}`)
+	if err := reloadConfig(context.Background(), "test"); err == nil || !strings.Contains(err.Error(), "invalid log level") {
+		t.Errorf("无效的日志级别应被拒绝, 实际为 %v", err)
+	}
+	if logging.Level() != "debug" || maxBodyBytes != 1024 {
+		t.Errorf("无效配置不应修改当前配置: level=%s maxBodyBytes=%d", logging.Level(), maxBodyBytes)
+	}
+
+	// 新集群连接失败时不应用任何设置，客户端参数和 TLS 配置也恢复为之前的值。
+	// 配置了 cert_allowed_cn 时连接前先与 PD 握手，PD 无法连接时立即失败
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	srv.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tuning, security := tikv.CurrentClientTuning(), tikv.CurrentSecurity()
+	write(`{"log": {"level": "warn"}, "server": {"max_body_bytes": 4096},
+		"tikv": {"pd_endpoints": ["127.0.0.1:1"], "ca_path": "` + caPath + `", "cert_allowed_cn": ["pd-server"],
+			"client": {"grpc_connection_count": 7}}}`)
+	if err := reloadConfig(context.Background(), "test"); err == nil {
+		t.Errorf("无法连接的集群应被拒绝")
+	}
+	if logging.Level() != "debug" || maxBodyBytes != 1024 {
+		t.Errorf("切换集群失败后不应修改当前配置: level=%s maxBodyBytes=%d", logging.Level(), maxBodyBytes)
+	}
+	if tikv.CurrentClientTuning() != tuning || !reflect.DeepEqual(tikv.CurrentSecurity(), security) || pd.CurrentTLSConfig() != nil {
+		t.Errorf("切换集群失败后应恢复客户端参数和 TLS 配置: tuning=%+v security=%+v", tikv.CurrentClientTuning(), tikv.CurrentSecurity())
+	}
+	if got := loadedConfig.Load().TiKV.PDEndpoints; strings.Join(got, ",") == "127.0.0.1:1" {
+		t.Errorf("切换集群失败后不应记录新的配置")
+	}
+	metrics.Register()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "tikvadmin_client_connected" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "cluster" && label.GetValue() == "127.0.0.1:1" {
+					t.Errorf("连接失败的集群不应有连接状态序列: %v", m)
+				}
+			}
+		}
+	}
+
+	write(`{"log": {"level": "debug"}, "tikv": {"api_version": "v1", "keyspace": "ks"}}`)
+	if err := reloadConfig(context.Background(), "test"); err == nil {
+		t.Errorf("v1 集群不支持 keyspace，应被拒绝")
+	}
+}
+
+// TestHandleGetConfig 测试当前生效的配置中敏感字段被隐藏
+func TestHandleGetConfig(t *testing.T) {
+	defer loadedConfig.Store(nil)
+
+	cfg, _ := config.LoadConfig("")
+	cfg.TiKV.CAPath = "/certs/ca.pem"
+	cfg.TiKV.KeyPath = "/certs/client-key.pem"
+	cfg.Server.TLSKeyPath = "/certs/server-key.pem"
+	loadedConfig.Store(cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/kv/config", handleGetConfig)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/kv/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, 期望 200", w.Code)
+	}
+
+	body := w.Body.String()
+	if strings.Contains(body, "key.pem") {
+		t.Errorf("私钥路径应被隐藏: %s", body)
+	}
+	var resp struct {
+		Data EffectiveConfigResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Config.TiKV.CAPath != "/certs/ca.pem" || resp.Data.Config.Log.Level != logging.Level() {
+		t.Errorf("生效配置错误: %+v", resp.Data.Config)
+	}
+	if cfg.TiKV.KeyPath != "/certs/client-key.pem" {
+		t.Errorf("不应修改已加载的配置")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMakeScanPair 测试 keysOnly / preview / hash 模式下的扫描结果
+func TestMakeScanPair(t *testing.T) {
+	key := []byte("txn_order_TXN-001")
+	value := []byte(`{"customer":"王五","amount":2399}`)
+
+	full := makeScanPair(key, value, scanValueOptions{Preview: -1})
+	if full.Value != string(value) || full.ValueSize != nil {
+		t.Errorf("默认模式应该返回完整 value: %+v", full)
+	}
+
+	keysOnly := makeScanPair(key, value, scanValueOptions{KeysOnly: true, Preview: -1})
+	if keysOnly.Key != string(key) || keysOnly.Value != "" || keysOnly.ValueSize != nil || keysOnly.ValueHash != "" {
+		t.Errorf("keysOnly 模式应该只返回 key: %+v", keysOnly)
+	}
+	valueSize := makeScanPair(key, value, scanValueOptions{ValueSize: true, Preview: -1})
+	if valueSize.Value != "" || valueSize.ValueSize == nil || *valueSize.ValueSize != len(value) || valueSize.ValueHash != "" {
+		t.Errorf("valueSize 模式应该只返回 value 大小: %+v", valueSize)
+	}
+
+	// "王" 占 3 个字节，预览 15 字节会落在字符中间，需要退回到字符边界
+	preview := makeScanPair(key, value, scanValueOptions{Preview: 15, Hash: true})
+	if preview.ValueSize == nil || *preview.ValueSize != len(value) {
+		t.Errorf("preview 模式应该返回 value 大小: %+v", preview)
+	}
+	if !preview.Truncated || !strings.HasPrefix(string(value), preview.Value) || len(preview.Value) > 15 {
+		t.Errorf("preview 截断错误: %q", preview.Value)
+	}
+	if !strings.HasSuffix(preview.Value, "\"") {
+		t.Errorf("preview 不应该截断多字节字符: %q", preview.Value)
+	}
+	if len(preview.ValueHash) != 64 {
+		t.Errorf("hash 应该是 SHA-256 十六进制: %q", preview.ValueHash)
+	}
+
+	hashOnly := makeScanPair(key, value, scanValueOptions{Preview: -1, Hash: true})
+	if hashOnly.Value != "" || hashOnly.ValueHash != preview.ValueHash || hashOnly.Truncated {
+		t.Errorf("只请求 hash 时不应该返回 value: %+v", hashOnly)
+	}
+}
+
+// TestScanValueOptionsFromQuery 测试 keysOnly 只拒绝与 preview 的组合
+func TestScanValueOptionsFromQuery(t *testing.T) {
+	for query, valid := range map[string]bool{
+		"keysOnly=true":                true,
+		"valueSize=true":               true,
+		"valueSize=true&hash=true":     true,
+		"preview=10&hash=true":         true,
+		"keysOnly=true&hash=true":      false,
+		"keysOnly=true&valueSize=true": false,
+		"keysOnly=true&preview=10":     false,
+		"keysOnly=true&preview=0":      false,
+		"valueSize=yes":                false,
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/kv?"+query, nil)
+		if _, err := scanValueOptionsFromQuery(c); (err == nil) != valid {
+			t.Errorf("%s: err = %v, valid = %v", query, err, valid)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"tikv-backend/pkg/tikv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestBodyLimitMiddleware 测试请求体超过上限时返回 413 或读取失败
+func TestBodyLimitMiddleware(t *testing.T) {
+	defer func(old int64) { maxBodyBytes = old }(maxBodyBytes)
+	maxBodyBytes = 8
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(bodyLimitMiddleware())
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	post := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/echo", body)
+		req.ContentLength = contentLength
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(strings.NewReader("12345678"), 8); w.Code != http.StatusOK || w.Body.String() != "12345678" {
+		t.Errorf("未超过上限的请求应成功: %d %s", w.Code, w.Body.String())
+	}
+	if w := post(strings.NewReader("123456789"), 9); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Content-Length 超过上限时应返回 413, 实际为 %d", w.Code)
+	}
+	// 未声明长度时在读取时报错
+	if w := post(strings.NewReader("123456789"), -1); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Errorf("读取超过上限的请求体应失败: %d %s", w.Code, w.Body.String())
+	}
+}
+
+// TestSingleKeyScan 测试 tikvadmin 通过 HTTP API 读取单个 key 的方式：
+// 包含 "/" 或与 /stats 等接口同名的 key 不能用 /api/kv/:key 读取，[key, KeyAfter(key)) 的扫描只返回这个 key
+func TestSingleKeyScan(t *testing.T) {
+	// 需要真实的 TiKV 集群，通过 TIKV_PD_ENDPOINTS 指定
+	pdEndpoints := os.Getenv("TIKV_PD_ENDPOINTS")
+	if pdEndpoints == "" {
+		t.Skip("TIKV_PD_ENDPOINTS not set, skipping TiKV integration test")
+	}
+	if err := InitializeTiKVClient(strings.Split(pdEndpoints, ","), tikv.DefaultClusterOptions()); err != nil {
+		t.Fatalf("Failed to initialize TiKV clients: %v", err)
+	}
+	defer CloseTiKVClient(time.Second)
+
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter()
+	serve := func(method, target string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	data := map[string]string{"tikvadmin_test/a/b": "slash", "stats": "named stats"}
+	for _, mode := range []string{"rawkv", "txn"} {
+		var keys []string
+		for key, value := range data {
+			keys = append(keys, key)
+			if w := serve(http.MethodPost, "/api/kv", map[string]string{"key": key, "value": value, "type": mode}); w.Code != http.StatusOK {
+				t.Fatalf("%s 写入 %q 失败: %d %s", mode, key, w.Code, w.Body.String())
+			}
+		}
+
+		for key, value := range data {
+			query := url.Values{"type": {mode}, "start": {key}, "end": {string(tikv.KeyAfter([]byte(key)))}, "limit": {"1"}}
+			w := serve(http.MethodGet, "/api/kv?"+query.Encode(), nil)
+			var resp struct {
+				Data struct {
+					Data []KeyValuePair `json:"data"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || err != nil {
+				t.Fatalf("%s 扫描 %q 失败: %d %s", mode, key, w.Code, w.Body.String())
+			}
+			if got := resp.Data.Data; len(got) != 1 || got[0].Key != key || got[0].Value != value {
+				t.Errorf("%s 扫描 %q = %+v", mode, key, got)
+			}
+		}
+
+		// 路径中编码过的 "/" 被 gin 还原后匹配不到 /:key
+		if w := serve(http.MethodGet, "/api/kv/"+url.PathEscape("tikvadmin_test/a/b")+"?type="+mode, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s GET /api/kv/:key 读取含 / 的 key 应匹配不到路由，实际为 %d", mode, w.Code)
+		}
+
+		if w := serve(http.MethodDelete, "/api/kv", map[string]any{"keys": keys, "type": mode}); w.Code != http.StatusOK {
+			t.Errorf("%s 删除失败: %d %s", mode, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestClusterStatusWithoutCluster 测试集群状态使用请求所用客户端的 endpoints，没有连接集群时为 unreachable
+func TestClusterStatusWithoutCluster(t *testing.T) {
+	defer setCurrentEndpoints(getCurrentEndpoints())
+	setCurrentEndpoints([]string{"127.0.0.1:1"})
+
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/kv/cluster", nil))
+
+	var resp struct {
+		Data struct {
+			ClusterStatus string   `json:"cluster_status"`
+			Endpoints     []string `json:"endpoints"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("查询集群状态失败: %d %s", w.Code, w.Body.String())
+	}
+	if resp.Data.ClusterStatus != "unreachable" || len(resp.Data.Endpoints) != 0 {
+		t.Errorf("没有连接集群时不应使用其他集群的 endpoints: %+v", resp.Data)
+	}
+}
+
+// TestScanWithoutCluster 测试没有连接集群时扫描返回 503，而不是成功的空结果；type 无效时返回 400
+func TestScanWithoutCluster(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := SetupRouter()
+	for target, want := range map[string]int{
+		"/api/kv?type=rawkv":           http.StatusServiceUnavailable,
+		"/api/kv?type=txn&prefix=user": http.StatusServiceUnavailable,
+		"/api/kv":                      http.StatusServiceUnavailable,
+		"/api/kv?type=raw":             http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want || !strings.Contains(w.Body.String(), `"success":false`) {
+			t.Errorf("GET %s = %d %s, 期望 %d", target, w.Code, w.Body.String(), want)
+		}
+	}
+}
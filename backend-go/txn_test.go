@@ -5,17 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"testing"
 
 	"tikv-backend/pkg/tikv"
 )
 
-// KeyValuePair 键值对结构（与main.go中定义保持一致）
-type KeyValuePair struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
-
 // TestData 测试数据结构
 type TestData struct {
 	OrderID   string  `json:"order_id"`
@@ -30,16 +26,18 @@ type TestData struct {
 
 // TestTxnClientPutAndScan 测试事务客户端的PUT和SCAN操作
 func TestTxnClientPutAndScan(t *testing.T) {
+	// 需要真实的 TiKV 集群，通过 TIKV_PD_ENDPOINTS 指定
+	pdEndpoints := os.Getenv("TIKV_PD_ENDPOINTS")
+	if pdEndpoints == "" {
+		t.Skip("TIKV_PD_ENDPOINTS not set, skipping TiKV integration test")
+	}
+
 	// 初始化TiKV客户端
 	ctx := context.Background()
-	endpoints := []string{
-		"172.16.0.10:2379",
-		"172.16.0.20:2379",
-		"172.16.0.30:2379",
-	}
+	endpoints := strings.Split(pdEndpoints, ",")
 
-	// 初始化全局TxnKVClient（确保与main.go中的初始化逻辑一致）
-	_, err := tikv.NewTxnClient(ctx, endpoints)
+	// 初始化全局客户端（确保与main.go中的初始化逻辑一致）
+	err := tikv.InitializeTiKVClient(endpoints)
 	if err != nil {
 		t.Fatalf("Failed to initialize txn client: %v", err)
 	}
@@ -199,15 +197,18 @@ func TestTxnClientPutAndScan(t *testing.T) {
 	t.Log("\n🎉 事务客户端测试完成!")
 }
 
-// scanTxnKeysWithClient 使用全局TxnKVClient扫描键值对
+// scanTxnKeysWithClient 使用当前集群的事务客户端扫描键值对
 func scanTxnKeysWithClient(ctx context.Context, prefix string, page, limit int) ([]KeyValuePair, error) {
-	// 确保TxnKVClient已初始化
-	if tikv.TxnKVClient == nil {
+	clients := tikv.AcquireClients()
+	defer clients.Release()
+
+	// 确保事务客户端已初始化
+	if clients.Txn() == nil {
 		return nil, fmt.Errorf("TxnKVClient is not initialized")
 	}
 
 	// 创建事务用于扫描
-	txn, err := tikv.TxnKVClient.Begin()
+	txn, err := clients.Txn().Begin()
 	if err != nil {
 		return nil, fmt.Errorf("begin scan transaction failed: %w", err)
 	}